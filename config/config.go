@@ -1,14 +1,272 @@
 package config
 
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
 type ServiceConfig struct {
 	APIConfig           APIConfig              `yaml:"api"`
 	OpenstreetmapConfig OpenstreetmapAPIConfig `yaml:"openstreetmap"`
 	WeatherConfig       WeatherAPIConfig       `yaml:"weather"`
+	CacheConfig         CacheConfig            `yaml:"cache"`
+	LiveConfig          LiveConfig             `yaml:"live"`
+	RulesConfig         RulesConfig            `yaml:"rules"`
+	WarmupConfig        WarmupConfig           `yaml:"warmup"`
+	AdminConfig         AdminConfig            `yaml:"admin"`
+	AuthConfig          AuthConfig             `yaml:"auth"`
+}
+
+// Validate checks the settings that can't be caught on parsing.
+func (c ServiceConfig) Validate() error {
+	if c.APIConfig.MaxBatchSize <= 0 {
+		return fmt.Errorf("api.maxbatchsize %d must be positive", c.APIConfig.MaxBatchSize)
+	}
+	if c.APIConfig.MaxBodyBytes <= 0 {
+		return fmt.Errorf("api.maxbodybytes %d must be positive", c.APIConfig.MaxBodyBytes)
+	}
+	for _, proxy := range c.APIConfig.RateLimit.TrustedProxies {
+		if _, err := ParseCIDR(proxy); err != nil {
+			return fmt.Errorf("api.ratelimit.trustedproxies: %w", err)
+		}
+	}
+	if err := c.APIConfig.RateLimit.validate(c.APIConfig.MaxBatchSize); err != nil {
+		return fmt.Errorf("api.ratelimit: %w", err)
+	}
+	if err := c.APIConfig.CORS.validate(); err != nil {
+		return fmt.Errorf("api.cors: %w", err)
+	}
+	if _, _, err := c.APIConfig.Deprecation.Dates(); err != nil {
+		return fmt.Errorf("api.deprecation: %w", err)
+	}
+	for name, profile := range c.APIConfig.Activities {
+		if err := profile.validate(); err != nil {
+			return fmt.Errorf("api.activities.%s: %w", name, err)
+		}
+	}
+	if err := c.CacheConfig.validate(); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	if err := c.LiveConfig.validate(); err != nil {
+		return fmt.Errorf("live: %w", err)
+	}
+	if c.RulesConfig.SigningSecret != "" {
+		if err := checkSecret(c.RulesConfig.SigningSecret); err != nil {
+			return fmt.Errorf("rules.signingsecret: %w", err)
+		}
+		if err := c.RulesConfig.validate(); err != nil {
+			return fmt.Errorf("rules: %w", err)
+		}
+	}
+	if c.AdminConfig.Token != "" {
+		if err := checkSecret(c.AdminConfig.Token); err != nil {
+			return fmt.Errorf("admin.token: %w", err)
+		}
+	}
+	return nil
+}
+
+// placeholders are well known example secrets, they must be replaced on deploy.
+var placeholders = []string{"change-me", "changeme", "secret", "password", "token", "example"}
+
+// checkSecret rejects the placeholder secrets.
+func checkSecret(secret string) error {
+	for _, placeholder := range placeholders {
+		if strings.EqualFold(strings.TrimSpace(secret), placeholder) {
+			return fmt.Errorf("%q is a placeholder, set a secret value", secret)
+		}
+	}
+	return nil
+}
+
+// validate checks the origins are exact origins or subdomain wildcards, and
+// that credentials are never allowed to wildcard origins.
+func (c CORSConfig) validate() error {
+	for _, origin := range c.AllowedOrigins {
+		wildcard := strings.Contains(origin, "*")
+		if wildcard && c.AllowCredentials {
+			return fmt.Errorf("credentials can't be allowed to wildcard origin %q", origin)
+		}
+		if origin == "*" {
+			continue
+		}
+		scheme, host, ok := strings.Cut(origin, "://")
+		if !ok || (scheme != "http" && scheme != "https") || host == "" || strings.ContainsAny(host, "/?#") {
+			return fmt.Errorf("invalid origin %q, expected scheme://host[:port]", origin)
+		}
+		if wildcard && (strings.Count(host, "*") != 1 || !strings.HasPrefix(host, "*.") || len(host) == len("*.")) {
+			return fmt.Errorf("invalid origin %q, wildcards must be subdomains like https://*.example.com", origin)
+		}
+	}
+	return nil
+}
+
+// ParseCIDR parses a CIDR or a single IP as a network.
+func ParseCIDR(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP %q", s)
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(s)
+	return network, err
 }
 
 type APIConfig struct {
-	Port            int `yaml:"port"`
-	ShutdownTimeout int `yaml:"shutdowntimeout"`
+	Port            int             `yaml:"port"`
+	ShutdownTimeout int             `yaml:"shutdowntimeout"`
+	MaxBatchSize    int             `yaml:"maxbatchsize"`
+	MaxBodyBytes    int64           `yaml:"maxbodybytes"`
+	RateLimit       RateLimitConfig `yaml:"ratelimit"`
+	CORS            CORSConfig      `yaml:"cors"`
+	// Deprecation applies to the unversioned routes, aliases of /v1.
+	Deprecation DeprecationConfig `yaml:"deprecation"`
+	Area        AreaConfig        `yaml:"area"`
+	// Activities are the profiles of /weather/activity by activity name.
+	Activities map[string]ActivityProfile `yaml:"activities"`
+}
+
+// ActivityProfile defines when the weather suits an activity, temperatures are
+// in degC, wind speeds in km/h and the precipitation probability in %. Unset
+// thresholds are not checked. Avoid keywords, e.g. thunderstorms, lower the
+// score of the days whose forecast text mentions them while Prefer ones
+// raise it.
+type ActivityProfile struct {
+	MinTemperature   *float64 `yaml:"mintemperature"`
+	MaxTemperature   *float64 `yaml:"maxtemperature"`
+	MaxWind          *float64 `yaml:"maxwind"`
+	MaxPrecipitation *float64 `yaml:"maxprecipitation"`
+	Avoid            []string `yaml:"avoid"`
+	Prefer           []string `yaml:"prefer"`
+}
+
+// validate checks the thresholds are consistent.
+func (p ActivityProfile) validate() error {
+	if p.MinTemperature != nil && p.MaxTemperature != nil && *p.MinTemperature > *p.MaxTemperature {
+		return fmt.Errorf("mintemperature %v must not exceed maxtemperature %v", *p.MinTemperature, *p.MaxTemperature)
+	}
+	if p.MaxWind != nil && *p.MaxWind < 0 {
+		return fmt.Errorf("maxwind %v must not be negative", *p.MaxWind)
+	}
+	if p.MaxPrecipitation != nil && (*p.MaxPrecipitation < 0 || *p.MaxPrecipitation > 100) {
+		return fmt.Errorf("maxprecipitation %v must be between 0 and 100", *p.MaxPrecipitation)
+	}
+	return nil
+}
+
+// AreaConfig defines the bounding box and nearby forecasts, MaxLimit caps how
+// many locations are forecast and MaxFetches how many of them may call
+// weather.gov per request. MaxRadius caps the nearby radius in km. Zero means
+// no cap.
+type AreaConfig struct {
+	MaxLimit   int     `yaml:"maxlimit"`
+	MaxFetches int     `yaml:"maxfetches"`
+	MaxRadius  float64 `yaml:"maxradius"`
+}
+
+// DeprecationConfig defines when the deprecated routes were deprecated and
+// when they will be removed, both are YYYY-MM-DD dates in UTC.
+type DeprecationConfig struct {
+	Date   string `yaml:"date"`
+	Sunset string `yaml:"sunset"`
+}
+
+// Dates parses the deprecation and sunset dates, unset dates are zero.
+func (c DeprecationConfig) Dates() (deprecated, sunset time.Time, err error) {
+	if c.Date != "" {
+		if deprecated, err = time.Parse(time.DateOnly, c.Date); err != nil {
+			return deprecated, sunset, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", c.Date)
+		}
+	}
+	if c.Sunset != "" {
+		if sunset, err = time.Parse(time.DateOnly, c.Sunset); err != nil {
+			return deprecated, sunset, fmt.Errorf("invalid sunset %q, expected YYYY-MM-DD", c.Sunset)
+		}
+	}
+	if !deprecated.IsZero() && !sunset.IsZero() && !sunset.After(deprecated) {
+		return deprecated, sunset, fmt.Errorf("sunset %s must be after the deprecation date %s", c.Sunset, c.Date)
+	}
+	return deprecated, sunset, nil
+}
+
+// CORSConfig defines the cross-origin requests policy, origins are exact
+// origins or subdomain wildcards like https://*.example.com. Methods default
+// to the ones of the registered routes, MaxAge is in seconds. CORS is
+// disabled when no origins are set.
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowedorigins"`
+	AllowedMethods   []string `yaml:"allowedmethods"`
+	AllowedHeaders   []string `yaml:"allowedheaders"`
+	AllowCredentials bool     `yaml:"allowcredentials"`
+	MaxAge           int      `yaml:"maxage"`
+}
+
+// AllowsOrigin reports whether the origin is one of the allowed origins or
+// matches one of their wildcards.
+func (c CORSConfig) AllowsOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range c.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(allowed, "*"); ok &&
+			len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitConfig defines the per client IP rate limits, X-Forwarded-For is
+// only honored from the trusted proxies, given as IPs or CIDRs. Client
+// buckets unused for IdleTimeout seconds are dropped.
+type RateLimitConfig struct {
+	TrustedProxies []string              `yaml:"trustedproxies"`
+	IdleTimeout    int                   `yaml:"idletimeout"`
+	Default        RouteLimit            `yaml:"default"`
+	Routes         map[string]RouteLimit `yaml:"routes"`
+}
+
+// RouteLimit defines a token bucket refilled with Rate tokens per second up
+// to Burst tokens, a request costs one token per requested city. A zero rate
+// means unlimited.
+type RouteLimit struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// batchRoutes are the rate-limited routes taking up to api.maxbatchsize
+// cities, locations or legs per request.
+var batchRoutes = []string{"/weather", "/alerts", "/current"}
+
+// Limit returns the limit of the route, the default one when the route has
+// none.
+func (c RateLimitConfig) Limit(route string) RouteLimit {
+	if limit, ok := c.Routes[route]; ok {
+		return limit
+	}
+	return c.Default
+}
+
+// validate checks a full batch fits in the bucket of every batch route, a
+// request costing more than the burst could never be allowed.
+func (c RateLimitConfig) validate(maxBatchSize int) error {
+	for _, route := range batchRoutes {
+		limit := c.Limit(route)
+		if limit.Rate > 0 && limit.Burst < maxBatchSize {
+			return fmt.Errorf("%s burst %d must not be less than api.maxbatchsize %d", route, limit.Burst, maxBatchSize)
+		}
+	}
+	return nil
 }
 
 type OpenstreetmapAPIConfig struct {
@@ -20,3 +278,114 @@ type WeatherAPIConfig struct {
 	URL     string `yaml:"host"`
 	Timeout int    `yaml:"timeout"`
 }
+
+// CacheConfig defines the in-memory cache settings, TTLs are in seconds.
+type CacheConfig struct {
+	AlertsTTL   int `yaml:"alertsttl"`
+	ForecastTTL int `yaml:"forecastttl"`
+	// StaleWhileRevalidate is how long past the forecast TTL an entry is
+	// served while refreshed in background, MaxStaleness is how long past the
+	// TTL an entry is served when weather.gov fails.
+	StaleWhileRevalidate int `yaml:"stalewhilerevalidate"`
+	MaxStaleness         int `yaml:"maxstaleness"`
+}
+
+// validate checks the TTLs are positive and the max staleness covers the
+// stale-while-revalidate window.
+func (c CacheConfig) validate() error {
+	if c.ForecastTTL <= 0 {
+		return fmt.Errorf("forecastttl %d must be positive", c.ForecastTTL)
+	}
+	if c.AlertsTTL <= 0 {
+		return fmt.Errorf("alertsttl %d must be positive", c.AlertsTTL)
+	}
+	if c.StaleWhileRevalidate < 0 {
+		return fmt.Errorf("stalewhilerevalidate %d must not be negative", c.StaleWhileRevalidate)
+	}
+	if c.MaxStaleness < c.StaleWhileRevalidate {
+		return fmt.Errorf("maxstaleness %d must not be less than stalewhilerevalidate %d", c.MaxStaleness, c.StaleWhileRevalidate)
+	}
+	return nil
+}
+
+// LiveConfig defines the WebSocket live updates settings, intervals are in seconds.
+type LiveConfig struct {
+	RefreshInterval int `yaml:"refreshinterval"`
+	PingInterval    int `yaml:"pinginterval"`
+	SendBuffer      int `yaml:"sendbuffer"`
+	MaxCities       int `yaml:"maxcities"`
+}
+
+// validate checks the intervals can drive a ticker and that every client can
+// queue and subscribe to at least one message and city.
+func (c LiveConfig) validate() error {
+	if c.RefreshInterval <= 0 {
+		return fmt.Errorf("refreshinterval %d must be positive", c.RefreshInterval)
+	}
+	if c.PingInterval <= 0 {
+		return fmt.Errorf("pinginterval %d must be positive", c.PingInterval)
+	}
+	if c.SendBuffer < 1 {
+		return fmt.Errorf("sendbuffer %d must be at least 1", c.SendBuffer)
+	}
+	if c.MaxCities < 1 {
+		return fmt.Errorf("maxcities %d must be at least 1", c.MaxCities)
+	}
+	return nil
+}
+
+// RulesConfig defines the webhook rules settings, the rules are disabled when
+// no signing secret is set so webhooks are never sent unsigned. StoreFile
+// empty keeps the rules in memory only, intervals and timeouts are in seconds.
+type RulesConfig struct {
+	StoreFile     string `yaml:"storefile"`
+	Interval      int    `yaml:"interval"`
+	SigningSecret string `yaml:"signingsecret"`
+	MaxAttempts   int    `yaml:"maxattempts"`
+	RetryBackoff  int    `yaml:"retrybackoff"`
+	Timeout       int    `yaml:"timeout"`
+}
+
+// validate checks the interval can drive the scheduler ticker and that every
+// webhook is attempted at least once, it only matters when the rules are on.
+func (c RulesConfig) validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval %d must be positive", c.Interval)
+	}
+	if c.MaxAttempts < 1 {
+		return fmt.Errorf("maxattempts %d must be at least 1", c.MaxAttempts)
+	}
+	return nil
+}
+
+// WarmupConfig defines the cache warmer settings, places are refreshed
+// RefreshBefore seconds before their forecast TTL lapses, +/- Jitter seconds.
+// RateLimit is the max number of places refreshed per second.
+type WarmupConfig struct {
+	RefreshBefore int           `yaml:"refreshbefore"`
+	Jitter        int           `yaml:"jitter"`
+	RateLimit     float64       `yaml:"ratelimit"`
+	Places        []WarmupPlace `yaml:"places"`
+}
+
+// WarmupPlace defines a place to keep warm, either a name with an optional
+// ISO 3166-1 alpha-2 country code or a pair of coordinates.
+type WarmupPlace struct {
+	Name    string   `yaml:"name"`
+	Country string   `yaml:"country"`
+	Lat     *float64 `yaml:"lat"`
+	Lon     *float64 `yaml:"lon"`
+}
+
+// AdminConfig defines the admin API settings, the API is disabled when no
+// token is set.
+type AdminConfig struct {
+	Token            string `yaml:"token"`
+	MaxSnapshotBytes int64  `yaml:"maxsnapshotbytes"`
+}
+
+// AuthConfig defines the API keys settings, the API is open when no keys
+// file is set.
+type AuthConfig struct {
+	KeysFile string `yaml:"keysfile"`
+}
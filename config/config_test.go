@@ -0,0 +1,318 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// apiConfig, cacheConfig and liveConfig are valid configurations, the tests
+// only change the settings they check.
+var (
+	apiConfig   = APIConfig{MaxBatchSize: 50, MaxBodyBytes: 65536}
+	cacheConfig = CacheConfig{ForecastTTL: 1800, AlertsTTL: 60, StaleWhileRevalidate: 60, MaxStaleness: 3600}
+	liveConfig  = LiveConfig{RefreshInterval: 60, PingInterval: 30, SendBuffer: 16, MaxCities: 20}
+)
+
+func TestValidate(t *testing.T) {
+	tcs := []struct {
+		name    string
+		cfg     APIConfig
+		wantErr bool
+	}{
+		{
+			name: "when exact and subdomain wildcard origins should be valid",
+			cfg: APIConfig{CORS: CORSConfig{
+				AllowedOrigins: []string{"http://localhost:3000", "https://*.example.com"},
+			}},
+		},
+		{
+			name: "when credentials with exact origins should be valid",
+			cfg: APIConfig{CORS: CORSConfig{
+				AllowedOrigins:   []string{"https://app.example.com"},
+				AllowCredentials: true,
+			}},
+		},
+		{
+			name: "when credentials with wildcard origin should fail",
+			cfg: APIConfig{CORS: CORSConfig{
+				AllowedOrigins:   []string{"https://*.example.com"},
+				AllowCredentials: true,
+			}},
+			wantErr: true,
+		},
+		{
+			name: "when credentials with any origin should fail",
+			cfg: APIConfig{CORS: CORSConfig{
+				AllowedOrigins:   []string{"*"},
+				AllowCredentials: true,
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "when wildcard is not a subdomain should fail",
+			cfg:     APIConfig{CORS: CORSConfig{AllowedOrigins: []string{"https://*example.com"}}},
+			wantErr: true,
+		},
+		{
+			name:    "when origin has a path should fail",
+			cfg:     APIConfig{CORS: CORSConfig{AllowedOrigins: []string{"https://example.com/app"}}},
+			wantErr: true,
+		},
+		{
+			name:    "when trusted proxy is invalid should fail",
+			cfg:     APIConfig{RateLimit: RateLimitConfig{TrustedProxies: []string{"10.0.0.0/33"}}},
+			wantErr: true,
+		},
+		{
+			name: "when trusted proxies are IPs and CIDRs should be valid",
+			cfg:  APIConfig{RateLimit: RateLimitConfig{TrustedProxies: []string{"127.0.0.1", "::1", "10.0.0.0/8"}}},
+		},
+		{
+			name: "when deprecation and sunset dates are set should be valid",
+			cfg:  APIConfig{Deprecation: DeprecationConfig{Date: "2026-10-19", Sunset: "2027-04-30"}},
+		},
+		{
+			name:    "when deprecation date is not a date should fail",
+			cfg:     APIConfig{Deprecation: DeprecationConfig{Date: "19/10/2026"}},
+			wantErr: true,
+		},
+		{
+			name:    "when sunset is before the deprecation should fail",
+			cfg:     APIConfig{Deprecation: DeprecationConfig{Date: "2026-10-19", Sunset: "2026-01-01"}},
+			wantErr: true,
+		},
+		{
+			name:    "when max batch size is zero should fail",
+			cfg:     APIConfig{MaxBodyBytes: 65536},
+			wantErr: true,
+		},
+		{
+			name:    "when max body bytes is negative should fail",
+			cfg:     APIConfig{MaxBatchSize: 50, MaxBodyBytes: -1},
+			wantErr: true,
+		},
+		{
+			name: "when activity thresholds are consistent should be valid",
+			cfg: APIConfig{Activities: map[string]ActivityProfile{
+				"running": {MinTemperature: ptr(5.0), MaxTemperature: ptr(22.0), MaxWind: ptr(30.0), MaxPrecipitation: ptr(40.0)},
+			}},
+		},
+		{
+			name: "when activity min temperature exceeds the max should fail",
+			cfg: APIConfig{Activities: map[string]ActivityProfile{
+				"running": {MinTemperature: ptr(25.0), MaxTemperature: ptr(22.0)},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "when activity precipitation is over 100% should fail",
+			cfg:     APIConfig{Activities: map[string]ActivityProfile{"beach": {MaxPrecipitation: ptr(120.0)}}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			if cfg.MaxBatchSize == 0 && cfg.MaxBodyBytes == 0 {
+				cfg.MaxBatchSize, cfg.MaxBodyBytes = apiConfig.MaxBatchSize, apiConfig.MaxBodyBytes
+			}
+			err := ServiceConfig{APIConfig: cfg, CacheConfig: cacheConfig, LiveConfig: liveConfig}.Validate()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateSecrets(t *testing.T) {
+	tcs := []struct {
+		name    string
+		cfg     ServiceConfig
+		wantErr bool
+	}{
+		{
+			name: "when admin token is empty should be valid",
+			cfg:  ServiceConfig{},
+		},
+		{
+			name: "when admin token is set should be valid",
+			cfg:  ServiceConfig{AdminConfig: AdminConfig{Token: "9f2c1e0b7a4d"}},
+		},
+		{
+			name: "when signing secret is set should be valid",
+			cfg:  ServiceConfig{RulesConfig: RulesConfig{SigningSecret: "3b9d6f0e1c2a", Interval: 300, MaxAttempts: 4}},
+		},
+		{
+			name: "when signing secret is empty should not check the rules settings",
+			cfg:  ServiceConfig{RulesConfig: RulesConfig{}},
+		},
+		{
+			name:    "when signing secret is set without interval should fail",
+			cfg:     ServiceConfig{RulesConfig: RulesConfig{SigningSecret: "3b9d6f0e1c2a", MaxAttempts: 4}},
+			wantErr: true,
+		},
+		{
+			name:    "when signing secret is set without attempts should fail",
+			cfg:     ServiceConfig{RulesConfig: RulesConfig{SigningSecret: "3b9d6f0e1c2a", Interval: 300}},
+			wantErr: true,
+		},
+		{
+			name:    "when signing secret is a placeholder should fail",
+			cfg:     ServiceConfig{RulesConfig: RulesConfig{SigningSecret: "change-me"}},
+			wantErr: true,
+		},
+		{
+			name:    "when admin token is a placeholder should fail",
+			cfg:     ServiceConfig{AdminConfig: AdminConfig{Token: "Change-Me"}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			cfg.APIConfig, cfg.CacheConfig, cfg.LiveConfig = apiConfig, cacheConfig, liveConfig
+			err := cfg.Validate()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateRateLimit(t *testing.T) {
+	tcs := []struct {
+		name      string
+		rateLimit RateLimitConfig
+		wantErr   bool
+	}{
+		{
+			name:      "when the bursts fit a full batch should be valid",
+			rateLimit: RateLimitConfig{Default: RouteLimit{Rate: 5, Burst: 50}, Routes: map[string]RouteLimit{"/weather/area": {Rate: 1, Burst: 5}}},
+		},
+		{
+			name:      "when a batch route is unlimited should be valid",
+			rateLimit: RateLimitConfig{Routes: map[string]RouteLimit{"/alerts": {}}},
+		},
+		{
+			name:      "when the default burst is less than a full batch should fail",
+			rateLimit: RateLimitConfig{Default: RouteLimit{Rate: 5, Burst: 20}, Routes: map[string]RouteLimit{"/weather": {Rate: 10, Burst: 50}}},
+			wantErr:   true,
+		},
+		{
+			name:      "when a batch route burst is less than a full batch should fail",
+			rateLimit: RateLimitConfig{Default: RouteLimit{Rate: 5, Burst: 50}, Routes: map[string]RouteLimit{"/current": {Rate: 2, Burst: 10}}},
+			wantErr:   true,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := ServiceConfig{APIConfig: apiConfig, CacheConfig: cacheConfig, LiveConfig: liveConfig}
+			cfg.APIConfig.RateLimit = tc.rateLimit
+			err := cfg.Validate()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateCache(t *testing.T) {
+	tcs := []struct {
+		name    string
+		cfg     CacheConfig
+		wantErr bool
+	}{
+		{
+			name: "when TTLs are positive should be valid",
+			cfg:  cacheConfig,
+		},
+		{
+			name: "when stale windows are unset should be valid",
+			cfg:  CacheConfig{ForecastTTL: 1800, AlertsTTL: 60},
+		},
+		{
+			name:    "when forecast TTL is zero should fail",
+			cfg:     CacheConfig{AlertsTTL: 60},
+			wantErr: true,
+		},
+		{
+			name:    "when alerts TTL is zero should fail",
+			cfg:     CacheConfig{ForecastTTL: 1800},
+			wantErr: true,
+		},
+		{
+			name:    "when max staleness is shorter than stale while revalidate should fail",
+			cfg:     CacheConfig{ForecastTTL: 1800, AlertsTTL: 60, StaleWhileRevalidate: 600, MaxStaleness: 60},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := ServiceConfig{APIConfig: apiConfig, CacheConfig: tc.cfg, LiveConfig: liveConfig}.Validate()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateLive(t *testing.T) {
+	tcs := []struct {
+		name    string
+		cfg     LiveConfig
+		wantErr bool
+	}{
+		{
+			name: "when intervals and buffer are positive should be valid",
+			cfg:  liveConfig,
+		},
+		{
+			name:    "when refresh interval is zero should fail",
+			cfg:     LiveConfig{PingInterval: 30, SendBuffer: 16, MaxCities: 20},
+			wantErr: true,
+		},
+		{
+			name:    "when ping interval is zero should fail",
+			cfg:     LiveConfig{RefreshInterval: 60, SendBuffer: 16, MaxCities: 20},
+			wantErr: true,
+		},
+		{
+			name:    "when send buffer is zero should fail",
+			cfg:     LiveConfig{RefreshInterval: 60, PingInterval: 30, MaxCities: 20},
+			wantErr: true,
+		},
+		{
+			name:    "when max cities is zero should fail",
+			cfg:     LiveConfig{RefreshInterval: 60, PingInterval: 30, SendBuffer: 16},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := ServiceConfig{APIConfig: apiConfig, CacheConfig: cacheConfig, LiveConfig: tc.cfg}.Validate()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/dibrito/ennismore-weather-app/internal/controller (interfaces: AlertsGateway)
+//
+// Generated by this command:
+//
+//	mockgen -package alerts_mock --destination=./gen/mock/clients/alerts/alerts_mock.go github.com/dibrito/ennismore-weather-app/internal/controller AlertsGateway
+//
+
+// Package alerts_mock is a generated GoMock package.
+package alerts_mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/dibrito/ennismore-weather-app/pkg/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAlertsGateway is a mock of AlertsGateway interface.
+type MockAlertsGateway struct {
+	ctrl     *gomock.Controller
+	recorder *MockAlertsGatewayMockRecorder
+}
+
+// MockAlertsGatewayMockRecorder is the mock recorder for MockAlertsGateway.
+type MockAlertsGatewayMockRecorder struct {
+	mock *MockAlertsGateway
+}
+
+// NewMockAlertsGateway creates a new mock instance.
+func NewMockAlertsGateway(ctrl *gomock.Controller) *MockAlertsGateway {
+	mock := &MockAlertsGateway{ctrl: ctrl}
+	mock.recorder = &MockAlertsGatewayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAlertsGateway) EXPECT() *MockAlertsGatewayMockRecorder {
+	return m.recorder
+}
+
+// GetAlerts mocks base method.
+func (m *MockAlertsGateway) GetAlerts(arg0 context.Context, arg1, arg2 string) ([]model.Alert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlerts", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.Alert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlerts indicates an expected call of GetAlerts.
+func (mr *MockAlertsGatewayMockRecorder) GetAlerts(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlerts", reflect.TypeOf((*MockAlertsGateway)(nil).GetAlerts), arg0, arg1, arg2)
+}
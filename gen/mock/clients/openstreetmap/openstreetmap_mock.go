@@ -41,16 +41,16 @@ func (m *MockOpenstreetmapperGateway) EXPECT() *MockOpenstreetmapperGatewayMockR
 }
 
 // GetLocation mocks base method.
-func (m *MockOpenstreetmapperGateway) GetLocation(arg0 context.Context, arg1 string) ([]model.Location, error) {
+func (m *MockOpenstreetmapperGateway) GetLocation(arg0 context.Context, arg1, arg2 string) ([]model.Location, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetLocation", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetLocation", arg0, arg1, arg2)
 	ret0, _ := ret[0].([]model.Location)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetLocation indicates an expected call of GetLocation.
-func (mr *MockOpenstreetmapperGatewayMockRecorder) GetLocation(arg0, arg1 any) *gomock.Call {
+func (mr *MockOpenstreetmapperGatewayMockRecorder) GetLocation(arg0, arg1, arg2 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocation", reflect.TypeOf((*MockOpenstreetmapperGateway)(nil).GetLocation), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocation", reflect.TypeOf((*MockOpenstreetmapperGateway)(nil).GetLocation), arg0, arg1, arg2)
 }
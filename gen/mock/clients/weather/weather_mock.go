@@ -54,3 +54,48 @@ func (mr *MockWeatherGatewayMockRecorder) GetForecast(arg0, arg1, arg2 any) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetForecast", reflect.TypeOf((*MockWeatherGateway)(nil).GetForecast), arg0, arg1, arg2)
 }
+
+// GetGridpoint mocks base method.
+func (m *MockWeatherGateway) GetGridpoint(arg0 context.Context, arg1, arg2 string) (map[string]model.GridpointLayer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGridpoint", arg0, arg1, arg2)
+	ret0, _ := ret[0].(map[string]model.GridpointLayer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGridpoint indicates an expected call of GetGridpoint.
+func (mr *MockWeatherGatewayMockRecorder) GetGridpoint(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGridpoint", reflect.TypeOf((*MockWeatherGateway)(nil).GetGridpoint), arg0, arg1, arg2)
+}
+
+// GetLatestObservation mocks base method.
+func (m *MockWeatherGateway) GetLatestObservation(arg0 context.Context, arg1 string) (model.Observation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestObservation", arg0, arg1)
+	ret0, _ := ret[0].(model.Observation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestObservation indicates an expected call of GetLatestObservation.
+func (mr *MockWeatherGatewayMockRecorder) GetLatestObservation(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestObservation", reflect.TypeOf((*MockWeatherGateway)(nil).GetLatestObservation), arg0, arg1)
+}
+
+// GetStations mocks base method.
+func (m *MockWeatherGateway) GetStations(arg0 context.Context, arg1, arg2 string) ([]model.Station, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStations", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.Station)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStations indicates an expected call of GetStations.
+func (mr *MockWeatherGatewayMockRecorder) GetStations(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStations", reflect.TypeOf((*MockWeatherGateway)(nil).GetStations), arg0, arg1, arg2)
+}
@@ -12,8 +12,13 @@ package controller_mock
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
+	config "github.com/dibrito/ennismore-weather-app/config"
+	controller "github.com/dibrito/ennismore-weather-app/internal/controller"
+	geo "github.com/dibrito/ennismore-weather-app/pkg/geo"
 	model "github.com/dibrito/ennismore-weather-app/pkg/model"
+	units "github.com/dibrito/ennismore-weather-app/pkg/units"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -40,31 +45,166 @@ func (m *MockServiceController) EXPECT() *MockServiceControllerMockRecorder {
 	return m.recorder
 }
 
-// GetCache mocks base method.
-func (m *MockServiceController) GetCache() model.CacheResponse {
+// CompareForecast mocks base method.
+func (m *MockServiceController) CompareForecast(arg0 context.Context, arg1 []string, arg2 string, arg3 time.Time, arg4 controller.ForecastOptions) (model.Comparison, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCache")
-	ret0, _ := ret[0].(model.CacheResponse)
-	return ret0
+	ret := m.ctrl.Call(m, "CompareForecast", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(model.Comparison)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompareForecast indicates an expected call of CompareForecast.
+func (mr *MockServiceControllerMockRecorder) CompareForecast(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompareForecast", reflect.TypeOf((*MockServiceController)(nil).CompareForecast), arg0, arg1, arg2, arg3, arg4)
+}
+
+// GetActivityForecast mocks base method.
+func (m *MockServiceController) GetActivityForecast(arg0 context.Context, arg1 []string, arg2 string, arg3 config.ActivityProfile, arg4 controller.ForecastOptions) (model.ActivityForecast, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActivityForecast", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(model.ActivityForecast)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActivityForecast indicates an expected call of GetActivityForecast.
+func (mr *MockServiceControllerMockRecorder) GetActivityForecast(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActivityForecast", reflect.TypeOf((*MockServiceController)(nil).GetActivityForecast), arg0, arg1, arg2, arg3, arg4)
+}
+
+// GetAlerts mocks base method.
+func (m *MockServiceController) GetAlerts(arg0 context.Context, arg1 []string) (model.WeatherAlerts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlerts", arg0, arg1)
+	ret0, _ := ret[0].(model.WeatherAlerts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// GetCache indicates an expected call of GetCache.
-func (mr *MockServiceControllerMockRecorder) GetCache() *gomock.Call {
+// GetAlerts indicates an expected call of GetAlerts.
+func (mr *MockServiceControllerMockRecorder) GetAlerts(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCache", reflect.TypeOf((*MockServiceController)(nil).GetCache))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlerts", reflect.TypeOf((*MockServiceController)(nil).GetAlerts), arg0, arg1)
+}
+
+// GetAreaForecast mocks base method.
+func (m *MockServiceController) GetAreaForecast(arg0 context.Context, arg1 geo.BBox, arg2, arg3 int, arg4 controller.ForecastOptions) (controller.AreaForecast, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAreaForecast", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(controller.AreaForecast)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAreaForecast indicates an expected call of GetAreaForecast.
+func (mr *MockServiceControllerMockRecorder) GetAreaForecast(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAreaForecast", reflect.TypeOf((*MockServiceController)(nil).GetAreaForecast), arg0, arg1, arg2, arg3, arg4)
+}
+
+// GetBatchForecast mocks base method.
+func (m *MockServiceController) GetBatchForecast(arg0 context.Context, arg1 []model.LocationQuery, arg2 controller.ForecastOptions) (model.WeatherForecast, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBatchForecast", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.WeatherForecast)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBatchForecast indicates an expected call of GetBatchForecast.
+func (mr *MockServiceControllerMockRecorder) GetBatchForecast(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBatchForecast", reflect.TypeOf((*MockServiceController)(nil).GetBatchForecast), arg0, arg1, arg2)
+}
+
+// GetCurrentConditions mocks base method.
+func (m *MockServiceController) GetCurrentConditions(arg0 context.Context, arg1 []string, arg2 units.System) (model.CurrentConditions, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCurrentConditions", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.CurrentConditions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCurrentConditions indicates an expected call of GetCurrentConditions.
+func (mr *MockServiceControllerMockRecorder) GetCurrentConditions(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCurrentConditions", reflect.TypeOf((*MockServiceController)(nil).GetCurrentConditions), arg0, arg1, arg2)
 }
 
 // GetForecast mocks base method.
-func (m *MockServiceController) GetForecast(arg0 context.Context, arg1 []string) (model.WeatherForecast, error) {
+func (m *MockServiceController) GetForecast(arg0 context.Context, arg1 []string, arg2 controller.ForecastOptions) (model.WeatherForecast, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetForecast", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetForecast", arg0, arg1, arg2)
 	ret0, _ := ret[0].(model.WeatherForecast)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetForecast indicates an expected call of GetForecast.
-func (mr *MockServiceControllerMockRecorder) GetForecast(arg0, arg1 any) *gomock.Call {
+func (mr *MockServiceControllerMockRecorder) GetForecast(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetForecast", reflect.TypeOf((*MockServiceController)(nil).GetForecast), arg0, arg1, arg2)
+}
+
+// GetGridForecast mocks base method.
+func (m *MockServiceController) GetGridForecast(arg0 context.Context, arg1, arg2 []string) (model.GridForecast, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGridForecast", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.GridForecast)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGridForecast indicates an expected call of GetGridForecast.
+func (mr *MockServiceControllerMockRecorder) GetGridForecast(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGridForecast", reflect.TypeOf((*MockServiceController)(nil).GetGridForecast), arg0, arg1, arg2)
+}
+
+// GetItineraryForecast mocks base method.
+func (m *MockServiceController) GetItineraryForecast(arg0 context.Context, arg1 []controller.Leg, arg2 controller.ForecastOptions) (model.Itinerary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetItineraryForecast", arg0, arg1, arg2)
+	ret0, _ := ret[0].(model.Itinerary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetItineraryForecast indicates an expected call of GetItineraryForecast.
+func (mr *MockServiceControllerMockRecorder) GetItineraryForecast(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItineraryForecast", reflect.TypeOf((*MockServiceController)(nil).GetItineraryForecast), arg0, arg1, arg2)
+}
+
+// GetNearbyForecast mocks base method.
+func (m *MockServiceController) GetNearbyForecast(arg0 context.Context, arg1 geo.Point, arg2 float64, arg3, arg4 int, arg5 controller.ForecastOptions) (controller.AreaForecast, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNearbyForecast", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(controller.AreaForecast)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNearbyForecast indicates an expected call of GetNearbyForecast.
+func (mr *MockServiceControllerMockRecorder) GetNearbyForecast(arg0, arg1, arg2, arg3, arg4, arg5 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNearbyForecast", reflect.TypeOf((*MockServiceController)(nil).GetNearbyForecast), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// StreamForecast mocks base method.
+func (m *MockServiceController) StreamForecast(arg0 context.Context, arg1 []model.LocationQuery, arg2 controller.ForecastOptions) <-chan controller.ForecastResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamForecast", arg0, arg1, arg2)
+	ret0, _ := ret[0].(<-chan controller.ForecastResult)
+	return ret0
+}
+
+// StreamForecast indicates an expected call of StreamForecast.
+func (mr *MockServiceControllerMockRecorder) StreamForecast(arg0, arg1, arg2 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetForecast", reflect.TypeOf((*MockServiceController)(nil).GetForecast), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamForecast", reflect.TypeOf((*MockServiceController)(nil).StreamForecast), arg0, arg1, arg2)
 }
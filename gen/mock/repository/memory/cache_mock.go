@@ -12,6 +12,8 @@ package cache_mock
 import (
 	reflect "reflect"
 
+	respository "github.com/dibrito/ennismore-weather-app/internal/repository"
+	geo "github.com/dibrito/ennismore-weather-app/pkg/geo"
 	model "github.com/dibrito/ennismore-weather-app/pkg/model"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,18 +41,19 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
-// GetCache mocks base method.
-func (m *MockRepository) GetCache() model.CacheResponse {
+// GetAlerts mocks base method.
+func (m *MockRepository) GetAlerts(arg0 string) ([]model.Alert, bool) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCache")
-	ret0, _ := ret[0].(model.CacheResponse)
-	return ret0
+	ret := m.ctrl.Call(m, "GetAlerts", arg0)
+	ret0, _ := ret[0].([]model.Alert)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
 }
 
-// GetCache indicates an expected call of GetCache.
-func (mr *MockRepositoryMockRecorder) GetCache() *gomock.Call {
+// GetAlerts indicates an expected call of GetAlerts.
+func (mr *MockRepositoryMockRecorder) GetAlerts(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCache", reflect.TypeOf((*MockRepository)(nil).GetCache))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlerts", reflect.TypeOf((*MockRepository)(nil).GetAlerts), arg0)
 }
 
 // GetLocation mocks base method.
@@ -69,10 +72,10 @@ func (mr *MockRepositoryMockRecorder) GetLocation(arg0 any) *gomock.Call {
 }
 
 // GetPeriods mocks base method.
-func (m *MockRepository) GetPeriods(arg0, arg1 string) (model.Period, bool) {
+func (m *MockRepository) GetPeriods(arg0, arg1 string) (respository.CachedPeriod, bool) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetPeriods", arg0, arg1)
-	ret0, _ := ret[0].(model.Period)
+	ret0, _ := ret[0].(respository.CachedPeriod)
 	ret1, _ := ret[1].(bool)
 	return ret0, ret1
 }
@@ -83,6 +86,47 @@ func (mr *MockRepositoryMockRecorder) GetPeriods(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPeriods", reflect.TypeOf((*MockRepository)(nil).GetPeriods), arg0, arg1)
 }
 
+// GetStation mocks base method.
+func (m *MockRepository) GetStation(arg0 string) (model.Station, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStation", arg0)
+	ret0, _ := ret[0].(model.Station)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// GetStation indicates an expected call of GetStation.
+func (mr *MockRepositoryMockRecorder) GetStation(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStation", reflect.TypeOf((*MockRepository)(nil).GetStation), arg0)
+}
+
+// NearestLocations mocks base method.
+func (m *MockRepository) NearestLocations(arg0 geo.Point, arg1 float64, arg2 int) []geo.Neighbor {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NearestLocations", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]geo.Neighbor)
+	return ret0
+}
+
+// NearestLocations indicates an expected call of NearestLocations.
+func (mr *MockRepositoryMockRecorder) NearestLocations(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NearestLocations", reflect.TypeOf((*MockRepository)(nil).NearestLocations), arg0, arg1, arg2)
+}
+
+// PutAlerts mocks base method.
+func (m *MockRepository) PutAlerts(arg0 string, arg1 []model.Alert) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PutAlerts", arg0, arg1)
+}
+
+// PutAlerts indicates an expected call of PutAlerts.
+func (mr *MockRepositoryMockRecorder) PutAlerts(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutAlerts", reflect.TypeOf((*MockRepository)(nil).PutAlerts), arg0, arg1)
+}
+
 // PutLocation mocks base method.
 func (m *MockRepository) PutLocation(arg0 string, arg1 model.Location) {
 	m.ctrl.T.Helper()
@@ -106,3 +150,29 @@ func (mr *MockRepositoryMockRecorder) PutPeriods(arg0, arg1, arg2 any) *gomock.C
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutPeriods", reflect.TypeOf((*MockRepository)(nil).PutPeriods), arg0, arg1, arg2)
 }
+
+// PutStation mocks base method.
+func (m *MockRepository) PutStation(arg0 string, arg1 model.Station) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PutStation", arg0, arg1)
+}
+
+// PutStation indicates an expected call of PutStation.
+func (mr *MockRepositoryMockRecorder) PutStation(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutStation", reflect.TypeOf((*MockRepository)(nil).PutStation), arg0, arg1)
+}
+
+// SearchLocations mocks base method.
+func (m *MockRepository) SearchLocations(arg0 geo.BBox) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchLocations", arg0)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// SearchLocations indicates an expected call of SearchLocations.
+func (mr *MockRepositoryMockRecorder) SearchLocations(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchLocations", reflect.TypeOf((*MockRepository)(nil).SearchLocations), arg0)
+}
@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/dibrito/ennismore-weather-app/internal/rules (interfaces: WeatherProvider)
+//
+// Generated by this command:
+//
+//	mockgen -package rules_mock --destination=./gen/mock/rules/rules_mock.go github.com/dibrito/ennismore-weather-app/internal/rules WeatherProvider
+//
+
+// Package rules_mock is a generated GoMock package.
+package rules_mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/dibrito/ennismore-weather-app/pkg/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockWeatherProvider is a mock of WeatherProvider interface.
+type MockWeatherProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockWeatherProviderMockRecorder
+}
+
+// MockWeatherProviderMockRecorder is the mock recorder for MockWeatherProvider.
+type MockWeatherProviderMockRecorder struct {
+	mock *MockWeatherProvider
+}
+
+// NewMockWeatherProvider creates a new mock instance.
+func NewMockWeatherProvider(ctrl *gomock.Controller) *MockWeatherProvider {
+	mock := &MockWeatherProvider{ctrl: ctrl}
+	mock.recorder = &MockWeatherProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWeatherProvider) EXPECT() *MockWeatherProviderMockRecorder {
+	return m.recorder
+}
+
+// GetAlerts mocks base method.
+func (m *MockWeatherProvider) GetAlerts(arg0 context.Context, arg1 []string) (model.WeatherAlerts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlerts", arg0, arg1)
+	ret0, _ := ret[0].(model.WeatherAlerts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlerts indicates an expected call of GetAlerts.
+func (mr *MockWeatherProviderMockRecorder) GetAlerts(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlerts", reflect.TypeOf((*MockWeatherProvider)(nil).GetAlerts), arg0, arg1)
+}
+
+// RefreshPeriods mocks base method.
+func (m *MockWeatherProvider) RefreshPeriods(arg0 context.Context, arg1 string) ([]model.Period, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshPeriods", arg0, arg1)
+	ret0, _ := ret[0].([]model.Period)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshPeriods indicates an expected call of RefreshPeriods.
+func (mr *MockWeatherProviderMockRecorder) RefreshPeriods(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshPeriods", reflect.TypeOf((*MockWeatherProvider)(nil).RefreshPeriods), arg0, arg1)
+}
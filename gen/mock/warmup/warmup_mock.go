@@ -0,0 +1,55 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/dibrito/ennismore-weather-app/internal/warmup (interfaces: Refresher)
+//
+// Generated by this command:
+//
+//	mockgen -package warmup_mock --destination=./gen/mock/warmup/warmup_mock.go github.com/dibrito/ennismore-weather-app/internal/warmup Refresher
+//
+
+// Package warmup_mock is a generated GoMock package.
+package warmup_mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/dibrito/ennismore-weather-app/pkg/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRefresher is a mock of Refresher interface.
+type MockRefresher struct {
+	ctrl     *gomock.Controller
+	recorder *MockRefresherMockRecorder
+}
+
+// MockRefresherMockRecorder is the mock recorder for MockRefresher.
+type MockRefresherMockRecorder struct {
+	mock *MockRefresher
+}
+
+// NewMockRefresher creates a new mock instance.
+func NewMockRefresher(ctrl *gomock.Controller) *MockRefresher {
+	mock := &MockRefresher{ctrl: ctrl}
+	mock.recorder = &MockRefresherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRefresher) EXPECT() *MockRefresherMockRecorder {
+	return m.recorder
+}
+
+// WarmForecast mocks base method.
+func (m *MockRefresher) WarmForecast(arg0 context.Context, arg1 model.LocationQuery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WarmForecast", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WarmForecast indicates an expected call of WarmForecast.
+func (mr *MockRefresherMockRecorder) WarmForecast(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WarmForecast", reflect.TypeOf((*MockRefresher)(nil).WarmForecast), arg0, arg1)
+}
@@ -0,0 +1,227 @@
+package admin
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/dibrito/ennismore-weather-app/pkg/problem"
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+// nowFunc will get the now time when a snapshot is exported.
+var nowFunc = time.Now
+
+// pagination of the cached keys.
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+// Cache defines the cache operations the admin API exposes, every returned
+// value must be a copy of the cache state.
+type Cache interface {
+	Keys() []model.CacheKey
+	Entry(key string) (model.CacheEntry, bool)
+	Entries() []model.CacheEntry
+	Delete(key string) bool
+	DeletePrefix(prefix string) int
+	Flush() int
+	Import(entries []model.CacheEntry)
+}
+
+// Handler defines the cache admin HTTP handler.
+type Handler struct {
+	cache Cache
+	cfg   config.AdminConfig
+}
+
+// deleteResponse reports how many cache keys were removed.
+type deleteResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// NewHandler creates a new cache admin HTTP handler.
+func NewHandler(cache Cache, cfg config.AdminConfig) *Handler {
+	return &Handler{cache, cfg}
+}
+
+// Routes defines the cache admin HTTP routes, meant to be mounted on
+// /admin/cache. Every route requires the admin token.
+func (h *Handler) Routes() http.Handler {
+	mux := chi.NewRouter()
+	mux.Use(Authenticate(h.cfg.Token))
+	mux.Get("/", h.List)
+	mux.Delete("/", h.DeleteAll)
+	mux.Get("/snapshot", h.Export)
+	mux.Put("/snapshot", h.Import)
+	mux.Get("/{key}", h.Get)
+	mux.Delete("/{key}", h.Delete)
+	return mux
+}
+
+// Authenticate only lets through requests carrying the token as an
+// `Authorization: Bearer` header, an empty token rejects every request.
+func Authenticate(token string) func(http.Handler) http.Handler {
+	want := sha256.Sum256([]byte(token))
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			got, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+			sum := sha256.Sum256([]byte(got))
+			if token == "" || !ok || subtle.ConstantTimeCompare(sum[:], want[:]) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				problem.Error(w, req, http.StatusUnauthorized, "invalid admin token")
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// List handles GET /admin/cache?offset=&limit= requests.
+func (h *Handler) List(w http.ResponseWriter, req *http.Request) {
+	offset, limit, errs := parsePage(req.URL.Query())
+	if len(errs) > 0 {
+		problem.Error(w, req, http.StatusBadRequest, "invalid page", errs...)
+		return
+	}
+
+	keys := h.cache.Keys()
+	page := model.CacheKeys{
+		Keys:   []model.CacheKey{},
+		Total:  len(keys),
+		Offset: offset,
+		Limit:  limit,
+	}
+	if offset < len(keys) {
+		page.Keys = keys[offset:min(offset+limit, len(keys))]
+	}
+	writeJSON(w, req, page)
+}
+
+// Get handles GET /admin/cache/{key} requests.
+func (h *Handler) Get(w http.ResponseWriter, req *http.Request) {
+	entry, ok := h.cache.Entry(urlKey(req))
+	if !ok {
+		problem.Error(w, req, http.StatusNotFound, "key not found")
+		return
+	}
+	writeJSON(w, req, entry)
+}
+
+// Delete handles DELETE /admin/cache/{key} requests.
+func (h *Handler) Delete(w http.ResponseWriter, req *http.Request) {
+	if !h.cache.Delete(urlKey(req)) {
+		problem.Error(w, req, http.StatusNotFound, "key not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteAll handles DELETE /admin/cache?prefix= requests, without a prefix
+// the whole cache is flushed.
+func (h *Handler) DeleteAll(w http.ResponseWriter, req *http.Request) {
+	var deleted int
+	if prefix := req.URL.Query().Get("prefix"); prefix != "" {
+		deleted = h.cache.DeletePrefix(prefix)
+	} else {
+		deleted = h.cache.Flush()
+	}
+	logging.GetLoggerFromContext(req.Context()).Info("cache entries deleted",
+		zap.Int("deleted", deleted))
+	writeJSON(w, req, deleteResponse{Deleted: deleted})
+}
+
+// Export handles GET /admin/cache/snapshot requests.
+func (h *Handler) Export(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, req, model.CacheSnapshot{
+		CreatedAt: nowFunc().UTC(),
+		Entries:   h.cache.Entries(),
+	})
+}
+
+// Import handles PUT /admin/cache/snapshot requests, the snapshot entries
+// replace the cached ones with the same key.
+func (h *Handler) Import(w http.ResponseWriter, req *http.Request) {
+	if h.cfg.MaxSnapshotBytes > 0 {
+		req.Body = http.MaxBytesReader(w, req.Body, h.cfg.MaxSnapshotBytes)
+	}
+
+	var snapshot model.CacheSnapshot
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&snapshot); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			problem.Error(w, req, http.StatusRequestEntityTooLarge, "snapshot too large")
+			return
+		}
+		problem.Error(w, req, http.StatusBadRequest, fmt.Sprintf("invalid snapshot: %v", err))
+		return
+	}
+	var errs []model.FieldError
+	for i, e := range snapshot.Entries {
+		if e.Key == "" {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("entries[%d].key", i), Detail: "key is required"})
+		}
+	}
+	if len(errs) > 0 {
+		problem.Error(w, req, http.StatusBadRequest, "invalid snapshot", errs...)
+		return
+	}
+
+	h.cache.Import(snapshot.Entries)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parsePage reads the offset and limit query params.
+func parsePage(query url.Values) (int, int, []model.FieldError) {
+	offset, limit := 0, defaultLimit
+	var errs []model.FieldError
+	var err error
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			errs = append(errs, model.FieldError{Field: "offset", Detail: "offset must be a non negative number"})
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxLimit {
+			errs = append(errs, model.FieldError{Field: "limit", Detail: fmt.Sprintf("limit must be between 1 and %d", maxLimit)})
+		}
+	}
+	return offset, limit, errs
+}
+
+// urlKey returns the unescaped cache key of the request path, keys may hold
+// spaces, commas and pipes.
+func urlKey(req *http.Request) string {
+	key := chi.URLParam(req, "key")
+	if unescaped, err := url.PathUnescape(key); err == nil {
+		return unescaped
+	}
+	return key
+}
+
+// writeJSON encodes the whole response before writing it, so an encoding
+// failure can still be reported as an error.
+func writeJSON(w http.ResponseWriter, req *http.Request, v any) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		logging.GetLoggerFromContext(req.Context()).Error("unable to parse response", zap.Error(err))
+		problem.Error(w, req, http.StatusInternalServerError, "unable to encode the response")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(append(bs, '\n'))
+}
@@ -0,0 +1,149 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	respository "github.com/dibrito/ennismore-weather-app/internal/repository"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/stretchr/testify/require"
+)
+
+const token = "secret"
+
+func newCache() Cache {
+	cache := respository.New(30*time.Minute, time.Minute, 0, 0)
+	temperature := 68.0
+	for _, city := range []string{"new york", "new orleans", "chicago"} {
+		cache.PutLocation(city, model.Location{DisplayName: city, Lat: "1", Lon: "2"})
+		cache.PutPeriods(city, "2024-09-23", model.Period{Description: "sunny", Temperature: &temperature})
+	}
+	cache.PutAlerts("chicago", []model.Alert{{ID: "urn:oid:1", Event: "Heat Advisory"}})
+	return cache
+}
+
+func serve(t *testing.T, cache Cache, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	NewHandler(cache, config.AdminConfig{Token: token}).Routes().ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestAuthenticate(t *testing.T) {
+	tcs := []struct {
+		name   string
+		token  string
+		header string
+		status int
+	}{
+		{name: "when no header should return UNAUTHORIZED", token: token, status: http.StatusUnauthorized},
+		{name: "when wrong token should return UNAUTHORIZED", token: token, header: "Bearer nope", status: http.StatusUnauthorized},
+		{name: "when no token configured should return UNAUTHORIZED", header: "Bearer ", status: http.StatusUnauthorized},
+		{name: "when valid token should return OK", token: token, header: "Bearer " + token, status: http.StatusOK},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			recorder := httptest.NewRecorder()
+			NewHandler(newCache(), config.AdminConfig{Token: tc.token}).Routes().ServeHTTP(recorder, req)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	recorder := serve(t, newCache(), http.MethodGet, "/?offset=1&limit=1", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var got model.CacheKeys
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&got))
+	require.Equal(t, 3, got.Total)
+	require.Len(t, got.Keys, 1)
+	require.Equal(t, "new orleans", got.Keys[0].Key)
+	require.Equal(t, []string{"location", "periods"}, got.Keys[0].Kinds)
+	require.NotNil(t, got.Keys[0].TTL)
+	require.InDelta(t, 1800, *got.Keys[0].TTL, 1)
+
+	recorder = serve(t, newCache(), http.MethodGet, "/?limit=0", "")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestGetAndDelete(t *testing.T) {
+	cache := newCache()
+
+	recorder := serve(t, cache, http.MethodGet, "/new%20york", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var entry model.CacheEntry
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&entry))
+	require.Equal(t, "new york", entry.Key)
+	require.Equal(t, "sunny", entry.Periods["2024-09-23"].Period.Description)
+
+	// the entry is a copy, changing it won't change the cache
+	*entry.Periods["2024-09-23"].Period.Temperature = 0
+	cached, _ := cache.Entry("new york")
+	require.Equal(t, 68.0, *cached.Periods["2024-09-23"].Period.Temperature)
+
+	require.Equal(t, http.StatusNoContent, serve(t, cache, http.MethodDelete, "/new%20york", "").Code)
+	require.Equal(t, http.StatusNotFound, serve(t, cache, http.MethodGet, "/new%20york", "").Code)
+	require.Equal(t, http.StatusNotFound, serve(t, cache, http.MethodDelete, "/new%20york", "").Code)
+}
+
+func TestDeleteAll(t *testing.T) {
+	cache := newCache()
+
+	recorder := serve(t, cache, http.MethodDelete, "/?prefix=new", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"deleted":2}`, recorder.Body.String())
+	require.Len(t, cache.Keys(), 1)
+
+	recorder = serve(t, cache, http.MethodDelete, "/", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"deleted":1}`, recorder.Body.String())
+	require.Empty(t, cache.Keys())
+}
+
+func TestSnapshot(t *testing.T) {
+	source := newCache()
+	recorder := serve(t, source, http.MethodGet, "/snapshot", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	snapshot := recorder.Body.String()
+
+	target := respository.New(30*time.Minute, time.Minute, 0, 0)
+	require.Equal(t, http.StatusNoContent, serve(t, target, http.MethodPut, "/snapshot", snapshot).Code)
+	want, err := json.Marshal(source.Entries())
+	require.NoError(t, err)
+	got, err := json.Marshal(target.Entries())
+	require.NoError(t, err)
+	require.JSONEq(t, string(want), string(got))
+
+	// fetch times are kept, the imported periods still expire on time
+	period, ok := target.GetPeriods("chicago", "2024-09-23")
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now(), period.FetchedAt, time.Minute)
+
+	recorder = serve(t, target, http.MethodPut, "/snapshot", `{"entries":[{"key":""}]}`)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	// exported and imported locations don't share their bounding box
+	box := []string{"40.4", "41.0", "-74.3", "-73.7"}
+	exporter := respository.New(30*time.Minute, time.Minute, 0, 0)
+	exporter.PutLocation("boston", model.Location{Lat: "1", Lon: "2", BoundingBox: box})
+	entry, ok := exporter.Entry("boston")
+	require.True(t, ok)
+	entry.Location.BoundingBox[0] = "0"
+	target.Import([]model.CacheEntry{entry})
+	entry.Location.BoundingBox[1] = "0"
+	exported, _ := exporter.Entry("boston")
+	require.Equal(t, box, exported.Location.BoundingBox)
+	imported, _ := target.Entry("boston")
+	require.Equal(t, []string{"0", "41.0", "-74.3", "-73.7"}, imported.Location.BoundingBox)
+}
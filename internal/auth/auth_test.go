@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newStore(t *testing.T, keys ...Key) Store {
+	path := filepath.Join(t.TempDir(), "apikeys.json")
+	bs, err := json.Marshal(keys)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, bs, 0o600))
+
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+	return store
+}
+
+func TestMiddleware(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 30, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+
+	store := newStore(t,
+		Key{ID: "basic", Hash: HashKey("basic-key"), PerMinute: 2, PerDay: 100},
+		Key{ID: "disabled", Hash: HashKey("disabled-key"), Disabled: true},
+	)
+	handler := New(store).Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key, ok := KeyFromContext(req.Context())
+		require.True(t, ok)
+		require.Equal(t, "basic", key.ID)
+	}))
+
+	tcs := []struct {
+		name    string
+		headers map[string]string
+		status  int
+		check   func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "when no api key should return UNAUTHORIZED",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:    "when unknown api key should return UNAUTHORIZED",
+			headers: map[string]string{"Authorization": "Bearer nope"},
+			status:  http.StatusUnauthorized,
+		},
+		{
+			name:    "when disabled api key should return FORBIDDEN",
+			headers: map[string]string{APIKeyHeader: "disabled-key"},
+			status:  http.StatusForbidden,
+		},
+		{
+			name:    "when bearer api key should return OK with quota",
+			headers: map[string]string{"Authorization": "Bearer basic-key"},
+			status:  http.StatusOK,
+			check: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+				require.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+				require.Equal(t, "30", recorder.Header().Get("RateLimit-Reset"))
+				require.Equal(t, "2;w=60, 100;w=86400", recorder.Header().Get("RateLimit-Policy"))
+			},
+		},
+		{
+			name:    "when X-API-Key header should return OK",
+			headers: map[string]string{APIKeyHeader: "basic-key"},
+			status:  http.StatusOK,
+			check: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+			},
+		},
+		{
+			name:    "when minute quota exhausted should return TOO MANY REQUESTS",
+			headers: map[string]string{APIKeyHeader: "basic-key"},
+			status:  http.StatusTooManyRequests,
+			check: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+				require.Equal(t, "30", recorder.Header().Get("Retry-After"))
+			},
+		},
+	}
+	// the cases share the key quota, they run in order
+	for _, tc := range tcs {
+		req := httptest.NewRequest(http.MethodGet, "/weather?city=london", nil)
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		require.Equal(t, tc.status, recorder.Code, tc.name)
+		if tc.check != nil {
+			tc.check(t, recorder)
+		}
+	}
+
+	// the next minute the quota is available again
+	fakeTime = fakeTime.Add(time.Minute)
+	req := httptest.NewRequest(http.MethodGet, "/weather?city=london", nil)
+	req.Header.Set(APIKeyHeader, "basic-key")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestQuotasPerDay(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+	fakeTime := time.Date(2024, 9, 23, 23, 59, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+
+	quotas := NewQuotas()
+	key := Key{ID: "daily", PerMinute: 10, PerDay: 1}
+	require.True(t, quotas.Allow(key).Allowed)
+
+	usage := quotas.Allow(key)
+	require.False(t, usage.Allowed)
+	require.Equal(t, 1, usage.Limit)
+	require.Equal(t, time.Minute, usage.Reset)
+
+	fakeTime = fakeTime.Add(time.Minute)
+	require.True(t, quotas.Allow(key).Allowed)
+
+	// keys without limits aren't counted
+	require.Equal(t, Usage{Allowed: true}, quotas.Allow(Key{ID: "unlimited"}))
+}
+
+func TestCheckMaxCities(t *testing.T) {
+	tcs := []struct {
+		name   string
+		key    *Key
+		cities int
+		want   bool
+	}{
+		{name: "when no api key should allow", cities: 50, want: true},
+		{name: "when no max cities should allow", key: &Key{}, cities: 50, want: true},
+		{name: "when within max cities should allow", key: &Key{MaxCities: 2}, cities: 2, want: true},
+		{name: "when over max cities should forbid", key: &Key{MaxCities: 2}, cities: 3},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/weather", nil)
+			if tc.key != nil {
+				req = req.WithContext(context.WithValue(req.Context(), keyCtxKey{}, *tc.key))
+			}
+			recorder := httptest.NewRecorder()
+			require.Equal(t, tc.want, CheckMaxCities(recorder, req, tc.cities))
+			if !tc.want {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	_, err := NewFileStore(filepath.Join(t.TempDir(), "missing.json"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	path := filepath.Join(t.TempDir(), "apikeys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[]`), 0o600))
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+	_, err = store.Lookup(HashKey("any"))
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id":"no-hash"}]`), 0o600))
+	_, err = NewFileStore(path)
+	require.Error(t, err)
+}
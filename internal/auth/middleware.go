@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/problem"
+	"go.uber.org/zap"
+)
+
+// APIKeyHeader is the header API keys can be sent on instead of
+// `Authorization: Bearer`.
+const APIKeyHeader = "X-API-Key"
+
+// keyCtxKey is the request context key of the authenticated API key.
+type keyCtxKey struct{}
+
+// Authenticator checks the request API keys and their quotas.
+type Authenticator struct {
+	store  Store
+	quotas *Quotas
+}
+
+// New creates an API key authenticator.
+func New(store Store) *Authenticator {
+	return &Authenticator{
+		store:  store,
+		quotas: NewQuotas(),
+	}
+}
+
+// Middleware only lets through requests with a valid API key within its
+// quotas, it replies UNAUTHORIZED on a missing or unknown key, FORBIDDEN on a
+// disabled key and TOO MANY REQUESTS once a quota is exhausted. The quota is
+// reported on the RateLimit-* headers.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		raw := requestKey(req)
+		if raw == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			problem.Error(w, req, http.StatusUnauthorized, "missing api key")
+			return
+		}
+
+		key, err := a.store.Lookup(HashKey(raw))
+		if errors.Is(err, ErrNotFound) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			problem.Error(w, req, http.StatusUnauthorized, "invalid api key")
+			return
+		} else if err != nil {
+			logging.GetLoggerFromContext(req.Context()).Error("unable to look up api key", zap.Error(err))
+			problem.Error(w, req, http.StatusInternalServerError, "unable to look up the api key")
+			return
+		}
+		if key.Disabled {
+			problem.Error(w, req, http.StatusForbidden, "api key disabled")
+			return
+		}
+
+		usage := a.quotas.Allow(key)
+		setRateLimitHeaders(w, usage)
+		if !usage.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(resetSeconds(usage)))
+			problem.Error(w, req, http.StatusTooManyRequests, "api key quota exceeded")
+			return
+		}
+
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), keyCtxKey{}, key)))
+	})
+}
+
+// KeyFromContext returns the authenticated API key of the request.
+func KeyFromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(keyCtxKey{}).(Key)
+	return key, ok
+}
+
+// CheckMaxCities replies FORBIDDEN when the request API key doesn't allow
+// that many cities, it reports whether the request may go on.
+func CheckMaxCities(w http.ResponseWriter, req *http.Request, cities int) bool {
+	key, ok := KeyFromContext(req.Context())
+	if !ok || key.MaxCities == 0 || cities <= key.MaxCities {
+		return true
+	}
+	problem.Error(w, req, http.StatusForbidden, "too many cities for the api key, max "+strconv.Itoa(key.MaxCities))
+	return false
+}
+
+// requestKey returns the API key of the `Authorization: Bearer` or
+// X-API-Key header.
+func requestKey(req *http.Request) string {
+	if key, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(key)
+	}
+	return strings.TrimSpace(req.Header.Get(APIKeyHeader))
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, usage Usage) {
+	if usage.Policy == "" {
+		return
+	}
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(usage.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(usage.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(resetSeconds(usage)))
+	w.Header().Set("RateLimit-Policy", usage.Policy)
+}
+
+func resetSeconds(usage Usage) int {
+	return int(math.Ceil(usage.Reset.Seconds()))
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// nowFunc will get the now time when the quotas are checked.
+var nowFunc = time.Now
+
+// Usage reports the quota of a key after a request, for the window closest
+// to run out.
+type Usage struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Duration
+	// Policy describes every quota window, e.g. "60;w=60, 10000;w=86400".
+	Policy string
+}
+
+// window counts the requests of a fixed time window.
+type window struct {
+	start time.Time
+	count int
+}
+
+// usage holds the per key windows.
+type usage struct {
+	minute window
+	day    window
+}
+
+// Quotas counts the requests of every key on fixed minute and day windows.
+type Quotas struct {
+	mu    sync.Mutex
+	usage map[string]*usage
+}
+
+// NewQuotas creates the in-memory key quotas.
+func NewQuotas() *Quotas {
+	return &Quotas{usage: make(map[string]*usage)}
+}
+
+// Allow counts a request for the key unless one of its quotas is exhausted.
+func (q *Quotas) Allow(key Key) Usage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := nowFunc().UTC()
+	u, ok := q.usage[key.ID]
+	if !ok {
+		u = &usage{}
+		q.usage[key.ID] = u
+	}
+	minuteStart := now.Truncate(time.Minute)
+	if !u.minute.start.Equal(minuteStart) {
+		u.minute = window{start: minuteStart}
+	}
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if !u.day.start.Equal(dayStart) {
+		u.day = window{start: dayStart}
+	}
+
+	type quota struct {
+		w      *window
+		limit  int
+		length time.Duration
+	}
+	var quotas []quota
+	if key.PerMinute > 0 {
+		quotas = append(quotas, quota{&u.minute, key.PerMinute, time.Minute})
+	}
+	if key.PerDay > 0 {
+		quotas = append(quotas, quota{&u.day, key.PerDay, 24 * time.Hour})
+	}
+	if len(quotas) == 0 {
+		return Usage{Allowed: true}
+	}
+
+	result := Usage{Allowed: true}
+	for _, qt := range quotas {
+		if qt.w.count >= qt.limit {
+			result.Allowed = false
+		}
+	}
+	if result.Allowed {
+		for _, qt := range quotas {
+			qt.w.count++
+		}
+	}
+
+	// report the window closest to run out
+	for i, qt := range quotas {
+		remaining := max(qt.limit-qt.w.count, 0)
+		if i == 0 || remaining < result.Remaining {
+			result.Limit = qt.limit
+			result.Remaining = remaining
+			result.Reset = qt.w.start.Add(qt.length).Sub(now)
+		}
+		if result.Policy != "" {
+			result.Policy += ", "
+		}
+		result.Policy += policy(qt.limit, qt.length)
+	}
+	return result
+}
+
+func policy(limit int, length time.Duration) string {
+	return strconv.Itoa(limit) + ";w=" + strconv.Itoa(int(length.Seconds()))
+}
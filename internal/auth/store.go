@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNotFound is returned when an API key is not found.
+var ErrNotFound = errors.New("api key not found")
+
+// Key defines an API key and its limits, only the key hash is stored. Zero
+// limits mean unlimited.
+type Key struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Hash      string `json:"hash"`
+	PerMinute int    `json:"perMinute"`
+	PerDay    int    `json:"perDay"`
+	MaxCities int    `json:"maxCities"`
+	Disabled  bool   `json:"disabled"`
+}
+
+// Store defines where the API keys are looked up.
+type Store interface {
+	Lookup(hash string) (Key, error)
+}
+
+// HashKey returns the hash an API key is stored and looked up with, the
+// hex encoded SHA-256 of the key.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// fileStore keeps the API keys loaded from a JSON file in memory.
+type fileStore struct {
+	keys map[string]Key
+}
+
+// NewFileStore creates an API keys store backed by the JSON file at path, a
+// missing file is an error as every request would be unauthorized.
+func NewFileStore(path string) (*fileStore, error) {
+	s := &fileStore{keys: make(map[string]Key)}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read api keys file: %w", err)
+	}
+	var keys []Key
+	if err := json.Unmarshal(bs, &keys); err != nil {
+		return nil, fmt.Errorf("unable to parse api keys file: %w", err)
+	}
+	for _, k := range keys {
+		if k.ID == "" || k.Hash == "" {
+			return nil, errors.New("unable to parse api keys file: id and hash are required")
+		}
+		s.keys[k.Hash] = k
+	}
+	return s, nil
+}
+
+// Lookup retrieves an API key by its hash.
+func (s *fileStore) Lookup(hash string) (Key, error) {
+	key, ok := s.keys[hash]
+	if !ok {
+		return Key{}, ErrNotFound
+	}
+	return key, nil
+}
@@ -3,17 +3,19 @@ package openstreetmap
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"time"
 
 	config "github.com/dibrito/ennismore-weather-app/config"
-	"github.com/dibrito/ennismore-weather-app/internal/controller"
+	"github.com/dibrito/ennismore-weather-app/internal/clients/upstream"
 	"github.com/dibrito/ennismore-weather-app/pkg/logging"
 	"github.com/dibrito/ennismore-weather-app/pkg/model"
 	"go.uber.org/zap"
 )
 
+// service names the API on upstream errors.
+const service = "openstreetmap"
+
 type Client struct {
 	URL     string
 	Timeout int
@@ -30,7 +32,9 @@ func New(c config.OpenstreetmapAPIConfig) *Client {
 	}
 }
 
-func (c *Client) GetLocation(ctx context.Context, parmas string) ([]model.Location, error) {
+// GetLocation searches the locations matching the params, country is an
+// optional ISO 3166-1 alpha-2 code to limit the search.
+func (c *Client) GetLocation(ctx context.Context, parmas, country string) ([]model.Location, error) {
 	logger := logging.GetLoggerFromContext(ctx)
 
 	var result []model.Location
@@ -48,27 +52,26 @@ func (c *Client) GetLocation(ctx context.Context, parmas string) ([]model.Locati
 	values := req.URL.Query()
 	values.Add("q", parmas)
 	values.Add("format", "json")
+	if country != "" {
+		values.Add("countrycodes", country)
+	}
 	req.URL.RawQuery = values.Encode()
 
 	logger.Info("calling URL", zap.String("url", req.URL.String()))
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return result, fmt.Errorf("failed to fetch data: %v", err)
+		return result, upstream.FromError(service, err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusFound {
-		return result, controller.ErrNotFound
-	}
-
 	if resp.StatusCode != http.StatusOK {
-		return result, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return result, upstream.FromResponse(service, resp)
 	}
 
 	err = json.NewDecoder(resp.Body).Decode(&result)
 	if err != nil {
-		return result, fmt.Errorf("failed to read response body: %v", err)
+		return result, upstream.BadPayload(service, err)
 	}
 
 	return result, nil
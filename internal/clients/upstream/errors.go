@@ -0,0 +1,150 @@
+// Package upstream classifies the failures of the third party APIs called by
+// the HTTP clients, so callers can act on them with errors.Is and errors.As.
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the upstream failure kinds, every Error matches one of them with errors.Is.
+var (
+	// ErrNotFound is a resource the upstream doesn't know about.
+	ErrNotFound = errors.New("not found")
+	// ErrOutOfCoverage is a point outside the upstream coverage, e.g.
+	// weather.gov only covers the US.
+	ErrOutOfCoverage = errors.New("out of coverage")
+	// ErrRateLimited is a request rejected by the upstream rate limits.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnavailable is an upstream failure, e.g. a 5xx or a network error.
+	ErrUnavailable = errors.New("unavailable")
+	// ErrTimeout is an upstream call that took too long.
+	ErrTimeout = errors.New("timeout")
+	// ErrBadPayload is an upstream response that can't be decoded.
+	ErrBadPayload = errors.New("bad payload")
+)
+
+// maxProblemBytes caps how much of an error response body is read.
+const maxProblemBytes = 64 << 10
+
+// outOfCoverageType is the weather.gov problem type of points it has no data
+// for, e.g. https://api.weather.gov/problems/InvalidPoint.
+const outOfCoverageType = "/InvalidPoint"
+
+// Error is a failed upstream call, Kind is one of the Err* kinds. Type, Detail
+// and CorrelationID come from the upstream problem+json body when sent.
+type Error struct {
+	Service       string
+	Kind          error
+	StatusCode    int
+	Type          string
+	Detail        string
+	CorrelationID string
+	// RetryAfter is how long the upstream asked to wait, zero when not sent.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", e.Service, e.Kind)
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (status %d)", e.StatusCode)
+	}
+	if e.Detail != "" {
+		fmt.Fprintf(&b, ": %s", e.Detail)
+	}
+	if e.CorrelationID != "" {
+		fmt.Fprintf(&b, " [correlationId %s]", e.CorrelationID)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	return b.String()
+}
+
+// Unwrap returns the kind and the cause, so both match with errors.Is.
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// problem is the weather.gov RFC 7807 error body.
+type problem struct {
+	Type          string `json:"type"`
+	Title         string `json:"title"`
+	Detail        string `json:"detail"`
+	CorrelationID string `json:"correlationId"`
+}
+
+// FromResponse classifies a response with an unexpected status code, the
+// body is parsed when it is a problem+json document.
+func FromResponse(service string, resp *http.Response) *Error {
+	e := &Error{Service: service, StatusCode: resp.StatusCode}
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		e.Kind = ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests:
+		e.Kind = ErrRateLimited
+		e.RetryAfter = retryAfter(resp.Header.Get("Retry-After"))
+	case resp.StatusCode == http.StatusGatewayTimeout || resp.StatusCode == http.StatusRequestTimeout:
+		e.Kind = ErrTimeout
+	default:
+		e.Kind = ErrUnavailable
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/problem+json" {
+		var p problem
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxProblemBytes)).Decode(&p); err == nil {
+			e.Type, e.Detail, e.CorrelationID = p.Type, p.Detail, p.CorrelationID
+			if e.Detail == "" {
+				e.Detail = p.Title
+			}
+		}
+	}
+	if strings.HasSuffix(e.Type, outOfCoverageType) {
+		e.Kind = ErrOutOfCoverage
+	}
+	return e
+}
+
+// FromError classifies a failed call, timeouts are told apart from other
+// network errors.
+func FromError(service string, err error) *Error {
+	kind := ErrUnavailable
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		kind = ErrTimeout
+	}
+	return &Error{Service: service, Kind: kind, Err: err}
+}
+
+// BadPayload reports a response that can't be decoded.
+func BadPayload(service string, err error) *Error {
+	return &Error{Service: service, Kind: ErrBadPayload, Err: err}
+}
+
+// retryAfter parses a Retry-After header, either seconds or an HTTP date.
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromResponse(t *testing.T) {
+	tcs := []struct {
+		name            string
+		status          int
+		header          http.Header
+		body            string
+		wantKind        error
+		wantDetail      string
+		wantCorrelation string
+		wantRetryAfter  time.Duration
+	}{
+		{
+			name:     "when not found should be not found",
+			status:   http.StatusNotFound,
+			wantKind: ErrNotFound,
+		},
+		{
+			name:   "when invalid point should be out of coverage",
+			status: http.StatusNotFound,
+			header: http.Header{"Content-Type": {"application/problem+json; charset=utf-8"}},
+			body: `{"type":"https://api.weather.gov/problems/InvalidPoint","title":"Invalid Point",` +
+				`"detail":"Unable to provide data for requested point 51.5,-0.12","correlationId":"4f1b2c"}`,
+			wantKind:        ErrOutOfCoverage,
+			wantDetail:      "Unable to provide data for requested point 51.5,-0.12",
+			wantCorrelation: "4f1b2c",
+		},
+		{
+			name:           "when too many requests should be rate limited",
+			status:         http.StatusTooManyRequests,
+			header:         http.Header{"Retry-After": {"30"}},
+			wantKind:       ErrRateLimited,
+			wantRetryAfter: 30 * time.Second,
+		},
+		{
+			name:     "when gateway timeout should be a timeout",
+			status:   http.StatusGatewayTimeout,
+			wantKind: ErrTimeout,
+		},
+		{
+			name:       "when server error should be unavailable and keep the title",
+			status:     http.StatusInternalServerError,
+			header:     http.Header{"Content-Type": {"application/problem+json"}},
+			body:       `{"type":"https://api.weather.gov/problems/UnexpectedProblem","title":"Unexpected Problem"}`,
+			wantKind:   ErrUnavailable,
+			wantDetail: "Unexpected Problem",
+		},
+		{
+			name:     "when body is not a problem should not be parsed",
+			status:   http.StatusBadGateway,
+			header:   http.Header{"Content-Type": {"text/html"}},
+			body:     `<html>bad gateway</html>`,
+			wantKind: ErrUnavailable,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			header := tc.header
+			if header == nil {
+				header = http.Header{}
+			}
+			err := FromResponse("weather.gov", &http.Response{
+				StatusCode: tc.status,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader(tc.body)),
+			})
+			require.ErrorIs(t, fmt.Errorf("get forecast: %w", err), tc.wantKind)
+			require.Equal(t, tc.status, err.StatusCode)
+			require.Equal(t, tc.wantDetail, err.Detail)
+			require.Equal(t, tc.wantCorrelation, err.CorrelationID)
+			require.Equal(t, tc.wantRetryAfter, err.RetryAfter)
+		})
+	}
+}
+
+func TestFromError(t *testing.T) {
+	refused := errors.New("connection refused")
+	err := FromError("openstreetmap", refused)
+	require.ErrorIs(t, err, ErrUnavailable)
+	require.ErrorIs(t, err, refused)
+
+	err = FromError("openstreetmap", fmt.Errorf("get: %w", context.DeadlineExceeded))
+	require.ErrorIs(t, err, ErrTimeout)
+	require.Equal(t, "openstreetmap: timeout: get: context deadline exceeded", err.Error())
+}
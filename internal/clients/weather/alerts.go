@@ -0,0 +1,49 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/dibrito/ennismore-weather-app/internal/clients/upstream"
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"go.uber.org/zap"
+)
+
+// GetAlerts fetches the active alerts for a pair of points.
+func (c *Client) GetAlerts(ctx context.Context, lat, long string) ([]model.Alert, error) {
+	logger := logging.GetLoggerFromContext(ctx)
+	var result model.AlertsResponse
+	// bind URI with query param
+	URI := fmt.Sprintf("%s/alerts/active?point=%s,%s", c.URL, lat, long)
+
+	logger.Info("alerts URL", zap.String("url", URI))
+	req, err := http.NewRequest(http.MethodGet, URI, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.WithContext(ctx)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, upstream.FromError(service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, upstream.FromResponse(service, resp)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, upstream.BadPayload(service, err)
+	}
+
+	alerts := make([]model.Alert, 0, len(result.Features))
+	for _, f := range result.Features {
+		alerts = append(alerts, f.Properties)
+	}
+	return alerts, nil
+}
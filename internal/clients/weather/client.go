@@ -8,12 +8,15 @@ import (
 	"time"
 
 	config "github.com/dibrito/ennismore-weather-app/config"
-	"github.com/dibrito/ennismore-weather-app/internal/controller"
+	"github.com/dibrito/ennismore-weather-app/internal/clients/upstream"
 	"github.com/dibrito/ennismore-weather-app/pkg/logging"
 	"github.com/dibrito/ennismore-weather-app/pkg/model"
 	"go.uber.org/zap"
 )
 
+// service names the API on upstream errors.
+const service = "weather.gov"
+
 type Client struct {
 	URL     string
 	Timeout int
@@ -57,22 +60,17 @@ func (c *Client) FetchForecastURL(ctx context.Context, lat, long string) (model.
 	req = req.WithContext(ctx)
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return result, fmt.Errorf("failed to fetch data: %v", err)
+		return result, upstream.FromError(service, err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusFound {
-		return result, controller.ErrNotFound
-	}
-
 	if resp.StatusCode != http.StatusOK {
-		return result, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-
+		return result, upstream.FromResponse(service, resp)
 	}
 
 	err = json.NewDecoder(resp.Body).Decode(&result)
 	if err != nil {
-		return result, fmt.Errorf("failed to read response body: %v", err)
+		return result, upstream.BadPayload(service, err)
 	}
 
 	return result, nil
@@ -91,22 +89,17 @@ func (c *Client) FetchForecastPeriods(ctx context.Context, pointsURL string) ([]
 	req = req.WithContext(ctx)
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return []model.Period{}, fmt.Errorf("failed to fetch data: %v", err)
+		return []model.Period{}, upstream.FromError(service, err)
 	}
 	defer resp.Body.Close()
 
-	// TODO: check if theres any chance the API returning not found here. I don't think so!
-	if resp.StatusCode == http.StatusFound {
-		return []model.Period{}, controller.ErrNotFound
-	}
-
 	if resp.StatusCode != http.StatusOK {
-		return []model.Period{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return []model.Period{}, upstream.FromResponse(service, resp)
 	}
 
 	err = json.NewDecoder(resp.Body).Decode(&result)
 	if err != nil {
-		return []model.Period{}, fmt.Errorf("failed to read response body: %v", err)
+		return []model.Period{}, upstream.BadPayload(service, err)
 	}
 
 	return result.Properties.Periods, nil
@@ -0,0 +1,63 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/dibrito/ennismore-weather-app/internal/clients/upstream"
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"go.uber.org/zap"
+)
+
+func (c *Client) GetGridpoint(ctx context.Context, lat, long string) (map[string]model.GridpointLayer, error) {
+	// fetch resp
+	resp, err := c.FetchForecastURL(ctx, lat, long)
+	if err != nil {
+		return nil, err
+	}
+
+	// fetch layers
+	return c.FetchGridpointLayers(ctx, resp.Properties.ForecastGridDataURL)
+}
+
+// FetchGridpointLayers fetches the raw gridpoint data, only the properties
+// holding a quantitative series are returned as layers.
+func (c *Client) FetchGridpointLayers(ctx context.Context, gridDataURL string) (map[string]model.GridpointLayer, error) {
+	logger := logging.GetLoggerFromContext(ctx)
+	var result model.GridpointResponse
+	URI := gridDataURL
+	logger.Info("gridpoint URL", zap.String("url", URI))
+
+	req, err := http.NewRequest(http.MethodGet, URI, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, upstream.FromError(service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, upstream.FromResponse(service, resp)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, upstream.BadPayload(service, err)
+	}
+
+	layers := make(map[string]model.GridpointLayer)
+	for name, raw := range result.Properties {
+		var layer model.GridpointLayer
+		// metadata and non numeric layers e.g. weather or hazards won't decode
+		if err := json.Unmarshal(raw, &layer); err != nil || layer.Values == nil {
+			continue
+		}
+		layers[name] = layer
+	}
+	return layers, nil
+}
@@ -0,0 +1,81 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/dibrito/ennismore-weather-app/internal/clients/upstream"
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"go.uber.org/zap"
+)
+
+// GetStations fetches the observation stations for a pair of points, sorted
+// by distance.
+func (c *Client) GetStations(ctx context.Context, lat, long string) ([]model.Station, error) {
+	logger := logging.GetLoggerFromContext(ctx)
+	var result model.StationsResponse
+	// bind URI with query param
+	URI := fmt.Sprintf("%s/points/%s,%s/stations", c.URL, lat, long)
+
+	logger.Info("stations URL", zap.String("url", URI))
+	req, err := http.NewRequest(http.MethodGet, URI, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.WithContext(ctx)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, upstream.FromError(service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, upstream.FromResponse(service, resp)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, upstream.BadPayload(service, err)
+	}
+
+	stations := make([]model.Station, 0, len(result.Features))
+	for _, f := range result.Features {
+		stations = append(stations, f.Properties)
+	}
+	return stations, nil
+}
+
+// GetLatestObservation fetches the latest observation for a station.
+func (c *Client) GetLatestObservation(ctx context.Context, stationID string) (model.Observation, error) {
+	logger := logging.GetLoggerFromContext(ctx)
+	var result model.ObservationResponse
+	URI := fmt.Sprintf("%s/stations/%s/observations/latest", c.URL, stationID)
+
+	logger.Info("observation URL", zap.String("url", URI))
+	req, err := http.NewRequest(http.MethodGet, URI, nil)
+	if err != nil {
+		return model.Observation{}, err
+	}
+
+	req = req.WithContext(ctx)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return model.Observation{}, upstream.FromError(service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return model.Observation{}, upstream.FromResponse(service, resp)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return model.Observation{}, upstream.BadPayload(service, err)
+	}
+
+	return result.Properties, nil
+}
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/dibrito/ennismore-weather-app/pkg/units"
+)
+
+// activity ratings, following the day score.
+const (
+	RatingGood = "good"
+	RatingFair = "fair"
+	RatingPoor = "poor"
+)
+
+// scoring rules, a day starts at maxScore and loses points for every
+// threshold it misses in proportion to how far it misses it, up to
+// maxPenalty per threshold. Avoided keywords cost a fixed amount and
+// preferred ones add a bonus.
+const (
+	maxScore             = 100
+	maxPenalty           = 40
+	temperaturePenalty   = 5 // points per degC out of range
+	windPenalty          = 2 // points per km/h over the max
+	precipitationPenalty = 1 // points per % over the max
+	avoidPenalty         = 30
+	preferBonus          = 10
+	goodScore            = 70
+	fairScore            = 40
+)
+
+// GetActivityForecast scores each forecast day of the cities for the activity
+// following its profile. The cities are forecast in metric units by
+// GetForecast, so they share its concurrency and cache, and the ones that
+// can't be forecast are left out.
+func (c *Controller) GetActivityForecast(ctx context.Context, cities []string, activity string, profile config.ActivityProfile, opts ForecastOptions) (model.ActivityForecast, error) {
+	// the profile thresholds are metric
+	opts.Units = units.Metric
+	forecast, err := c.GetForecast(ctx, cities, opts)
+	if err != nil {
+		return model.ActivityForecast{}, err
+	}
+
+	result := model.ActivityForecast{Activity: activity}
+	for _, f := range forecast.Forecast {
+		city := model.CityActivity{Name: f.Name, Stale: f.Stale}
+		for _, d := range f.Detail {
+			score, reasons := scoreActivity(d, profile)
+			city.Days = append(city.Days, model.ActivityDay{
+				Date:    d.StartTime.Format("2006-01-02"),
+				Score:   score,
+				Rating:  rating(score),
+				Reasons: reasons,
+				Detail:  d,
+			})
+		}
+		result.Forecast = append(result.Forecast, city)
+	}
+	return result, nil
+}
+
+// scoreActivity checks the forecast day against the profile thresholds and
+// keywords, every check that applies adds its reason.
+func scoreActivity(d model.Detail, p config.ActivityProfile) (int, []string) {
+	score := maxScore
+	var reasons []string
+	penalize := func(points float64) {
+		score -= int(math.Min(math.Round(points), maxPenalty))
+	}
+
+	if p.MinTemperature != nil || p.MaxTemperature != nil {
+		switch t := d.Temperature; {
+		case t == nil:
+			reasons = append(reasons, "temperature not forecast")
+		case p.MinTemperature != nil && *t < *p.MinTemperature:
+			penalize((*p.MinTemperature - *t) * temperaturePenalty)
+			reasons = append(reasons, fmt.Sprintf("temperature %v°C is below the %v°C minimum", *t, *p.MinTemperature))
+		case p.MaxTemperature != nil && *t > *p.MaxTemperature:
+			penalize((*t - *p.MaxTemperature) * temperaturePenalty)
+			reasons = append(reasons, fmt.Sprintf("temperature %v°C is above the %v°C maximum", *t, *p.MaxTemperature))
+		default:
+			reasons = append(reasons, fmt.Sprintf("temperature %v°C is within the limits", *t))
+		}
+	}
+
+	if p.MaxWind != nil {
+		switch w := d.WindSpeed; {
+		case w == nil:
+			reasons = append(reasons, "wind not forecast")
+		case w.Value > *p.MaxWind:
+			penalize((w.Value - *p.MaxWind) * windPenalty)
+			reasons = append(reasons, fmt.Sprintf("wind up to %v %s is above the %v %s maximum", w.Value, w.Unit, *p.MaxWind, w.Unit))
+		default:
+			reasons = append(reasons, fmt.Sprintf("wind up to %v %s is within the limit", w.Value, w.Unit))
+		}
+	}
+
+	if p.MaxPrecipitation != nil {
+		switch pop := d.PrecipitationProbability; {
+		case pop == nil:
+			reasons = append(reasons, "precipitation not forecast")
+		case *pop > *p.MaxPrecipitation:
+			penalize((*pop - *p.MaxPrecipitation) * precipitationPenalty)
+			reasons = append(reasons, fmt.Sprintf("%v%% chance of precipitation is above the %v%% maximum", *pop, *p.MaxPrecipitation))
+		default:
+			reasons = append(reasons, fmt.Sprintf("%v%% chance of precipitation is within the limit", *pop))
+		}
+	}
+
+	text := d.Summary + " " + d.Description
+	for _, keyword := range p.Avoid {
+		if mentions(text, keyword) {
+			score -= avoidPenalty
+			reasons = append(reasons, fmt.Sprintf("forecast mentions %s", keyword))
+		}
+	}
+	for _, keyword := range p.Prefer {
+		if mentions(text, keyword) {
+			score += preferBonus
+			reasons = append(reasons, fmt.Sprintf("forecast mentions %s", keyword))
+		}
+	}
+	return max(0, min(score, maxScore)), reasons
+}
+
+// mentions reports whether a word of text starts with the keyword, ignoring
+// case, so thunderstorm matches Thunderstorms but rain doesn't match drain.
+func mentions(text, keyword string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	keyword = strings.Join(strings.Fields(strings.ToLower(keyword)), " ")
+	return keyword != "" && strings.Contains(" "+strings.Join(words, " "), " "+keyword)
+}
+
+func rating(score int) string {
+	switch {
+	case score >= goodScore:
+		return RatingGood
+	case score >= fairScore:
+		return RatingFair
+	default:
+		return RatingPoor
+	}
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	alertsAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/alerts"
+	openStreetMapAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/openstreetmap"
+	weatherAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/weather"
+	respository "github.com/dibrito/ennismore-weather-app/internal/repository"
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestScoreActivity(t *testing.T) {
+	float := func(v float64) *float64 { return &v }
+	running := config.ActivityProfile{
+		MinTemperature:   float(5),
+		MaxTemperature:   float(22),
+		MaxWind:          float(30),
+		MaxPrecipitation: float(40),
+		Avoid:            []string{"thunderstorm", "heat"},
+		Prefer:           []string{"sunny", "partly cloudy"},
+	}
+
+	tcs := []struct {
+		name        string
+		profile     config.ActivityProfile
+		detail      model.Detail
+		wantScore   int
+		wantRating  string
+		wantReasons []string
+	}{
+		{
+			name:    "when every threshold is met should rate good",
+			profile: running,
+			detail: model.Detail{Summary: "Cloudy", Temperature: float(15), PrecipitationProbability: float(10),
+				WindSpeed: &model.Measurement{Value: 20, Unit: "km/h"}},
+			wantScore:  100,
+			wantRating: RatingGood,
+			wantReasons: []string{
+				"temperature 15°C is within the limits",
+				"wind up to 20 km/h is within the limit",
+				"10% chance of precipitation is within the limit",
+			},
+		},
+		{
+			name:    "when thresholds are missed should lose points in proportion",
+			profile: running,
+			detail: model.Detail{Summary: "Rain", Temperature: float(25), PrecipitationProbability: float(60),
+				WindSpeed: &model.Measurement{Value: 35, Unit: "km/h"}},
+			wantScore:  55,
+			wantRating: RatingFair,
+			wantReasons: []string{
+				"temperature 25°C is above the 22°C maximum",
+				"wind up to 35 km/h is above the 30 km/h maximum",
+				"60% chance of precipitation is above the 40% maximum",
+			},
+		},
+		{
+			name:    "when a threshold is far off should cap its penalty",
+			profile: running,
+			detail: model.Detail{Temperature: float(-10), PrecipitationProbability: float(0),
+				WindSpeed: &model.Measurement{Value: 10, Unit: "km/h"}},
+			wantScore:  60,
+			wantRating: RatingFair,
+			wantReasons: []string{
+				"temperature -10°C is below the 5°C minimum",
+				"wind up to 10 km/h is within the limit",
+				"0% chance of precipitation is within the limit",
+			},
+		},
+		{
+			name:    "when keywords are mentioned should apply penalties and bonuses",
+			profile: running,
+			detail: model.Detail{Summary: "Thunderstorms Likely", Description: "Partly cloudy, then mostly sunny.",
+				Temperature: float(30), PrecipitationProbability: float(80), WindSpeed: &model.Measurement{Value: 50, Unit: "km/h"}},
+			wantScore:  0,
+			wantRating: RatingPoor,
+			wantReasons: []string{
+				"temperature 30°C is above the 22°C maximum",
+				"wind up to 50 km/h is above the 30 km/h maximum",
+				"80% chance of precipitation is above the 40% maximum",
+				"forecast mentions thunderstorm",
+				"forecast mentions sunny",
+				"forecast mentions partly cloudy",
+			},
+		},
+		{
+			name:        "when a keyword is inside a word should not match",
+			profile:     config.ActivityProfile{Avoid: []string{"heat"}, Prefer: []string{"clear"}},
+			detail:      model.Detail{Description: "Wheat fields, clearing skies."},
+			wantScore:   100,
+			wantRating:  RatingGood,
+			wantReasons: []string{"forecast mentions clear"},
+		},
+		{
+			name:        "when values are not forecast should not score them",
+			profile:     running,
+			detail:      model.Detail{Description: "gray"},
+			wantScore:   100,
+			wantRating:  RatingGood,
+			wantReasons: []string{"temperature not forecast", "wind not forecast", "precipitation not forecast"},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			score, reasons := scoreActivity(tc.detail, tc.profile)
+			require.Equal(t, tc.wantScore, score)
+			require.Equal(t, tc.wantRating, rating(score))
+			require.Equal(t, tc.wantReasons, reasons)
+		})
+	}
+}
+
+func TestGetActivityForecastCached(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+	day := fakeTime.Add(-2 * time.Hour)
+	// the stormy night must not replace the sunny day once cached
+	periods := []model.Period{
+		{StartTime: day, EndTime: day.Add(12 * time.Hour), ShortForecast: "Sunny"},
+		{StartTime: day.Add(12 * time.Hour), EndTime: day.Add(24 * time.Hour), ShortForecast: "Thunderstorms"},
+	}
+
+	ctrl := gomock.NewController(t)
+	weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+	cache := respository.New(time.Hour, time.Minute, 0, 0)
+	cache.PutLocation("london", model.Location{Lat: "51.5", Lon: "-0.1"})
+	// the second request is served from cache
+	weatherAPIMock.EXPECT().GetForecast(gomock.Any(), "51.5", "-0.1").Return(periods, nil).Times(1)
+
+	weatherAppController := New(openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl), weatherAPIMock,
+		alertsAPIMock.NewMockAlertsGateway(ctrl), cache)
+	ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+	profile := config.ActivityProfile{Avoid: []string{"thunderstorm"}, Prefer: []string{"sunny"}}
+
+	fetched, err := weatherAppController.GetActivityForecast(ctx, []string{"london"}, "running", profile, ForecastOptions{Days: 1})
+	require.NoError(t, err)
+	require.Len(t, fetched.Forecast, 1)
+	require.Equal(t, []string{"forecast mentions sunny"}, fetched.Forecast[0].Days[0].Reasons)
+
+	cached, err := weatherAppController.GetActivityForecast(ctx, []string{"london"}, "running", profile, ForecastOptions{Days: 1})
+	require.NoError(t, err)
+	require.Equal(t, fetched, cached)
+}
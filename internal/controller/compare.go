@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+)
+
+// metrics the cities can be compared on.
+const (
+	MetricTemperature   = "temperature"
+	MetricPrecipitation = "precipitation"
+	MetricWind          = "wind"
+)
+
+// Metrics lists the supported comparison metrics.
+var Metrics = []string{MetricTemperature, MetricPrecipitation, MetricWind}
+
+// comparisonMetric reads the compared value of a forecast detail, highest
+// tells whether the highest value wins, e.g. the warmest city.
+type comparisonMetric struct {
+	highest bool
+	value   func(model.Detail) *model.Measurement
+}
+
+var comparisonMetrics = map[string]comparisonMetric{
+	MetricTemperature: {highest: true, value: func(d model.Detail) *model.Measurement {
+		if d.Temperature == nil {
+			return nil
+		}
+		// forecast temperatures come as C or F
+		unit := d.TemperatureUnit
+		if unit != "" && !strings.HasPrefix(unit, "deg") {
+			unit = "deg" + unit
+		}
+		return &model.Measurement{Value: *d.Temperature, Unit: unit}
+	}},
+	MetricPrecipitation: {value: func(d model.Detail) *model.Measurement {
+		if d.PrecipitationProbability == nil {
+			return nil
+		}
+		return &model.Measurement{Value: *d.PrecipitationProbability, Unit: "%"}
+	}},
+	MetricWind: {value: func(d model.Detail) *model.Measurement {
+		return d.WindSpeed
+	}},
+}
+
+// better reports whether a beats b.
+func (m comparisonMetric) better(a, b float64) bool {
+	if m.highest {
+		return a > b
+	}
+	return a < b
+}
+
+// CompareForecast ranks the forecast of the cities on the metric. The cities
+// are forecast by GetForecast, so they share its concurrency and cache, and
+// the ones that can't be forecast are left out. Every forecast day is
+// compared, or only date when it isn't zero.
+func (c *Controller) CompareForecast(ctx context.Context, cities []string, metric string, date time.Time, opts ForecastOptions) (model.Comparison, error) {
+	m, ok := comparisonMetrics[metric]
+	if !ok {
+		return model.Comparison{}, &Error{Kind: KindInternal, Detail: "unsupported metric " + metric}
+	}
+	days := forecastDays(opts.Days)
+	if !date.IsZero() {
+		horizon := forecastDays(MaxForecastDays)
+		i := slices.IndexFunc(horizon, func(day time.Time) bool { return matchDate(day, date) })
+		if i < 0 {
+			return model.Comparison{}, ErrForecastNotFound
+		}
+		// the forecast starts today, only the requested day is compared
+		opts.Days = i + 1
+		days = horizon[i : i+1]
+	}
+
+	forecast, err := c.GetForecast(ctx, cities, opts)
+	if err != nil {
+		return model.Comparison{}, err
+	}
+	return compare(forecast, metric, m, days), nil
+}
+
+// compare picks the winners of each day and ranks the cities by their mean
+// value. A mean over fewer days can't be compared with a complete one, so the
+// cities missing days are ranked after the ones with more of them, and cities
+// keep the forecast order on ties.
+func compare(forecast model.WeatherForecast, metric string, m comparisonMetric, days []time.Time) model.Comparison {
+	result := model.Comparison{Metric: metric, Best: "lowest"}
+	if m.highest {
+		result.Best = "highest"
+	}
+
+	type total struct {
+		sum  float64
+		n    int
+		unit string
+		wins int
+	}
+	totals := make([]total, len(forecast.Forecast))
+	for _, day := range days {
+		compared := model.DayComparison{Date: day.Format("2006-01-02")}
+		var best *float64
+		for i, f := range forecast.Forecast {
+			var value *model.Measurement
+			for _, d := range f.Detail {
+				if matchDate(d.StartTime, day) {
+					value = m.value(d)
+					break
+				}
+			}
+			compared.Values = append(compared.Values, model.CityValue{Name: f.Name, Value: value})
+			if value == nil {
+				continue
+			}
+			totals[i].sum += value.Value
+			totals[i].n++
+			totals[i].unit = value.Unit
+			if best == nil || m.better(value.Value, *best) {
+				best = &value.Value
+			}
+		}
+		for i, v := range compared.Values {
+			if v.Value != nil && v.Value.Value == *best {
+				compared.Winners = append(compared.Winners, v.Name)
+				totals[i].wins++
+			}
+		}
+		compared.Tie = len(compared.Winners) > 1
+		result.Days = append(result.Days, compared)
+	}
+
+	for i, f := range forecast.Forecast {
+		rank := model.CityRank{Name: f.Name, Days: totals[i].n, Wins: totals[i].wins}
+		if t := totals[i]; t.n > 0 {
+			rank.Value = &model.Measurement{Value: math.Round(t.sum/float64(t.n)*10) / 10, Unit: t.unit}
+		}
+		result.Ranking = append(result.Ranking, rank)
+	}
+	sort.SliceStable(result.Ranking, func(i, j int) bool {
+		a, b := result.Ranking[i], result.Ranking[j]
+		if a.Days != b.Days {
+			return a.Days > b.Days
+		}
+		// cities without values have no days
+		return a.Value != nil && m.better(a.Value.Value, b.Value.Value)
+	})
+	for i := range result.Ranking {
+		result.Ranking[i].Rank = i + 1
+		if i > 0 && result.Ranking[i-1].Days == result.Ranking[i].Days &&
+			sameValue(result.Ranking[i-1].Value, result.Ranking[i].Value) {
+			result.Ranking[i].Rank = result.Ranking[i-1].Rank
+		}
+	}
+	return result
+}
+
+// sameValue reports whether both cities share their place on a ranking.
+func sameValue(a, b *model.Measurement) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Value == b.Value
+}
@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	alertsAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/alerts"
+	openStreetMapAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/openstreetmap"
+	weatherAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/weather"
+	repositoryMock "github.com/dibrito/ennismore-weather-app/gen/mock/repository/memory"
+	respository "github.com/dibrito/ennismore-weather-app/internal/repository"
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestCompareForecast(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+	float := func(v float64) *float64 { return &v }
+	// temperature, precipitation and wind of each city on the 23rd and the 24th
+	forecasts := map[string][2]struct {
+		temperature, precipitation *float64
+		wind                       string
+	}{
+		"london": {{float(60), float(80), "10 mph"}, {float(70), float(10), "5 to 15 mph"}},
+		"paris":  {{float(70), float(20), "5 mph"}, {float(70), nil, "5 to 10 mph"}},
+		"rome":   {{float(80), nil, ""}, {nil, nil, ""}},
+	}
+
+	tcs := []struct {
+		name   string
+		metric string
+		date   time.Time
+		want   model.Comparison
+	}{
+		{
+			name:   "when temperature should rank the warmest first and the cities missing days last",
+			metric: MetricTemperature,
+			want: model.Comparison{
+				Metric: MetricTemperature,
+				Best:   "highest",
+				Ranking: []model.CityRank{
+					{Rank: 1, Name: "paris", Value: &model.Measurement{Value: 70, Unit: "degF"}, Days: 2, Wins: 1},
+					{Rank: 2, Name: "london", Value: &model.Measurement{Value: 65, Unit: "degF"}, Days: 2, Wins: 1},
+					{Rank: 3, Name: "rome", Value: &model.Measurement{Value: 80, Unit: "degF"}, Days: 1, Wins: 1},
+				},
+				Days: []model.DayComparison{
+					{Date: "2024-09-23", Values: []model.CityValue{
+						{Name: "london", Value: &model.Measurement{Value: 60, Unit: "degF"}},
+						{Name: "paris", Value: &model.Measurement{Value: 70, Unit: "degF"}},
+						{Name: "rome", Value: &model.Measurement{Value: 80, Unit: "degF"}},
+					}, Winners: []string{"rome"}},
+					{Date: "2024-09-24", Values: []model.CityValue{
+						{Name: "london", Value: &model.Measurement{Value: 70, Unit: "degF"}},
+						{Name: "paris", Value: &model.Measurement{Value: 70, Unit: "degF"}},
+						{Name: "rome"},
+					}, Winners: []string{"london", "paris"}, Tie: true},
+				},
+			},
+		},
+		{
+			name:   "when precipitation should rank the driest first and the cities without values last",
+			metric: MetricPrecipitation,
+			want: model.Comparison{
+				Metric: MetricPrecipitation,
+				Best:   "lowest",
+				Ranking: []model.CityRank{
+					{Rank: 1, Name: "london", Value: &model.Measurement{Value: 45, Unit: "%"}, Days: 2, Wins: 1},
+					{Rank: 2, Name: "paris", Value: &model.Measurement{Value: 20, Unit: "%"}, Days: 1, Wins: 1},
+					{Rank: 3, Name: "rome"},
+				},
+				Days: []model.DayComparison{
+					{Date: "2024-09-23", Values: []model.CityValue{
+						{Name: "london", Value: &model.Measurement{Value: 80, Unit: "%"}},
+						{Name: "paris", Value: &model.Measurement{Value: 20, Unit: "%"}},
+						{Name: "rome"},
+					}, Winners: []string{"paris"}},
+					{Date: "2024-09-24", Values: []model.CityValue{
+						{Name: "london", Value: &model.Measurement{Value: 10, Unit: "%"}},
+						{Name: "paris"},
+						{Name: "rome"},
+					}, Winners: []string{"london"}},
+				},
+			},
+		},
+		{
+			name:   "when date is set should only compare its wind",
+			metric: MetricWind,
+			date:   time.Date(2024, 9, 24, 0, 0, 0, 0, time.UTC),
+			want: model.Comparison{
+				Metric: MetricWind,
+				Best:   "lowest",
+				Ranking: []model.CityRank{
+					{Rank: 1, Name: "paris", Value: &model.Measurement{Value: 10, Unit: "mph"}, Days: 1, Wins: 1},
+					{Rank: 2, Name: "london", Value: &model.Measurement{Value: 15, Unit: "mph"}, Days: 1},
+					{Rank: 3, Name: "rome"},
+				},
+				Days: []model.DayComparison{
+					{Date: "2024-09-24", Values: []model.CityValue{
+						{Name: "london", Value: &model.Measurement{Value: 15, Unit: "mph"}},
+						{Name: "paris", Value: &model.Measurement{Value: 10, Unit: "mph"}},
+						{Name: "rome"},
+					}, Winners: []string{"paris"}},
+				},
+			},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repoMock := repositoryMock.NewMockRepository(ctrl)
+			openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+			weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+			alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+			repoMock.EXPECT().GetLocation(gomock.Any()).Return(location, true).AnyTimes()
+			for city, days := range forecasts {
+				for i, d := range days {
+					start := fakeTime.AddDate(0, 0, i)
+					repoMock.EXPECT().GetPeriods(city, start.Format("2006-01-02")).Return(respository.CachedPeriod{Period: model.Period{
+						StartTime:                  start,
+						EndTime:                    start.Add(time.Hour),
+						Temperature:                d.temperature,
+						TemperatureUnit:            "F",
+						ProbabilityOfPrecipitation: model.QuantitativeValue{Value: d.precipitation},
+						WindSpeed:                  d.wind,
+					}, FetchedAt: fakeTime}, true).AnyTimes()
+				}
+			}
+
+			weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+			ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+
+			got, err := weatherAppController.CompareForecast(ctx, []string{"london", "paris", "rome"}, tc.metric, tc.date, ForecastOptions{Days: 2})
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCompareForecastOutOfHorizon(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+	nowFunc = func() time.Time {
+		return time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	}
+
+	ctrl := gomock.NewController(t)
+	weatherAppController := New(openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl), weatherAPIMock.NewMockWeatherGateway(ctrl),
+		alertsAPIMock.NewMockAlertsGateway(ctrl), repositoryMock.NewMockRepository(ctrl))
+
+	_, err := weatherAppController.CompareForecast(context.Background(), []string{"london"}, MetricWind, time.Date(2024, 9, 30, 0, 0, 0, 0, time.UTC), ForecastOptions{})
+	require.ErrorIs(t, err, ErrForecastNotFound)
+}
+
+func TestCompareForecastCached(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+	float := func(v float64) *float64 { return &v }
+	// day and night periods of the 23rd and the 24th, the nights would rank
+	// paris first
+	periods := func(day, night float64) []model.Period {
+		var result []model.Period
+		for i := 0; i < 2; i++ {
+			start := time.Date(2024, 9, 23+i, 6, 0, 0, 0, time.UTC)
+			result = append(result,
+				model.Period{StartTime: start, EndTime: start.Add(12 * time.Hour), Temperature: float(day), TemperatureUnit: "F"},
+				model.Period{StartTime: start.Add(12 * time.Hour), EndTime: start.Add(24 * time.Hour), Temperature: float(night), TemperatureUnit: "F"})
+		}
+		return result
+	}
+
+	ctrl := gomock.NewController(t)
+	weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+	cache := respository.New(time.Hour, time.Minute, 0, 0)
+	cache.PutLocation("london", model.Location{Lat: "51.5", Lon: "-0.1"})
+	cache.PutLocation("paris", model.Location{Lat: "48.9", Lon: "2.4"})
+	// the second request is served from cache
+	weatherAPIMock.EXPECT().GetForecast(gomock.Any(), "51.5", "-0.1").Return(periods(80, 40), nil).Times(1)
+	weatherAPIMock.EXPECT().GetForecast(gomock.Any(), "48.9", "2.4").Return(periods(70, 60), nil).Times(1)
+
+	weatherAppController := New(openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl), weatherAPIMock,
+		alertsAPIMock.NewMockAlertsGateway(ctrl), cache)
+	ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+
+	fetched, err := weatherAppController.CompareForecast(ctx, []string{"london", "paris"}, MetricTemperature, time.Time{}, ForecastOptions{Days: 2})
+	require.NoError(t, err)
+	require.Equal(t, "london", fetched.Ranking[0].Name)
+
+	cached, err := weatherAppController.CompareForecast(ctx, []string{"london", "paris"}, MetricTemperature, time.Time{}, ForecastOptions{Days: 2})
+	require.NoError(t, err)
+	require.Equal(t, fetched, cached)
+}
@@ -3,154 +3,868 @@ package controller
 import (
 	"context"
 	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/dibrito/ennismore-weather-app/internal/clients/upstream"
 	respository "github.com/dibrito/ennismore-weather-app/internal/repository"
+	"github.com/dibrito/ennismore-weather-app/pkg/geo"
+	"github.com/dibrito/ennismore-weather-app/pkg/iso8601"
 	"github.com/dibrito/ennismore-weather-app/pkg/logging"
 	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/dibrito/ennismore-weather-app/pkg/units"
 	"go.uber.org/zap"
 )
 
-// ErrNotFound is returned when a requested record is not
-// found.
-// TODO: move to pkg
-var ErrNotFound = errors.New("not found")
-
 // nowFunc will get the now time when GetForecast is executed.
 var nowFunc = time.Now
 
+// staleObservationAge defines when the latest observation of a station is
+// reported as stale, stations usually report every hour.
+const staleObservationAge = 90 * time.Minute
+
+// maxStationAttempts defines how many of the nearest stations are tried when
+// a station has no latest observation.
+const maxStationAttempts = 3
+
+// DefaultForecastDays defines how many days are forecast when not requested,
+// MaxForecastDays is the weather.gov forecast horizon.
+const (
+	DefaultForecastDays = 3
+	MaxForecastDays     = 7
+)
+
+// revalidateTimeout bounds the background refresh of a stale forecast.
+const revalidateTimeout = 30 * time.Second
+
+// cache status of a forecast.
+const (
+	CacheHit   = "HIT"
+	CacheMiss  = "MISS"
+	CacheStale = "STALE"
+)
+
+// maxConcurrentLocations limits how many locations are forecast at the same
+// time so a long list won't flood the 3rd party APIs.
+const maxConcurrentLocations = 4
+
 type OpenstreetmapperGateway interface {
-	GetLocation(ctx context.Context, city string) ([]model.Location, error)
+	GetLocation(ctx context.Context, city, country string) ([]model.Location, error)
 }
 
 type WeatherGateway interface {
 	GetForecast(ctx context.Context, lat, long string) ([]model.Period, error)
+	GetStations(ctx context.Context, lat, long string) ([]model.Station, error)
+	GetLatestObservation(ctx context.Context, stationID string) (model.Observation, error)
+	GetGridpoint(ctx context.Context, lat, long string) (map[string]model.GridpointLayer, error)
+}
+
+type AlertsGateway interface {
+	GetAlerts(ctx context.Context, lat, long string) ([]model.Alert, error)
+}
+
+// ForecastOptions defines the optional parts of a forecast request.
+type ForecastOptions struct {
+	// IncludeAlerts attaches the active alerts to each city forecast.
+	IncludeAlerts bool
+	// Days defines how many days are forecast starting today, zero means DefaultForecastDays.
+	Days int
+	// Units converts the forecast temperatures, empty keeps weather.gov units.
+	Units units.System
 }
 
 // Controller defines a metadata service controller.
 type Controller struct {
 	openStreetMapperClient OpenstreetmapperGateway
 	weatherClient          WeatherGateway
+	alertsClient           AlertsGateway
 	cacheRepository        respository.Repository
+	// revalidating holds the keys of the stale forecasts being refreshed.
+	revalidating sync.Map
 }
 
 // New creates a weather-app service controller.
 func New(openStreetMapperClient OpenstreetmapperGateway,
-	weatherClient WeatherGateway, cache respository.Repository) *Controller {
+	weatherClient WeatherGateway, alertsClient AlertsGateway,
+	cache respository.Repository) *Controller {
 	return &Controller{
 		openStreetMapperClient: openStreetMapperClient,
 		weatherClient:          weatherClient,
+		alertsClient:           alertsClient,
 		cacheRepository:        cache,
 	}
 }
 
-// Get returns movie metadata by id.
-func (c *Controller) GetForecast(ctx context.Context, cities []string) (model.WeatherForecast, error) {
-	logger := logging.GetLoggerFromContext(ctx)
+// GetForecast returns the forecast for today + 2 days for each city.
+func (c *Controller) GetForecast(ctx context.Context, cities []string, opts ForecastOptions) (model.WeatherForecast, error) {
+	locations := make([]model.LocationQuery, 0, len(cities))
+	for _, city := range cities {
+		locations = append(locations, model.LocationQuery{Name: city})
+	}
+	return c.GetBatchForecast(ctx, locations, opts)
+}
+
+// GetBatchForecast returns the forecast for each location, locations can be
+// requested by name, name and country or coordinates. Locations are forecast
+// concurrently, the response keeps the requested order and skips failures.
+// When no location could be forecast the error of the first one is returned.
+func (c *Controller) GetBatchForecast(ctx context.Context, locations []model.LocationQuery, opts ForecastOptions) (model.WeatherForecast, error) {
 	var result model.WeatherForecast
 
+	forecasts, err := c.collectForecasts(ctx, locations, opts)
+	if err != nil {
+		return result, err
+	}
+	for _, f := range forecasts {
+		if f != nil {
+			result.Forecast = append(result.Forecast, *f)
+		}
+	}
+	return result, nil
+}
+
+// collectForecasts forecasts the locations concurrently, the forecasts keep
+// the position of their location and failures are left nil. It fails with the
+// error of the first location when none could be forecast.
+func (c *Controller) collectForecasts(ctx context.Context, locations []model.LocationQuery, opts ForecastOptions) ([]*model.Forecast, error) {
+	forecasts := make([]*model.Forecast, len(locations))
+	errs := make([]error, len(locations))
+	served := 0
+	for r := range c.StreamForecast(ctx, locations, opts) {
+		if r.Err != nil {
+			errs[r.Index] = r.Err
+			continue
+		}
+		forecasts[r.Index] = &r.Forecast
+		served++
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if served == 0 {
+		if err := firstError(errs); err != nil {
+			return nil, err
+		}
+	}
+	return forecasts, nil
+}
+
+// firstError returns the first non nil error.
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AreaForecast is the forecast of the known locations within a bounding box,
+// Total counts the locations within the box and Skipped names the ones left
+// out because they would exceed the upstream fetches of the request.
+type AreaForecast struct {
+	model.WeatherForecast
+	Total   int
+	Skipped []string
+}
+
+// GetAreaForecast returns the forecast of the cached locations within the
+// box, the closest to its center first. At most limit locations are forecast,
+// zero means all of them, and at most maxFetches of them may need weather.gov
+// because their forecast isn't cached, zero means no cap.
+func (c *Controller) GetAreaForecast(ctx context.Context, box geo.BBox, limit, maxFetches int, opts ForecastOptions) (AreaForecast, error) {
+	keys := c.cacheRepository.SearchLocations(box)
+	result := AreaForecast{Total: len(keys)}
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	locations, _, skipped := c.capFetches(keys, opts, maxFetches)
+	result.Skipped = skipped
+
+	forecast, err := c.GetBatchForecast(ctx, locations, opts)
+	if err != nil {
+		return result, err
+	}
+	result.WeatherForecast = forecast
+	return result, nil
+}
+
+// GetNearbyForecast returns the forecast of the cached locations within
+// radius km of p, the closest first. Each forecast carries its distance from
+// p, in km or miles following opts.Units, and its bearing. The limit and
+// maxFetches caps work as in GetAreaForecast.
+func (c *Controller) GetNearbyForecast(ctx context.Context, p geo.Point, radius float64, limit, maxFetches int, opts ForecastOptions) (AreaForecast, error) {
+	neighbors := c.cacheRepository.NearestLocations(p, radius, 0)
+	result := AreaForecast{Total: len(neighbors)}
+	if limit > 0 && len(neighbors) > limit {
+		neighbors = neighbors[:limit]
+	}
+
+	keys := make([]string, 0, len(neighbors))
+	for _, n := range neighbors {
+		keys = append(keys, n.Key)
+	}
+	locations, kept, skipped := c.capFetches(keys, opts, maxFetches)
+	result.Skipped = skipped
+
+	forecasts, err := c.collectForecasts(ctx, locations, opts)
+	if err != nil {
+		return result, err
+	}
+	for i, f := range forecasts {
+		if f == nil {
+			continue
+		}
+		n := neighbors[kept[i]]
+		// the index measures in km, units converts them from meters
+		distance, unit := units.Convert(n.Distance*1000, "m", opts.Units)
+		f.Distance = &model.Measurement{Value: distance, Unit: unit}
+		f.Bearing = &model.Measurement{Value: n.Bearing, Unit: "deg"}
+		result.Forecast = append(result.Forecast, *f)
+	}
+	return result, nil
+}
+
+// capFetches maps the location keys into queries, the keys past maxFetches
+// locations that need weather.gov because their forecast isn't cached are
+// skipped, zero means no cap. It returns the position of the kept keys and
+// the names of the skipped ones.
+func (c *Controller) capFetches(keys []string, opts ForecastOptions, maxFetches int) ([]model.LocationQuery, []int, []string) {
+	days := forecastDays(opts.Days)
+	locations := make([]model.LocationQuery, 0, len(keys))
+	var kept []int
+	var skipped []string
+	fetches := 0
+	for i, key := range keys {
+		query := model.ParseLocationKey(key)
+		// stale-if-error periods are refreshed before being served
+		if cached := c.getPeriodsFromCache(key, days); !cached.complete(days) || cached.freshness == respository.StaleIfError {
+			if maxFetches > 0 && fetches == maxFetches {
+				skipped = append(skipped, query.DisplayName())
+				continue
+			}
+			fetches++
+		}
+		locations = append(locations, query)
+		kept = append(kept, i)
+	}
+	return locations, kept, skipped
+}
+
+// ForecastResult represents the outcome of forecasting a single location,
+// Index is the position of the location on the request.
+type ForecastResult struct {
+	Index    int
+	Forecast model.Forecast
+	Err      error
+}
+
+// StreamForecast forecasts the locations concurrently and sends each result
+// as soon as it is ready. The channel is closed once every location is done
+// or ctx is cancelled, e.g. when the client disconnects.
+func (c *Controller) StreamForecast(ctx context.Context, locations []model.LocationQuery, opts ForecastOptions) <-chan ForecastResult {
+	days := forecastDays(opts.Days)
+	jobs := make([]forecastJob, 0, len(locations))
+	for _, query := range locations {
+		jobs = append(jobs, forecastJob{query: query, days: days})
+	}
+	return c.streamJobs(ctx, jobs, opts)
+}
+
+// forecastJob is a location to forecast on its own days.
+type forecastJob struct {
+	query model.LocationQuery
+	days  []time.Time
+}
+
+// streamJobs forecasts the jobs concurrently like StreamForecast, the result
+// indexes are the job positions.
+func (c *Controller) streamJobs(ctx context.Context, jobs []forecastJob, opts ForecastOptions) <-chan ForecastResult {
+	results := make(chan ForecastResult)
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < min(maxConcurrentLocations, len(jobs)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				forecast, err := c.forecastLocation(ctx, jobs[idx].query, jobs[idx].days, opts)
+				select {
+				case results <- ForecastResult{Index: idx, Forecast: forecast, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(results)
+		defer wg.Wait()
+		defer close(indexes)
+		for idx := range jobs {
+			select {
+			case indexes <- idx:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return results
+}
+
+// ForecastHorizon returns the first and the last day weather.gov forecasts,
+// today and MaxForecastDays - 1 days later.
+func ForecastHorizon() (time.Time, time.Time) {
+	days := forecastDays(MaxForecastDays)
+	return days[0], days[len(days)-1]
+}
+
+// forecastDays returns the days to forecast starting today.
+func forecastDays(numDays int) []time.Time {
 	// TODO probably don't need this
 	// since the firs element of the API is "today" always
 	now := nowFunc().UTC()
-	days := []time.Time{now, now.AddDate(0, 0, 1), now.AddDate(0, 0, 2)}
+	if numDays == 0 {
+		numDays = DefaultForecastDays
+	}
+	days := make([]time.Time, 0, numDays)
+	for i := 0; i < numDays; i++ {
+		days = append(days, now.AddDate(0, 0, i))
+	}
+	return days
+}
+
+// windowDays returns the days from from to to, both included, e.g. the days
+// of an itinerary leg.
+func windowDays(from, to time.Time) []time.Time {
+	var days []time.Time
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
+
+// forecastLocation returns the forecast of a location for the given days,
+// the returned forecast is always named after the location even on failures.
+func (c *Controller) forecastLocation(ctx context.Context, query model.LocationQuery, days []time.Time, opts ForecastOptions) (model.Forecast, error) {
+	logger := logging.GetLoggerFromContext(ctx)
+	forecast := model.Forecast{Name: query.DisplayName()}
+
+	location, err := c.getLocation(ctx, query)
+	if err != nil {
+		return forecast, err
+	}
+	forecast.Location = &location
+	city := query.Key()
+
+	// for each city I need one period per day: now + 2days by default
+	// either I get from cache
+	cached := c.getPeriodsFromCache(city, days)
+	logger.Info("cach periods",
+		zap.Any("periods", cached.periods))
+	periods := cached.periods
+	switch {
+	case cached.complete(days) && cached.freshness == respository.Fresh:
+		forecast.Cache = CacheHit
+		forecast.Age = cached.age()
+	case cached.complete(days) && cached.freshness == respository.Stale:
+		// serve the stale periods right away and refresh them in background
+		forecast.Cache, forecast.Stale = CacheStale, true
+		forecast.Age = cached.age()
+		c.revalidate(ctx, query)
+	default:
+		// or I query 3rd API
+		logger.Info("periods not found in cache, calling client",
+			zap.Any("days", days))
+		periodsClient, err := c.weatherClient.GetForecast(ctx, location.Lat, location.Lon)
+		if err != nil {
+			logger.Warn("unable to retrieve forecast",
+				zap.String("location", city),
+				zap.String("lat", location.Lat),
+				zap.String("log", location.Lon),
+				zap.Error(err))
+			if !cached.complete(days) {
+				return forecast, upstreamError("forecast", err)
+			}
+			// stale if error, the cached periods are still within the max staleness
+			forecast.Cache, forecast.Stale = CacheStale, true
+			forecast.Age = cached.age()
+			break
+		}
+		c.putPeriods(city, periodsClient)
+		forecast.Cache = CacheMiss
+		periods = periodsClient
+	}
+
+	logger.Info("finnding forecasts details",
+		zap.Any("days", days),
+		zap.Any("periods", periods),
+	)
+
+	// here we need today's forecast and next days
+	details := findForecast(periods, days, opts.Units)
+	if len(details) == 0 {
+		logger.Warn("unable find forecast for time period(now+2days)",
+			zap.String("location", city),
+			zap.String("lat", location.Lat),
+			zap.String("log", location.Lon))
+		return forecast, ErrForecastNotFound
+	}
+	forecast.Detail = details
+
+	if opts.IncludeAlerts {
+		// alerts are a best effort addition, a failure here won't drop the forecast
+		alerts, err := c.getAlerts(ctx, city, location)
+		if err != nil {
+			logger.Warn("unable to retrieve alerts",
+				zap.String("location", city),
+				zap.Error(err))
+		}
+		forecast.Alerts = alerts
+	}
+
+	return forecast, nil
+}
+
+// RefreshPeriods fetches the city forecast periods from the weather client,
+// bypassing the cached periods, and stores them in cache.
+func (c *Controller) RefreshPeriods(ctx context.Context, city string) ([]model.Period, error) {
+	return c.refreshPeriods(ctx, model.LocationQuery{Name: city})
+}
+
+// WarmForecast refreshes the cached forecast periods of a location so that
+// forecast requests keep being served from cache. A location with both a name
+// and coordinates is also cached under its name, the key requests by city
+// look up, with the coordinates as its location so it isn't geocoded.
+func (c *Controller) WarmForecast(ctx context.Context, query model.LocationQuery) error {
+	periods, err := c.refreshPeriods(ctx, query)
+	if err != nil || query.Name == "" || !query.HasCoordinates() {
+		return err
+	}
+
+	named := model.LocationQuery{Name: query.Name, Country: query.Country}
+	location, err := c.getLocation(ctx, query)
+	if err != nil {
+		return err
+	}
+	c.cacheRepository.PutLocation(named.Key(), location)
+	c.putPeriods(named.Key(), periods)
+	return nil
+}
+
+// refreshPeriods fetches the location forecast periods from the weather
+// client and stores them in cache under the location key.
+func (c *Controller) refreshPeriods(ctx context.Context, query model.LocationQuery) ([]model.Period, error) {
+	location, err := c.getLocation(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	periods, err := c.weatherClient.GetForecast(ctx, location.Lat, location.Lon)
+	if err != nil {
+		return nil, upstreamError("forecast", err)
+	}
+	c.putPeriods(query.Key(), periods)
+	return periods, nil
+}
+
+// GetAlerts returns the active alerts for each city, cities that fail are
+// skipped unless they all do, then the error of the first one is returned.
+func (c *Controller) GetAlerts(ctx context.Context, cities []string) (model.WeatherAlerts, error) {
+	logger := logging.GetLoggerFromContext(ctx)
+	var result model.WeatherAlerts
+	var errs []error
 
-	// get points for each city
 	for _, city := range cities {
-		var location model.Location
-		locationCache, ok := c.cacheRepository.GetLocation(city)
-		location = locationCache
-		if !ok {
-			logger.Info("location not found in cache, calling client",
-				zap.String("location", city))
-			locationClient, err := c.openStreetMapperClient.GetLocation(ctx, city)
-			if err != nil {
-				// here we won't fail the whole operation but log the failures
-				// better approach can be discussed, e.g. we could return the response
-				// also with failures, or provide some report of the operation.
-				logger.Warn("unable to retrieve location",
+		location, err := c.getLocation(ctx, model.LocationQuery{Name: city})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		alerts, err := c.getAlerts(ctx, city, location)
+		if err != nil {
+			logger.Warn("unable to retrieve alerts",
+				zap.String("location", city),
+				zap.String("lat", location.Lat),
+				zap.String("log", location.Lon),
+				zap.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+
+		result.Alerts = append(result.Alerts, model.CityAlerts{
+			Name:   city,
+			Alerts: alerts,
+		})
+	}
+
+	if len(result.Alerts) == 0 {
+		return result, firstError(errs)
+	}
+	return result, nil
+}
+
+// GetCurrentConditions returns the latest observation from the station
+// nearest to each city, converted to the given unit system. Cities fail like
+// in GetAlerts.
+func (c *Controller) GetCurrentConditions(ctx context.Context, cities []string, system units.System) (model.CurrentConditions, error) {
+	logger := logging.GetLoggerFromContext(ctx)
+	var result model.CurrentConditions
+	var errs []error
+
+	for _, city := range cities {
+		location, err := c.getLocation(ctx, model.LocationQuery{Name: city})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		station, observation, err := c.getObservation(ctx, city, location)
+		if err != nil {
+			logger.Warn("unable to retrieve observation",
+				zap.String("location", city),
+				zap.String("lat", location.Lat),
+				zap.String("log", location.Lon),
+				zap.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+
+		result.Current = append(result.Current, newCityConditions(city, station, observation, system))
+	}
+
+	if len(result.Current) == 0 {
+		return result, firstError(errs)
+	}
+	return result, nil
+}
+
+// GetGridForecast returns the requested gridpoint layers for each city,
+// expanded to hourly resolution. Cities fail like in GetAlerts.
+func (c *Controller) GetGridForecast(ctx context.Context, cities []string, layers []string) (model.GridForecast, error) {
+	logger := logging.GetLoggerFromContext(ctx)
+	var result model.GridForecast
+	var errs []error
+
+	for _, city := range cities {
+		location, err := c.getLocation(ctx, model.LocationQuery{Name: city})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		gridpoint, err := c.weatherClient.GetGridpoint(ctx, location.Lat, location.Lon)
+		if err != nil {
+			logger.Warn("unable to retrieve gridpoint",
+				zap.String("location", city),
+				zap.String("lat", location.Lat),
+				zap.String("log", location.Lon),
+				zap.Error(err))
+			errs = append(errs, upstreamError("gridpoint", err))
+			continue
+		}
+
+		grid := model.CityGrid{
+			Name:   city,
+			Layers: make(map[string]model.GridLayer, len(layers)),
+		}
+		for _, name := range layers {
+			layer, ok := gridpoint[name]
+			if !ok {
+				logger.Info("layer not found in gridpoint",
 					zap.String("location", city),
-					zap.Error(err))
-				continue
-			}
-			// location = locationClient
-			if len(locationClient) == 0 {
-				logger.Info("location not found",
-					zap.String("location", city))
+					zap.String("layer", name))
 				continue
 			}
-			// add to cache
-			location = locationClient[0]
-			c.cacheRepository.PutLocation(city, location)
-		}
-
-		// for each city I need 3 periods: now + 2days
-		// either I get from cache
-		periods := c.getPeriodsFromCache(city, days)
-		logger.Info("cach periods",
-			zap.Any("periods", periods))
-		if len(periods) != 3 {
-			// or I query 3rd API
-			logger.Info("periods not found in cache, calling client",
-				zap.Any("days", days))
-			periodsClient, err := c.weatherClient.GetForecast(ctx, location.Lat, location.Lon)
+			values, err := expandHourly(layer.Values)
 			if err != nil {
-				logger.Warn("unable to retrieve forecast",
+				logger.Warn("unable to parse gridpoint layer",
 					zap.String("location", city),
-					zap.String("lat", location.Lat),
-					zap.String("log", location.Lon),
+					zap.String("layer", name),
 					zap.Error(err))
 				continue
 			}
-			for _, p := range periodsClient {
-				c.cacheRepository.PutPeriods(city, p.StartTime.Format("2006-01-02"), p)
+			grid.Layers[name] = model.GridLayer{
+				Unit:   strings.TrimPrefix(layer.UOM, "wmoUnit:"),
+				Values: values,
 			}
-			periods = periodsClient
 		}
 
-		logger.Info("finnding forecasts details",
-			zap.Any("days", days),
-			zap.Any("periods", periods),
-		)
+		result.Grid = append(result.Grid, grid)
+	}
 
-		// here we need today's forecast and next 2 days
-		details := findForecast(periods, days)
-		if len(details) == 0 {
-			logger.Warn("unable find forecast for time period(now+2days)",
-				zap.String("location", city),
-				zap.String("lat", location.Lat),
-				zap.String("log", location.Lon))
+	if len(result.Grid) == 0 {
+		return result, firstError(errs)
+	}
+	return result, nil
+}
+
+// getLocation retrieves the location either from the requested coordinates,
+// from cache or from the openstreetmap client, failures are logged and
+// reported as an error so the caller can skip the city.
+func (c *Controller) getLocation(ctx context.Context, query model.LocationQuery) (model.Location, error) {
+	logger := logging.GetLoggerFromContext(ctx)
+
+	if query.HasCoordinates() {
+		// weather.gov redirects points with more than 4 decimals
+		return model.Location{
+			Lat:         strconv.FormatFloat(*query.Lat, 'f', 4, 64),
+			Lon:         strconv.FormatFloat(*query.Lon, 'f', 4, 64),
+			DisplayName: query.DisplayName(),
+		}, nil
+	}
+
+	city := query.Key()
+	location, ok := c.cacheRepository.GetLocation(city)
+	if ok {
+		return location, nil
+	}
+
+	logger.Info("location not found in cache, calling client",
+		zap.String("location", city))
+	locationClient, err := c.openStreetMapperClient.GetLocation(ctx, query.Name, query.Country)
+	if err != nil {
+		// here we won't fail the whole operation but log the failures
+		// better approach can be discussed, e.g. we could return the response
+		// also with failures, or provide some report of the operation.
+		logger.Warn("unable to retrieve location",
+			zap.String("location", city),
+			zap.Error(err))
+		return model.Location{}, upstreamError("location", err)
+	}
+	if len(locationClient) == 0 {
+		logger.Info("location not found",
+			zap.String("location", city))
+		return model.Location{}, ErrLocationNotFound
+	}
+	// add to cache
+	location = locationClient[0]
+	c.cacheRepository.PutLocation(city, location)
+	return location, nil
+}
+
+// getAlerts retrieves the active alerts for a city either from cache or from
+// the alerts client, alerts are deduplicated by ID before being cached.
+func (c *Controller) getAlerts(ctx context.Context, city string, location model.Location) ([]model.Alert, error) {
+	logger := logging.GetLoggerFromContext(ctx)
+
+	if alerts, ok := c.cacheRepository.GetAlerts(city); ok {
+		return alerts, nil
+	}
+
+	logger.Info("alerts not found in cache, calling client",
+		zap.String("location", city))
+	alertsClient, err := c.alertsClient.GetAlerts(ctx, location.Lat, location.Lon)
+	if err != nil {
+		return nil, upstreamError("alerts", err)
+	}
+
+	alerts := dedupeAlerts(alertsClient)
+	c.cacheRepository.PutAlerts(city, alerts)
+	return alerts, nil
+}
+
+// getObservation retrieves the latest observation for a city, the nearest
+// station is taken from cache or resolved with the weather client trying the
+// closest stations until one of them reports an observation.
+func (c *Controller) getObservation(ctx context.Context, city string, location model.Location) (model.Station, model.Observation, error) {
+	logger := logging.GetLoggerFromContext(ctx)
+
+	if station, ok := c.cacheRepository.GetStation(city); ok {
+		observation, err := c.weatherClient.GetLatestObservation(ctx, station.ID)
+		if err != nil {
+			return station, observation, upstreamError("observation", err)
+		}
+		return station, observation, nil
+	}
+
+	logger.Info("station not found in cache, calling client",
+		zap.String("location", city))
+	stations, err := c.weatherClient.GetStations(ctx, location.Lat, location.Lon)
+	if err != nil {
+		return model.Station{}, model.Observation{}, upstreamError("observation station", err)
+	}
+	if len(stations) == 0 {
+		return model.Station{}, model.Observation{}, ErrStationNotFound
+	}
+
+	var lastErr error
+	for i, station := range stations {
+		if i == maxStationAttempts {
+			break
+		}
+		observation, err := c.weatherClient.GetLatestObservation(ctx, station.ID)
+		if err != nil {
+			// only a station without observation is worth skipping, the next
+			// ones would fail the same when weather.gov is down or limiting
+			if !errors.Is(err, upstream.ErrNotFound) {
+				return model.Station{}, model.Observation{}, upstreamError("observation", err)
+			}
+			logger.Info("station without latest observation",
+				zap.String("station", station.ID),
+				zap.Error(err))
+			lastErr = err
 			continue
 		}
+		c.cacheRepository.PutStation(city, station)
+		return station, observation, nil
+	}
+	return model.Station{}, model.Observation{}, upstreamError("observation", lastErr)
+}
 
-		result.Forecast = append(result.Forecast, model.Forecast{
-			Name:   city,
-			Detail: details,
-		})
+// newCityConditions maps a station observation into the weather-app response.
+func newCityConditions(city string, station model.Station, observation model.Observation, system units.System) model.CityConditions {
+	age := nowFunc().Sub(observation.Timestamp)
+	return model.CityConditions{
+		Name:          city,
+		StationID:     station.ID,
+		StationName:   station.Name,
+		Timestamp:     observation.Timestamp,
+		Age:           int64(age.Seconds()),
+		Stale:         age > staleObservationAge,
+		Description:   observation.TextDescription,
+		Temperature:   newMeasurement(observation.Temperature, system),
+		Humidity:      newMeasurement(observation.RelativeHumidity, system),
+		WindSpeed:     newMeasurement(observation.WindSpeed, system),
+		WindDirection: newMeasurement(observation.WindDirection, system),
+		Visibility:    newMeasurement(observation.Visibility, system),
 	}
+}
 
-	// fill cache
+// newMeasurement converts a quantitative value, nil is returned when the
+// station did not report it.
+func newMeasurement(q model.QuantitativeValue, system units.System) *model.Measurement {
+	if q.Value == nil {
+		return nil
+	}
+	value, unit := units.Convert(*q.Value, q.UnitCode, system)
+	return &model.Measurement{
+		Value: math.Round(value*10) / 10,
+		Unit:  unit,
+	}
+}
 
+// expandHourly expands the gridpoint values, each one valid for an ISO 8601
+// interval, into one value per hour of the interval.
+func expandHourly(values []model.GridpointValue) ([]model.GridValue, error) {
+	var result []model.GridValue
+	for _, v := range values {
+		start, d, err := iso8601.ParseInterval(v.ValidTime)
+		if err != nil {
+			return nil, err
+		}
+		start = start.UTC()
+		for t := start; t.Before(start.Add(d)); t = t.Add(time.Hour) {
+			result = append(result, model.GridValue{
+				Time:  t,
+				Value: v.Value,
+			})
+		}
+	}
 	return result, nil
 }
 
+// dedupeAlerts removes repeated alerts keeping the first occurrence of each ID.
+func dedupeAlerts(alerts []model.Alert) []model.Alert {
+	seen := make(map[string]struct{}, len(alerts))
+	result := make([]model.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if _, ok := seen[a.ID]; ok {
+			continue
+		}
+		seen[a.ID] = struct{}{}
+		result = append(result, a)
+	}
+	return result
+}
+
+// cachedPeriods holds the cached periods of a location, freshness and
+// fetchedAt are the ones of the oldest period.
+type cachedPeriods struct {
+	periods   []model.Period
+	freshness respository.Freshness
+	fetchedAt time.Time
+}
+
+// complete reports whether there is a cached period for each day.
+func (c cachedPeriods) complete(days []time.Time) bool {
+	return len(c.periods) == len(days)
+}
+
+// age returns the seconds since the oldest period was fetched.
+func (c cachedPeriods) age() int64 {
+	return int64(nowFunc().Sub(c.fetchedAt).Seconds())
+}
+
 // getPeriodsFromCache will get all posible periods: now:2days
-func (c *Controller) getPeriodsFromCache(city string, days []time.Time) []model.Period {
-	var result []model.Period
+func (c *Controller) getPeriodsFromCache(city string, days []time.Time) cachedPeriods {
+	var result cachedPeriods
 	for _, day := range days {
-		if p, ok := c.cacheRepository.GetPeriods(city, day.Format("2006-01-02")); ok {
-			result = append(result, p)
+		p, ok := c.cacheRepository.GetPeriods(city, day.Format("2006-01-02"))
+		if !ok {
+			continue
+		}
+		result.periods = append(result.periods, p.Period)
+		result.freshness = max(result.freshness, p.Freshness)
+		if result.fetchedAt.IsZero() || p.FetchedAt.Before(result.fetchedAt) {
+			result.fetchedAt = p.FetchedAt
 		}
 	}
 	return result
 }
 
-// findForecast finds forecast for today + 2 days.
-func findForecast(periods []model.Period, days []time.Time) []model.Detail {
+// putPeriods caches the first period of each day, the one findForecast picks,
+// so the night period doesn't replace the day one and cached forecasts match
+// the fetched ones.
+func (c *Controller) putPeriods(key string, periods []model.Period) {
+	cached := make(map[string]bool, len(periods))
+	for _, p := range periods {
+		day := p.StartTime.Format("2006-01-02")
+		if cached[day] {
+			continue
+		}
+		cached[day] = true
+		c.cacheRepository.PutPeriods(key, day, p)
+	}
+}
+
+// revalidate refreshes the location periods in background, a location is
+// only refreshed once at a time.
+func (c *Controller) revalidate(ctx context.Context, query model.LocationQuery) {
+	key := query.Key()
+	if _, loaded := c.revalidating.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+
+	// the refresh outlives the request
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), revalidateTimeout)
+	go func() {
+		defer cancel()
+		defer c.revalidating.Delete(key)
+		if _, err := c.refreshPeriods(ctx, query); err != nil {
+			logging.GetLoggerFromContext(ctx).Warn("unable to revalidate forecast",
+				zap.String("location", key),
+				zap.Error(err))
+		}
+	}()
+}
+
+// findForecast finds forecast for each day, today + 2 days by default or any
+// window within the forecast horizon, see windowDays.
+func findForecast(periods []model.Period, days []time.Time, system units.System) []model.Detail {
 	var result []model.Detail
 	for _, day := range days {
 		for _, p := range periods {
 			if matchDate(p.StartTime, day) {
-				result = append(result, model.Detail(p))
+				result = append(result, newDetail(p, system))
 				break
 			}
 		}
@@ -159,13 +873,59 @@ func findForecast(periods []model.Period, days []time.Time) []model.Detail {
 	return result
 }
 
+// newDetail maps a forecast period into a forecast detail, converting the
+// temperature when a unit system is given.
+func newDetail(p model.Period, system units.System) model.Detail {
+	detail := model.Detail{
+		StartTime:                p.StartTime,
+		EndTime:                  p.EndTime,
+		Summary:                  p.ShortForecast,
+		Description:              p.Description,
+		Temperature:              p.Temperature,
+		TemperatureUnit:          p.TemperatureUnit,
+		PrecipitationProbability: p.ProbabilityOfPrecipitation.Value,
+		WindSpeed:                windSpeed(p.WindSpeed, system),
+	}
+	if system == "" || p.Temperature == nil {
+		return detail
+	}
+	value, unit := units.Convert(*p.Temperature, "deg"+p.TemperatureUnit, system)
+	value = math.Round(value)
+	detail.Temperature = &value
+	detail.TemperatureUnit = strings.TrimPrefix(unit, "deg")
+	return detail
+}
+
+// windSpeed parses the forecast wind speed, e.g. "5 to 10 mph", into its
+// highest speed, converted when a unit system is given. It is nil when the
+// speed can't be parsed.
+func windSpeed(s string, system units.System) *model.Measurement {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return nil
+	}
+	value, err := strconv.ParseFloat(fields[len(fields)-2], 64)
+	if err != nil {
+		return nil
+	}
+	unit := fields[len(fields)-1]
+	kmh := value
+	switch unit {
+	case "mph":
+		kmh = units.Kilometers(value, units.Imperial)
+	case "km/h":
+	default:
+		return nil
+	}
+	if system == "" {
+		return &model.Measurement{Value: value, Unit: unit}
+	}
+	value, unit = units.Convert(kmh, "wmoUnit:km_h-1", system)
+	return &model.Measurement{Value: math.Round(value), Unit: unit}
+}
+
 func matchDate(a, b time.Time) bool {
 	yearA, monthA, dayA := a.Date()
 	yearB, monthB, dayB := b.Date()
 	return yearA == yearB && monthA == monthB && dayA == dayB
 }
-
-// NOTE:this is test purpose only to get cache content during app execution, disregard this func.
-func (c *Controller) GetCache() model.CacheResponse {
-	return c.cacheRepository.GetCache()
-}
@@ -3,14 +3,21 @@ package controller
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
+	alertsAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/alerts"
 	openStreetMapAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/openstreetmap"
 	weatherAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/weather"
 	repositoryMock "github.com/dibrito/ennismore-weather-app/gen/mock/repository/memory"
+	"github.com/dibrito/ennismore-weather-app/internal/clients/upstream"
+	respository "github.com/dibrito/ennismore-weather-app/internal/repository"
+	"github.com/dibrito/ennismore-weather-app/pkg/geo"
 	"github.com/dibrito/ennismore-weather-app/pkg/logging"
 	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/dibrito/ennismore-weather-app/pkg/units"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap/zaptest"
@@ -21,6 +28,14 @@ var (
 		Lat: "123",
 		Lon: "-456",
 	}
+	alert = model.Alert{
+		ID:       "urn:oid:2.49.0.1.840.0.1",
+		Event:    "Heat Advisory",
+		Headline: "Heat Advisory issued September 23",
+		Severity: "Moderate",
+		Urgency:  "Expected",
+		AreaDesc: "Miami-Dade",
+	}
 )
 
 func TestGetForecast(t *testing.T) {
@@ -47,7 +62,9 @@ func TestGetForecast(t *testing.T) {
 				want := model.WeatherForecast{
 					Forecast: []model.Forecast{
 						{
-							Name: "london",
+							Name:     "london",
+							Cache:    CacheHit,
+							Location: &location,
 							Detail: []model.Detail{
 								{
 									StartTime:   nowFunc(),
@@ -75,7 +92,7 @@ func TestGetForecast(t *testing.T) {
 				mapMock *openStreetMapAPIMock.MockOpenstreetmapperGateway,
 				weatherMock *weatherAPIMock.MockWeatherGateway,
 				cacheMock *repositoryMock.MockRepository) {
-				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any()).Times(0)
+				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				weatherMock.EXPECT().GetForecast(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
 				cacheMock.EXPECT().GetLocation("london").Return(
@@ -85,12 +102,11 @@ func TestGetForecast(t *testing.T) {
 			},
 		},
 		{
-			name:   "when location not in cache and client call fails should skip city",
+			name:   "when location not in cache and client call fails should fail as unavailable",
 			cities: []string{"london"},
 			checkResponse: func(t *testing.T, got model.WeatherForecast, err error) {
-				require.NoError(t, err)
-				want := model.WeatherForecast{}
-				require.Equal(t, want, got)
+				require.Equal(t, KindUnavailable, KindOf(err))
+				require.Empty(t, got.Forecast)
 			},
 			setupMocks: func(
 				t *testing.T,
@@ -100,18 +116,18 @@ func TestGetForecast(t *testing.T) {
 				cacheMock.EXPECT().GetLocation("london").Return(
 					model.Location{},
 					false).Times(1)
-				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any()).Times(1).Return(
+				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(
 					[]model.Location{}, errors.New("client-err"),
 				)
 			},
 		},
 		{
-			name:   "when location not in cache and client call is OK but location array is empty should skip city",
+			name:   "when location not in cache and client call is OK but location array is empty should fail as not found",
 			cities: []string{"london"},
 			checkResponse: func(t *testing.T, got model.WeatherForecast, err error) {
-				require.NoError(t, err)
-				want := model.WeatherForecast{}
-				require.Equal(t, want, got)
+				require.ErrorIs(t, err, ErrLocationNotFound)
+				require.Equal(t, KindNotFound, KindOf(err))
+				require.Empty(t, got.Forecast)
 			},
 			setupMocks: func(
 				t *testing.T,
@@ -121,7 +137,7 @@ func TestGetForecast(t *testing.T) {
 				cacheMock.EXPECT().GetLocation("london").Return(
 					model.Location{},
 					false).Times(1)
-				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any()).Times(1).Return(
+				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(
 					[]model.Location{}, nil,
 				)
 			},
@@ -134,7 +150,9 @@ func TestGetForecast(t *testing.T) {
 				want := model.WeatherForecast{
 					Forecast: []model.Forecast{
 						{
-							Name: "london",
+							Name:     "london",
+							Cache:    CacheHit,
+							Location: &location,
 							Detail: []model.Detail{
 								{
 									StartTime:   nowFunc(),
@@ -165,7 +183,7 @@ func TestGetForecast(t *testing.T) {
 				cacheMock.EXPECT().GetLocation("london").Return(
 					model.Location{},
 					false).Times(1)
-				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any()).Times(1).Return(
+				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).Return(
 					[]model.Location{location}, nil,
 				)
 				// add location to cache
@@ -182,7 +200,9 @@ func TestGetForecast(t *testing.T) {
 				want := model.WeatherForecast{
 					Forecast: []model.Forecast{
 						{
-							Name: "london",
+							Name:     "london",
+							Cache:    CacheMiss,
+							Location: &location,
 							Detail: []model.Detail{
 								{
 									StartTime:   nowFunc(),
@@ -210,12 +230,12 @@ func TestGetForecast(t *testing.T) {
 				mapMock *openStreetMapAPIMock.MockOpenstreetmapperGateway,
 				weatherMock *weatherAPIMock.MockWeatherGateway,
 				cacheMock *repositoryMock.MockRepository) {
-				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any()).Times(0)
+				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				cacheMock.EXPECT().GetLocation("london").Return(
 					location,
 					true).Times(1)
 				cacheMock.EXPECT().GetPeriods("london", gomock.Any()).Return(
-					model.Period{}, false).Times(3)
+					respository.CachedPeriod{}, false).Times(3)
 				weatherMock.EXPECT().GetForecast(gomock.Any(), location.Lat, location.Lon).Times(1).Return(
 					[]model.Period{
 						{
@@ -255,66 +275,65 @@ func TestGetForecast(t *testing.T) {
 			},
 		},
 		{
-			name:   "when periods not in cache and call client NOT ok should skip city",
+			name:   "when periods not in cache and call client NOT ok should fail as unavailable",
 			cities: []string{"london"},
 			checkResponse: func(t *testing.T, got model.WeatherForecast, err error) {
-				require.NoError(t, err)
-				want := model.WeatherForecast{}
-				require.Equal(t, want, got)
+				require.Equal(t, KindUnavailable, KindOf(err))
+				require.Equal(t, "unable to retrieve forecast", DetailOf(err))
+				require.Empty(t, got.Forecast)
 			},
 			setupMocks: func(
 				t *testing.T,
 				mapMock *openStreetMapAPIMock.MockOpenstreetmapperGateway,
 				weatherMock *weatherAPIMock.MockWeatherGateway,
 				cacheMock *repositoryMock.MockRepository) {
-				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any()).Times(0)
+				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				cacheMock.EXPECT().GetLocation("london").Return(
 					location,
 					true).Times(1)
 				cacheMock.EXPECT().GetPeriods("london", gomock.Any()).Return(
-					model.Period{}, false).Times(3)
+					respository.CachedPeriod{}, false).Times(3)
 				weatherMock.EXPECT().GetForecast(gomock.Any(), location.Lat, location.Lon).Times(1).Return(
-					[]model.Period{}, errors.New("client-error"))
+					[]model.Period{}, &upstream.Error{Service: "weather.gov", Kind: upstream.ErrUnavailable, StatusCode: http.StatusInternalServerError})
 			},
 		},
 		{
-			name:   "when periods not matching days should skip city",
+			name:   "when periods not matching days should fail as not found",
 			cities: []string{"london"},
 			checkResponse: func(t *testing.T, got model.WeatherForecast, err error) {
-				require.NoError(t, err)
-				want := model.WeatherForecast{}
-				require.Equal(t, want, got)
+				require.ErrorIs(t, err, ErrForecastNotFound)
+				require.Empty(t, got.Forecast)
 			},
 			setupMocks: func(
 				t *testing.T,
 				mapMock *openStreetMapAPIMock.MockOpenstreetmapperGateway,
 				weatherMock *weatherAPIMock.MockWeatherGateway,
 				cacheMock *repositoryMock.MockRepository) {
-				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any()).Times(0)
+				mapMock.EXPECT().GetLocation(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				weatherMock.EXPECT().GetForecast(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 				cacheMock.EXPECT().GetLocation("london").Return(
 					location,
 					true).Times(1)
 				cacheMock.EXPECT().GetPeriods("london", gomock.Any()).Return(
-					model.Period{
+					respository.CachedPeriod{Period: model.Period{
 						StartTime:   nowFunc().AddDate(0, 0, 10),
 						EndTime:     nowFunc().AddDate(0, 0, 10).Add(time.Hour),
 						Description: "gray",
-					},
+					}, FetchedAt: nowFunc()},
 					true).Times(1)
 				cacheMock.EXPECT().GetPeriods("london", gomock.Any()).Return(
-					model.Period{
+					respository.CachedPeriod{Period: model.Period{
 						StartTime:   nowFunc().AddDate(0, 0, 11),
 						EndTime:     nowFunc().AddDate(0, 0, 11).Add(time.Hour),
 						Description: "gray",
-					},
+					}, FetchedAt: nowFunc()},
 					true).Times(1)
 				cacheMock.EXPECT().GetPeriods("london", gomock.Any()).Return(
-					model.Period{
+					respository.CachedPeriod{Period: model.Period{
 						StartTime:   nowFunc().AddDate(0, 0, 12),
 						EndTime:     nowFunc().AddDate(0, 0, 12).Add(time.Hour),
 						Description: "gray",
-					},
+					}, FetchedAt: nowFunc()},
 					true).Times(1)
 			},
 		},
@@ -327,39 +346,885 @@ func TestGetForecast(t *testing.T) {
 			repoMock := repositoryMock.NewMockRepository(ctrl)
 			openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
 			weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+			alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
 
-			weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, repoMock)
+			weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
 			logger := zaptest.NewLogger(t)
 			ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, logger)
 
 			tc.setupMocks(t, openStreetMapAPIMock, weatherAPIMock, repoMock)
 
-			got, err := weatherAppController.GetForecast(ctx, tc.cities)
+			got, err := weatherAppController.GetForecast(ctx, tc.cities, ForecastOptions{})
 			tc.checkResponse(t, got, err)
 		})
 	}
 }
 
+func TestGetForecastIncludeAlerts(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+
+	ctrl := gomock.NewController(t)
+	repoMock := repositoryMock.NewMockRepository(ctrl)
+	openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+	weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+	alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+	repoMock.EXPECT().GetLocation("london").Return(location, true).Times(1)
+	setGetPeriodCalls(repoMock)
+	repoMock.EXPECT().GetAlerts("london").Return(nil, false).Times(1)
+	alertsAPIMock.EXPECT().GetAlerts(gomock.Any(), location.Lat, location.Lon).Return(
+		[]model.Alert{alert, alert}, nil).Times(1)
+	repoMock.EXPECT().PutAlerts("london", []model.Alert{alert}).Times(1)
+
+	weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+	ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+
+	got, err := weatherAppController.GetForecast(ctx, []string{"london"}, ForecastOptions{IncludeAlerts: true})
+	require.NoError(t, err)
+	require.Len(t, got.Forecast, 1)
+	require.Len(t, got.Forecast[0].Detail, 3)
+	require.Equal(t, []model.Alert{alert}, got.Forecast[0].Alerts)
+}
+
+func TestGetForecastStale(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+
+	cached := model.Period{StartTime: fakeTime, EndTime: fakeTime.Add(time.Hour), Description: "cached"}
+	fresh := model.Period{StartTime: fakeTime, EndTime: fakeTime.Add(time.Hour), Description: "fresh"}
+	tcs := []struct {
+		name      string
+		freshness respository.Freshness
+		clientErr error
+		want      model.Forecast
+	}{
+		{
+			name:      "when stale should return cached data and refresh in background",
+			freshness: respository.Stale,
+			want: model.Forecast{Name: "london", Stale: true, Cache: CacheStale, Age: 1900, Location: &location,
+				Detail: []model.Detail{{StartTime: cached.StartTime, EndTime: cached.EndTime, Description: "cached"}}},
+		},
+		{
+			name:      "when stale if error and client fails should return cached data",
+			freshness: respository.StaleIfError,
+			clientErr: errors.New("client-error"),
+			want: model.Forecast{Name: "london", Stale: true, Cache: CacheStale, Age: 1900, Location: &location,
+				Detail: []model.Detail{{StartTime: cached.StartTime, EndTime: cached.EndTime, Description: "cached"}}},
+		},
+		{
+			name:      "when stale if error and client is ok should return fresh data",
+			freshness: respository.StaleIfError,
+			want: model.Forecast{Name: "london", Cache: CacheMiss, Location: &location,
+				Detail: []model.Detail{{StartTime: fresh.StartTime, EndTime: fresh.EndTime, Description: "fresh"}}},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repoMock := repositoryMock.NewMockRepository(ctrl)
+			openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+			weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+			alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+			refreshed := make(chan struct{})
+			repoMock.EXPECT().GetLocation("london").Return(location, true).AnyTimes()
+			repoMock.EXPECT().GetPeriods("london", gomock.Any()).Return(respository.CachedPeriod{
+				Period:    cached,
+				FetchedAt: fakeTime.Add(-1900 * time.Second),
+				Freshness: tc.freshness,
+			}, true).Times(1)
+			weatherAPIMock.EXPECT().GetForecast(gomock.Any(), location.Lat, location.Lon).DoAndReturn(
+				func(context.Context, string, string) ([]model.Period, error) {
+					if tc.clientErr != nil {
+						return nil, tc.clientErr
+					}
+					return []model.Period{fresh}, nil
+				}).Times(1)
+			if tc.clientErr == nil {
+				repoMock.EXPECT().PutPeriods("london", "2024-09-23", fresh).Do(
+					func(string, string, model.Period) { close(refreshed) }).Times(1)
+			} else {
+				close(refreshed)
+			}
+
+			weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+			ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+
+			got, err := weatherAppController.GetForecast(ctx, []string{"london"}, ForecastOptions{Days: 1})
+			require.NoError(t, err)
+			require.Equal(t, model.WeatherForecast{Forecast: []model.Forecast{tc.want}}, got)
+
+			select {
+			case <-refreshed:
+			case <-time.After(time.Second):
+				t.Fatal("forecast wasn't refreshed")
+			}
+		})
+	}
+}
+
+func TestGetBatchForecast(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+
+	ctrl := gomock.NewController(t)
+	repoMock := repositoryMock.NewMockRepository(ctrl)
+	openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+	weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+	alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+	lat, lon := 38.89511, -77.03637
+	temperature := 68.0
+	periods := []model.Period{
+		{StartTime: fakeTime, EndTime: fakeTime.Add(2 * time.Hour), Description: "sunny",
+			Temperature: &temperature, TemperatureUnit: "F"},
+		{StartTime: fakeTime.AddDate(0, 0, 1), EndTime: fakeTime.AddDate(0, 0, 1).Add(2 * time.Hour), Description: "gray",
+			Temperature: &temperature, TemperatureUnit: "F"},
+	}
+
+	// coordinates skip the location lookup
+	openStreetMapAPIMock.EXPECT().GetLocation(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	repoMock.EXPECT().GetLocation(gomock.Any()).Times(0)
+	repoMock.EXPECT().GetPeriods("38.8951,-77.0364", gomock.Any()).Return(respository.CachedPeriod{}, false).Times(2)
+	weatherAPIMock.EXPECT().GetForecast(gomock.Any(), "38.8951", "-77.0364").Return(periods, nil).Times(1)
+	repoMock.EXPECT().PutPeriods("38.8951,-77.0364", gomock.Any(), gomock.Any()).Times(2)
+
+	weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+	ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+
+	got, err := weatherAppController.GetBatchForecast(ctx,
+		[]model.LocationQuery{{Name: "white house", Lat: &lat, Lon: &lon}},
+		ForecastOptions{Days: 2, Units: units.Metric})
+	require.NoError(t, err)
+
+	celsius := 20.0
+	want := model.WeatherForecast{
+		Forecast: []model.Forecast{
+			{
+				Name:     "white house",
+				Cache:    CacheMiss,
+				Location: &model.Location{Lat: "38.8951", Lon: "-77.0364", DisplayName: "white house"},
+				Detail: []model.Detail{
+					{StartTime: periods[0].StartTime, EndTime: periods[0].EndTime, Description: "sunny",
+						Temperature: &celsius, TemperatureUnit: "C"},
+					{StartTime: periods[1].StartTime, EndTime: periods[1].EndTime, Description: "gray",
+						Temperature: &celsius, TemperatureUnit: "C"},
+				},
+			},
+		},
+	}
+	require.Equal(t, want, got)
+}
+
+func TestGetAreaForecast(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+	period := model.Period{StartTime: fakeTime, EndTime: fakeTime.Add(2 * time.Hour), Description: "gray"}
+	box := geo.BBox{MinLon: -10, MinLat: 40, MaxLon: 15, MaxLat: 55}
+
+	tcs := []struct {
+		name        string
+		limit       int
+		maxFetches  int
+		wantNames   []string
+		wantSkipped []string
+	}{
+		{
+			name:        "when fetches cap reached should skip the uncached locations",
+			maxFetches:  1,
+			wantNames:   []string{"london", "paris"},
+			wantSkipped: []string{"berlin"},
+		},
+		{
+			name:      "when limited should only forecast the closest locations",
+			limit:     2,
+			wantNames: []string{"london", "paris"},
+		},
+		{
+			name:      "when no cap should forecast every location",
+			wantNames: []string{"london", "paris", "berlin"},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repoMock := repositoryMock.NewMockRepository(ctrl)
+			openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+			weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+			alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+			repoMock.EXPECT().SearchLocations(box).Return([]string{"london", "paris|fr", "berlin"}).Times(1)
+			repoMock.EXPECT().GetLocation(gomock.Any()).Return(location, true).AnyTimes()
+			// london is cached, paris and berlin need weather.gov
+			repoMock.EXPECT().GetPeriods("london", "2024-09-23").Return(
+				respository.CachedPeriod{Period: period, FetchedAt: fakeTime}, true).AnyTimes()
+			repoMock.EXPECT().GetPeriods(gomock.Any(), "2024-09-23").Return(respository.CachedPeriod{}, false).AnyTimes()
+			repoMock.EXPECT().PutPeriods(gomock.Any(), "2024-09-23", period).Times(len(tc.wantNames) - 1)
+			weatherAPIMock.EXPECT().GetForecast(gomock.Any(), location.Lat, location.Lon).Return(
+				[]model.Period{period}, nil).Times(len(tc.wantNames) - 1)
+
+			weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+			ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+
+			got, err := weatherAppController.GetAreaForecast(ctx, box, tc.limit, tc.maxFetches, ForecastOptions{Days: 1})
+			require.NoError(t, err)
+			require.Equal(t, 3, got.Total)
+			require.Equal(t, tc.wantSkipped, got.Skipped)
+			names := make([]string, 0, len(got.Forecast))
+			for _, f := range got.Forecast {
+				names = append(names, f.Name)
+			}
+			require.Equal(t, tc.wantNames, names)
+		})
+	}
+}
+
+func TestGetNearbyForecast(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+	period := model.Period{StartTime: fakeTime, EndTime: fakeTime.Add(2 * time.Hour), Description: "gray"}
+	charingCross := geo.Point{Lat: 51.5080, Lon: -0.1247}
+	neighbors := []geo.Neighbor{
+		{Key: "london", Distance: 0.2, Bearing: 248.8},
+		{Key: "atlantis", Distance: 5, Bearing: 90},
+		{Key: "paris|fr", Distance: 343.5, Bearing: 148.1},
+		{Key: "berlin", Distance: 930.1, Bearing: 78.3},
+	}
+
+	tcs := []struct {
+		name         string
+		limit        int
+		maxFetches   int
+		units        units.System
+		wantTotal    int
+		wantForecast []model.Forecast
+		wantSkipped  []string
+	}{
+		{
+			name:  "when a location fails should keep the distances of the others",
+			units: units.Metric,
+			wantForecast: []model.Forecast{
+				{Name: "london", Distance: &model.Measurement{Value: 0.2, Unit: "km"}, Bearing: &model.Measurement{Value: 248.8, Unit: "deg"}},
+				{Name: "paris", Distance: &model.Measurement{Value: 343.5, Unit: "km"}, Bearing: &model.Measurement{Value: 148.1, Unit: "deg"}},
+				{Name: "berlin", Distance: &model.Measurement{Value: 930.1, Unit: "km"}, Bearing: &model.Measurement{Value: 78.3, Unit: "deg"}},
+			},
+		},
+		{
+			name:       "when fetches cap reached should skip the uncached locations",
+			maxFetches: 2,
+			units:      units.Imperial,
+			wantForecast: []model.Forecast{
+				{Name: "london", Distance: &model.Measurement{Value: 0.2 / 1.609344, Unit: "mi"}, Bearing: &model.Measurement{Value: 248.8, Unit: "deg"}},
+				{Name: "paris", Distance: &model.Measurement{Value: 343.5 / 1.609344, Unit: "mi"}, Bearing: &model.Measurement{Value: 148.1, Unit: "deg"}},
+			},
+			wantSkipped: []string{"berlin"},
+		},
+		{
+			name:  "when limited should only forecast the closest locations",
+			limit: 1,
+			wantForecast: []model.Forecast{
+				{Name: "london", Distance: &model.Measurement{Value: 0.2, Unit: "km"}, Bearing: &model.Measurement{Value: 248.8, Unit: "deg"}},
+			},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repoMock := repositoryMock.NewMockRepository(ctrl)
+			openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+			weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+			alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+			repoMock.EXPECT().NearestLocations(charingCross, 1000.0, 0).Return(neighbors).Times(1)
+			repoMock.EXPECT().GetLocation("atlantis").Return(model.Location{}, false).AnyTimes()
+			openStreetMapAPIMock.EXPECT().GetLocation(gomock.Any(), "atlantis", "").Return(nil, nil).AnyTimes()
+			repoMock.EXPECT().GetLocation(gomock.Any()).Return(location, true).AnyTimes()
+			// london is cached, the others need weather.gov
+			repoMock.EXPECT().GetPeriods("london", "2024-09-23").Return(
+				respository.CachedPeriod{Period: period, FetchedAt: fakeTime}, true).AnyTimes()
+			repoMock.EXPECT().GetPeriods(gomock.Any(), "2024-09-23").Return(respository.CachedPeriod{}, false).AnyTimes()
+			repoMock.EXPECT().PutPeriods(gomock.Any(), "2024-09-23", period).AnyTimes()
+			weatherAPIMock.EXPECT().GetForecast(gomock.Any(), location.Lat, location.Lon).Return(
+				[]model.Period{period}, nil).AnyTimes()
+
+			weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+			ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+
+			got, err := weatherAppController.GetNearbyForecast(ctx, charingCross, 1000, tc.limit, tc.maxFetches, ForecastOptions{Days: 1, Units: tc.units})
+			require.NoError(t, err)
+			require.Equal(t, 4, got.Total)
+			require.Equal(t, tc.wantSkipped, got.Skipped)
+			require.Len(t, got.Forecast, len(tc.wantForecast))
+			for i, want := range tc.wantForecast {
+				require.Equal(t, want.Name, got.Forecast[i].Name)
+				require.Equal(t, want.Distance.Unit, got.Forecast[i].Distance.Unit)
+				require.InDelta(t, want.Distance.Value, got.Forecast[i].Distance.Value, 1e-9)
+				require.Equal(t, want.Bearing, got.Forecast[i].Bearing)
+			}
+		})
+	}
+}
+
+func TestGetBatchForecastKeepsOrder(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+
+	ctrl := gomock.NewController(t)
+	repoMock := repositoryMock.NewMockRepository(ctrl)
+	openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+	weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+	alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+	cities := []string{"london", "paris", "atlantis", "rome", "lisbon", "madrid"}
+	for _, city := range cities {
+		if city == "atlantis" {
+			repoMock.EXPECT().GetLocation(city).Return(model.Location{}, false).Times(1)
+			openStreetMapAPIMock.EXPECT().GetLocation(gomock.Any(), city, "").Return(nil, nil).Times(1)
+			continue
+		}
+		repoMock.EXPECT().GetLocation(city).Return(location, true).Times(1)
+		repoMock.EXPECT().GetPeriods(city, gomock.Any()).Return(respository.CachedPeriod{Period: model.Period{
+			StartTime:   fakeTime,
+			EndTime:     fakeTime.Add(time.Hour),
+			Description: city,
+		}, FetchedAt: nowFunc()}, true).Times(1)
+	}
+
+	weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+	ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+
+	got, err := weatherAppController.GetForecast(ctx, cities, ForecastOptions{Days: 1})
+	require.NoError(t, err)
+
+	var names []string
+	for _, f := range got.Forecast {
+		names = append(names, f.Name)
+	}
+	require.Equal(t, []string{"london", "paris", "rome", "lisbon", "madrid"}, names)
+}
+
+func TestStreamForecastStopsOnCancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repoMock := repositoryMock.NewMockRepository(ctrl)
+	openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+	weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+	alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+	repoMock.EXPECT().GetLocation(gomock.Any()).Return(model.Location{}, false).AnyTimes()
+	openStreetMapAPIMock.EXPECT().GetLocation(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t)))
+
+	locations := make([]model.LocationQuery, 100)
+	for i := range locations {
+		locations[i] = model.LocationQuery{Name: fmt.Sprintf("city-%d", i)}
+	}
+	results := weatherAppController.StreamForecast(ctx, locations, ForecastOptions{})
+	<-results
+	cancel()
+
+	received := 1
+	for range results {
+		received++
+	}
+	require.Less(t, received, len(locations))
+}
+
+func TestWarmForecast(t *testing.T) {
+	lat, lon := 47.6062, -122.3321
+	start := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	periods := []model.Period{
+		{StartTime: start, EndTime: start.Add(12 * time.Hour), Description: "sunny"},
+	}
+	seattle := model.Location{Lat: "47.6062", Lon: "-122.3321", DisplayName: "seattle"}
+
+	tcs := []struct {
+		name      string
+		query     model.LocationQuery
+		wantKeys  []string
+		wantNamed bool
+	}{
+		{
+			name:     "coordinates",
+			query:    model.LocationQuery{Lat: &lat, Lon: &lon},
+			wantKeys: []string{"47.6062,-122.3321"},
+		},
+		{
+			name:      "name and coordinates",
+			query:     model.LocationQuery{Name: "seattle", Lat: &lat, Lon: &lon},
+			wantKeys:  []string{"47.6062,-122.3321", "seattle"},
+			wantNamed: true,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repoMock := repositoryMock.NewMockRepository(ctrl)
+			openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+			weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+			alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+			// configured coordinates are never geocoded
+			openStreetMapAPIMock.EXPECT().GetLocation(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			weatherAPIMock.EXPECT().GetForecast(gomock.Any(), "47.6062", "-122.3321").Return(periods, nil).Times(1)
+			for _, key := range tc.wantKeys {
+				repoMock.EXPECT().PutPeriods(key, "2024-09-23", periods[0]).Times(1)
+			}
+			if tc.wantNamed {
+				repoMock.EXPECT().PutLocation("seattle", seattle).Times(1)
+			}
+
+			weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+			ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+			require.NoError(t, weatherAppController.WarmForecast(ctx, tc.query))
+		})
+	}
+}
+
+func TestGetAlerts(t *testing.T) {
+	tcs := []struct {
+		name          string
+		cities        []string
+		checkResponse func(t *testing.T, got model.WeatherAlerts, err error)
+		setupMocks    func(t *testing.T, mapMock *openStreetMapAPIMock.MockOpenstreetmapperGateway,
+			alertsMock *alertsAPIMock.MockAlertsGateway, cacheMock *repositoryMock.MockRepository)
+	}{
+		{
+			name:   "when alerts in cache should return cached alerts",
+			cities: []string{"miami"},
+			checkResponse: func(t *testing.T, got model.WeatherAlerts, err error) {
+				require.NoError(t, err)
+				want := model.WeatherAlerts{
+					Alerts: []model.CityAlerts{{Name: "miami", Alerts: []model.Alert{alert}}},
+				}
+				require.Equal(t, want, got)
+			},
+			setupMocks: func(
+				t *testing.T,
+				mapMock *openStreetMapAPIMock.MockOpenstreetmapperGateway,
+				alertsMock *alertsAPIMock.MockAlertsGateway,
+				cacheMock *repositoryMock.MockRepository) {
+				cacheMock.EXPECT().GetLocation("miami").Return(location, true).Times(1)
+				cacheMock.EXPECT().GetAlerts("miami").Return([]model.Alert{alert}, true).Times(1)
+				alertsMock.EXPECT().GetAlerts(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:   "when alerts not in cache should dedupe and add alerts to cache",
+			cities: []string{"miami"},
+			checkResponse: func(t *testing.T, got model.WeatherAlerts, err error) {
+				require.NoError(t, err)
+				want := model.WeatherAlerts{
+					Alerts: []model.CityAlerts{{Name: "miami", Alerts: []model.Alert{alert}}},
+				}
+				require.Equal(t, want, got)
+			},
+			setupMocks: func(
+				t *testing.T,
+				mapMock *openStreetMapAPIMock.MockOpenstreetmapperGateway,
+				alertsMock *alertsAPIMock.MockAlertsGateway,
+				cacheMock *repositoryMock.MockRepository) {
+				cacheMock.EXPECT().GetLocation("miami").Return(location, true).Times(1)
+				cacheMock.EXPECT().GetAlerts("miami").Return(nil, false).Times(1)
+				alertsMock.EXPECT().GetAlerts(gomock.Any(), location.Lat, location.Lon).Return(
+					[]model.Alert{alert, alert}, nil).Times(1)
+				cacheMock.EXPECT().PutAlerts("miami", []model.Alert{alert}).Times(1)
+			},
+		},
+		{
+			name:   "when alerts client call fails should fail as unavailable",
+			cities: []string{"miami"},
+			checkResponse: func(t *testing.T, got model.WeatherAlerts, err error) {
+				require.Equal(t, KindUnavailable, KindOf(err))
+				require.Equal(t, model.WeatherAlerts{}, got)
+			},
+			setupMocks: func(
+				t *testing.T,
+				mapMock *openStreetMapAPIMock.MockOpenstreetmapperGateway,
+				alertsMock *alertsAPIMock.MockAlertsGateway,
+				cacheMock *repositoryMock.MockRepository) {
+				cacheMock.EXPECT().GetLocation("miami").Return(location, true).Times(1)
+				cacheMock.EXPECT().GetAlerts("miami").Return(nil, false).Times(1)
+				alertsMock.EXPECT().GetAlerts(gomock.Any(), location.Lat, location.Lon).Return(
+					nil, errors.New("client-error")).Times(1)
+			},
+		},
+		{
+			name:   "when only some cities fail should skip them",
+			cities: []string{"atlantis", "miami"},
+			checkResponse: func(t *testing.T, got model.WeatherAlerts, err error) {
+				require.NoError(t, err)
+				want := model.WeatherAlerts{
+					Alerts: []model.CityAlerts{{Name: "miami", Alerts: []model.Alert{alert}}},
+				}
+				require.Equal(t, want, got)
+			},
+			setupMocks: func(
+				t *testing.T,
+				mapMock *openStreetMapAPIMock.MockOpenstreetmapperGateway,
+				alertsMock *alertsAPIMock.MockAlertsGateway,
+				cacheMock *repositoryMock.MockRepository) {
+				cacheMock.EXPECT().GetLocation("atlantis").Return(model.Location{}, false).Times(1)
+				mapMock.EXPECT().GetLocation(gomock.Any(), "atlantis", "").Return(nil, nil).Times(1)
+				cacheMock.EXPECT().GetLocation("miami").Return(location, true).Times(1)
+				cacheMock.EXPECT().GetAlerts("miami").Return([]model.Alert{alert}, true).Times(1)
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repoMock := repositoryMock.NewMockRepository(ctrl)
+			openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+			weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+			alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+			weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+			logger := zaptest.NewLogger(t)
+			ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, logger)
+
+			tc.setupMocks(t, openStreetMapAPIMock, alertsAPIMock, repoMock)
+
+			got, err := weatherAppController.GetAlerts(ctx, tc.cities)
+			tc.checkResponse(t, got, err)
+		})
+	}
+}
+
+func TestGetCurrentConditions(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+
+	temperature, humidity, windSpeed := 20.0, 65.5, 16.09344
+	observation := model.Observation{
+		Timestamp:        fakeTime.Add(-30 * time.Minute),
+		TextDescription:  "Mostly Cloudy",
+		Temperature:      model.QuantitativeValue{UnitCode: "wmoUnit:degC", Value: &temperature},
+		RelativeHumidity: model.QuantitativeValue{UnitCode: "wmoUnit:percent", Value: &humidity},
+		WindSpeed:        model.QuantitativeValue{UnitCode: "wmoUnit:km_h-1", Value: &windSpeed},
+	}
+	station := model.Station{ID: "KMDW", Name: "Chicago Midway"}
+
+	tcs := []struct {
+		name          string
+		system        units.System
+		checkResponse func(t *testing.T, got model.CurrentConditions, err error)
+		setupMocks    func(weatherMock *weatherAPIMock.MockWeatherGateway, cacheMock *repositoryMock.MockRepository)
+	}{
+		{
+			name:   "when station in cache should return metric observation",
+			system: units.Metric,
+			checkResponse: func(t *testing.T, got model.CurrentConditions, err error) {
+				require.NoError(t, err)
+				want := model.CurrentConditions{
+					Current: []model.CityConditions{
+						{
+							Name:        "chicago",
+							StationID:   "KMDW",
+							StationName: "Chicago Midway",
+							Timestamp:   observation.Timestamp,
+							Age:         1800,
+							Description: "Mostly Cloudy",
+							Temperature: &model.Measurement{Value: 20, Unit: "degC"},
+							Humidity:    &model.Measurement{Value: 65.5, Unit: "%"},
+							WindSpeed:   &model.Measurement{Value: 16.1, Unit: "km/h"},
+						},
+					},
+				}
+				require.Equal(t, want, got)
+			},
+			setupMocks: func(weatherMock *weatherAPIMock.MockWeatherGateway, cacheMock *repositoryMock.MockRepository) {
+				cacheMock.EXPECT().GetLocation("chicago").Return(location, true).Times(1)
+				cacheMock.EXPECT().GetStation("chicago").Return(station, true).Times(1)
+				weatherMock.EXPECT().GetStations(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				weatherMock.EXPECT().GetLatestObservation(gomock.Any(), "KMDW").Return(observation, nil).Times(1)
+			},
+		},
+		{
+			name:   "when nearest station has no observation should try next station and cache it",
+			system: units.Imperial,
+			checkResponse: func(t *testing.T, got model.CurrentConditions, err error) {
+				require.NoError(t, err)
+				require.Len(t, got.Current, 1)
+				require.Equal(t, "KMDW", got.Current[0].StationID)
+				require.Equal(t, &model.Measurement{Value: 68, Unit: "degF"}, got.Current[0].Temperature)
+				require.Equal(t, &model.Measurement{Value: 10, Unit: "mph"}, got.Current[0].WindSpeed)
+			},
+			setupMocks: func(weatherMock *weatherAPIMock.MockWeatherGateway, cacheMock *repositoryMock.MockRepository) {
+				cacheMock.EXPECT().GetLocation("chicago").Return(location, true).Times(1)
+				cacheMock.EXPECT().GetStation("chicago").Return(model.Station{}, false).Times(1)
+				weatherMock.EXPECT().GetStations(gomock.Any(), location.Lat, location.Lon).Return(
+					[]model.Station{{ID: "KXXX"}, station}, nil).Times(1)
+				weatherMock.EXPECT().GetLatestObservation(gomock.Any(), "KXXX").Return(
+					model.Observation{}, &upstream.Error{Service: "weather.gov", Kind: upstream.ErrNotFound, StatusCode: http.StatusNotFound}).Times(1)
+				weatherMock.EXPECT().GetLatestObservation(gomock.Any(), "KMDW").Return(observation, nil).Times(1)
+				cacheMock.EXPECT().PutStation("chicago", station).Times(1)
+			},
+		},
+		{
+			name:   "when weather.gov is rate limiting should not try next station",
+			system: units.Metric,
+			checkResponse: func(t *testing.T, got model.CurrentConditions, err error) {
+				require.Equal(t, KindRateLimited, KindOf(err))
+				require.Equal(t, model.CurrentConditions{}, got)
+			},
+			setupMocks: func(weatherMock *weatherAPIMock.MockWeatherGateway, cacheMock *repositoryMock.MockRepository) {
+				cacheMock.EXPECT().GetLocation("chicago").Return(location, true).Times(1)
+				cacheMock.EXPECT().GetStation("chicago").Return(model.Station{}, false).Times(1)
+				weatherMock.EXPECT().GetStations(gomock.Any(), location.Lat, location.Lon).Return(
+					[]model.Station{{ID: "KXXX"}, station}, nil).Times(1)
+				weatherMock.EXPECT().GetLatestObservation(gomock.Any(), "KXXX").Return(
+					model.Observation{}, &upstream.Error{Service: "weather.gov", Kind: upstream.ErrRateLimited, StatusCode: http.StatusTooManyRequests}).Times(1)
+				weatherMock.EXPECT().GetLatestObservation(gomock.Any(), "KMDW").Times(0)
+				cacheMock.EXPECT().PutStation(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:   "when observation is old should report stale",
+			system: units.Metric,
+			checkResponse: func(t *testing.T, got model.CurrentConditions, err error) {
+				require.NoError(t, err)
+				require.Len(t, got.Current, 1)
+				require.True(t, got.Current[0].Stale)
+				require.Equal(t, int64(3*60*60), got.Current[0].Age)
+			},
+			setupMocks: func(weatherMock *weatherAPIMock.MockWeatherGateway, cacheMock *repositoryMock.MockRepository) {
+				old := observation
+				old.Timestamp = fakeTime.Add(-3 * time.Hour)
+				cacheMock.EXPECT().GetLocation("chicago").Return(location, true).Times(1)
+				cacheMock.EXPECT().GetStation("chicago").Return(station, true).Times(1)
+				weatherMock.EXPECT().GetLatestObservation(gomock.Any(), "KMDW").Return(old, nil).Times(1)
+			},
+		},
+		{
+			name:   "when no stations should fail as not found",
+			system: units.Metric,
+			checkResponse: func(t *testing.T, got model.CurrentConditions, err error) {
+				require.ErrorIs(t, err, ErrStationNotFound)
+				require.Equal(t, model.CurrentConditions{}, got)
+			},
+			setupMocks: func(weatherMock *weatherAPIMock.MockWeatherGateway, cacheMock *repositoryMock.MockRepository) {
+				cacheMock.EXPECT().GetLocation("chicago").Return(location, true).Times(1)
+				cacheMock.EXPECT().GetStation("chicago").Return(model.Station{}, false).Times(1)
+				weatherMock.EXPECT().GetStations(gomock.Any(), location.Lat, location.Lon).Return(nil, nil).Times(1)
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repoMock := repositoryMock.NewMockRepository(ctrl)
+			openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+			weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+			alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+			weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+			ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+
+			tc.setupMocks(weatherAPIMock, repoMock)
+
+			got, err := weatherAppController.GetCurrentConditions(ctx, []string{"chicago"}, tc.system)
+			tc.checkResponse(t, got, err)
+		})
+	}
+}
+
+func TestGetGridForecast(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repoMock := repositoryMock.NewMockRepository(ctrl)
+	openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+	weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+	alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+	temperature, skyCover := 18.3, 40.0
+	repoMock.EXPECT().GetLocation("denver").Return(location, true).Times(1)
+	weatherAPIMock.EXPECT().GetGridpoint(gomock.Any(), location.Lat, location.Lon).Return(
+		map[string]model.GridpointLayer{
+			"temperature": {
+				UOM: "wmoUnit:degC",
+				Values: []model.GridpointValue{
+					{ValidTime: "2024-09-23T18:00:00+00:00/PT2H", Value: &temperature},
+					{ValidTime: "2024-09-23T20:00:00+00:00/PT1H", Value: nil},
+				},
+			},
+			"skyCover": {
+				UOM:    "wmoUnit:percent",
+				Values: []model.GridpointValue{{ValidTime: "2024-09-23T18:00:00+00:00/PT1H", Value: &skyCover}},
+			},
+		}, nil).Times(1)
+
+	weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+	ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+
+	got, err := weatherAppController.GetGridForecast(ctx, []string{"denver"}, []string{"temperature", "snowfallAmount"})
+	require.NoError(t, err)
+	want := model.GridForecast{
+		Grid: []model.CityGrid{
+			{
+				Name: "denver",
+				Layers: map[string]model.GridLayer{
+					"temperature": {
+						Unit: "degC",
+						Values: []model.GridValue{
+							{Time: time.Date(2024, 9, 23, 18, 0, 0, 0, time.UTC), Value: &temperature},
+							{Time: time.Date(2024, 9, 23, 19, 0, 0, 0, time.UTC), Value: &temperature},
+							{Time: time.Date(2024, 9, 23, 20, 0, 0, 0, time.UTC), Value: nil},
+						},
+					},
+				},
+			},
+		},
+	}
+	require.Equal(t, want, got)
+}
+
 func setGetPeriodCalls(cacheMock *repositoryMock.MockRepository) {
 	cacheMock.EXPECT().GetPeriods("london", gomock.Any()).Return(
-		model.Period{
+		respository.CachedPeriod{Period: model.Period{
 			StartTime:   nowFunc(),
 			EndTime:     nowFunc().Add(2 * time.Hour),
 			Description: "gray",
-		},
+		}, FetchedAt: nowFunc()},
 		true).Times(1)
 	cacheMock.EXPECT().GetPeriods("london", gomock.Any()).Return(
-		model.Period{
+		respository.CachedPeriod{Period: model.Period{
 			StartTime:   nowFunc().Add(24 * time.Hour),
 			EndTime:     nowFunc().Add(26 * time.Hour),
 			Description: "gray",
-		},
+		}, FetchedAt: nowFunc()},
 		true).Times(1)
 	cacheMock.EXPECT().GetPeriods("london", gomock.Any()).Return(
-		model.Period{
+		respository.CachedPeriod{Period: model.Period{
 			StartTime:   nowFunc().Add(48 * time.Hour),
 			EndTime:     nowFunc().Add(50 * time.Hour),
 			Description: "gray",
-		},
+		}, FetchedAt: nowFunc()},
 		true).Times(1)
 }
+
+func TestErrorKinds(t *testing.T) {
+	tcs := []struct {
+		name       string
+		err        error
+		wantKind   Kind
+		wantDetail string
+	}{
+		{
+			name:       "when wrapped sentinel should keep its kind",
+			err:        fmt.Errorf("lookup: %w", ErrLocationNotFound),
+			wantKind:   KindNotFound,
+			wantDetail: "location not found",
+		},
+		{
+			name:       "when upstream failure should be unavailable",
+			err:        upstreamError("forecast", &upstream.Error{Service: "weather.gov", Kind: upstream.ErrUnavailable, StatusCode: http.StatusServiceUnavailable}),
+			wantKind:   KindUnavailable,
+			wantDetail: "unable to retrieve forecast",
+		},
+		{
+			name:       "when upstream deadline should be a timeout",
+			err:        upstreamError("forecast", fmt.Errorf("get: %w", context.DeadlineExceeded)),
+			wantKind:   KindTimeout,
+			wantDetail: "upstream timed out",
+		},
+		{
+			name:       "when upstream timeout should be a timeout",
+			err:        upstreamError("forecast", upstream.FromError("weather.gov", context.DeadlineExceeded)),
+			wantKind:   KindTimeout,
+			wantDetail: "weather.gov timed out",
+		},
+		{
+			name:       "when upstream out of coverage should be not found",
+			err:        upstreamError("forecast", &upstream.Error{Service: "weather.gov", Kind: upstream.ErrOutOfCoverage, StatusCode: http.StatusNotFound}),
+			wantKind:   KindNotFound,
+			wantDetail: "no forecast outside the weather.gov coverage",
+		},
+		{
+			name:       "when upstream rate limited should be rate limited",
+			err:        upstreamError("location", &upstream.Error{Service: "openstreetmap", Kind: upstream.ErrRateLimited, StatusCode: http.StatusTooManyRequests}),
+			wantKind:   KindRateLimited,
+			wantDetail: "openstreetmap rate limit reached",
+		},
+		{
+			name:       "when upstream bad payload should be unavailable",
+			err:        upstreamError("forecast", upstream.BadPayload("weather.gov", errors.New("unexpected EOF"))),
+			wantKind:   KindUnavailable,
+			wantDetail: "weather.gov sent an invalid forecast",
+		},
+		{
+			name:       "when already classified should keep its kind",
+			err:        upstreamError("forecast", ErrForecastNotFound),
+			wantKind:   KindNotFound,
+			wantDetail: "no forecast for the requested days",
+		},
+		{
+			name:       "when client canceled should be canceled",
+			err:        fmt.Errorf("forecast: %w", context.Canceled),
+			wantKind:   KindCanceled,
+			wantDetail: "request canceled",
+		},
+		{
+			name:       "when upstream call canceled should be canceled",
+			err:        upstreamError("forecast", upstream.FromError("weather.gov", context.Canceled)),
+			wantKind:   KindCanceled,
+			wantDetail: "request canceled",
+		},
+		{
+			name:       "when unknown error should be internal",
+			err:        errors.New("boom"),
+			wantKind:   KindInternal,
+			wantDetail: "unexpected failure",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.wantKind, KindOf(tc.err))
+			require.Equal(t, tc.wantDetail, DetailOf(tc.err))
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/internal/clients/upstream"
+)
+
+// Kind classifies the controller errors, handlers map each kind into a status code.
+type Kind int
+
+const (
+	// KindInternal is an unexpected failure.
+	KindInternal Kind = iota
+	// KindNotFound is a location, forecast or station that doesn't exist.
+	KindNotFound
+	// KindUnavailable is an upstream failure, e.g. weather.gov is down.
+	KindUnavailable
+	// KindTimeout is an upstream call that took too long.
+	KindTimeout
+	// KindRateLimited is an upstream call rejected by its rate limits.
+	KindRateLimited
+	// KindCanceled is a request abandoned by its client, e.g. a disconnect.
+	KindCanceled
+)
+
+// Error is a controller error, Detail is safe to be sent to clients while Err
+// keeps the cause for the logs.
+type Error struct {
+	Kind   Kind
+	Detail string
+	// RetryAfter is how long the upstream asked to wait, zero when unknown.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// errors returned by the controller and its gateways, match them with errors.Is.
+var (
+	ErrLocationNotFound = &Error{Kind: KindNotFound, Detail: "location not found"}
+	ErrForecastNotFound = &Error{Kind: KindNotFound, Detail: "no forecast for the requested days"}
+	ErrStationNotFound  = &Error{Kind: KindNotFound, Detail: "no observation station nearby"}
+)
+
+// upstreamError classifies the failure to retrieve what, e.g. a forecast,
+// from an upstream. Errors already classified are kept.
+func upstreamError(what string, err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	e = &Error{Kind: KindUnavailable, Detail: "unable to retrieve " + what, Err: err}
+	service := "upstream"
+	var upstreamErr *upstream.Error
+	if errors.As(err, &upstreamErr) {
+		service = upstreamErr.Service
+		e.RetryAfter = upstreamErr.RetryAfter
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		e.Kind, e.Detail = KindCanceled, "request canceled"
+	case errors.Is(err, upstream.ErrOutOfCoverage):
+		e.Kind, e.Detail = KindNotFound, "no "+what+" outside the "+service+" coverage"
+	case errors.Is(err, upstream.ErrNotFound):
+		e.Kind, e.Detail = KindNotFound, what+" not found"
+	case errors.Is(err, upstream.ErrRateLimited):
+		e.Kind, e.Detail = KindRateLimited, service+" rate limit reached"
+	case errors.Is(err, upstream.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		e.Kind, e.Detail = KindTimeout, service+" timed out"
+	case errors.Is(err, upstream.ErrBadPayload):
+		e.Detail = service + " sent an invalid " + what
+	}
+	return e
+}
+
+// KindOf returns the kind of err, errors not returned by the controller are
+// internal.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return KindTimeout
+	case errors.Is(err, context.Canceled):
+		return KindCanceled
+	}
+	return KindInternal
+}
+
+// DetailOf returns the client safe detail of err.
+func DetailOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Detail
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timed out"
+	case errors.Is(err, context.Canceled):
+		return "request canceled"
+	}
+	return "unexpected failure"
+}
+
+// RetryAfterOf returns how long the upstream asked to wait, zero when unknown.
+func RetryAfterOf(err error) time.Duration {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.RetryAfter
+	}
+	return 0
+}
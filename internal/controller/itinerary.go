@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+)
+
+// Leg is a stop of an itinerary, its location is forecast from From to To,
+// both days included.
+type Leg struct {
+	Location model.LocationQuery
+	From     time.Time
+	To       time.Time
+}
+
+// GetItineraryForecast forecasts the location of each leg on its days only.
+// The legs are forecast concurrently and share the cache of GetForecast, a
+// leg that can't be forecast keeps its days on the timeline with the error.
+// When no leg could be forecast the error of the first one is returned.
+func (c *Controller) GetItineraryForecast(ctx context.Context, legs []Leg, opts ForecastOptions) (model.Itinerary, error) {
+	jobs := make([]forecastJob, 0, len(legs))
+	for _, leg := range legs {
+		jobs = append(jobs, forecastJob{query: leg.Location, days: windowDays(leg.From, leg.To)})
+	}
+	results := make([]ForecastResult, len(jobs))
+	errs := make([]error, len(jobs))
+	served := 0
+	for r := range c.streamJobs(ctx, jobs, opts) {
+		results[r.Index], errs[r.Index] = r, r.Err
+		if r.Err == nil {
+			served++
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return model.Itinerary{}, err
+	}
+	if served == 0 {
+		if err := firstError(errs); err != nil {
+			return model.Itinerary{}, err
+		}
+	}
+
+	var itinerary model.Itinerary
+	for i, job := range jobs {
+		r := results[i]
+		for _, day := range job.days {
+			stop := model.ItineraryStop{Date: day.Format("2006-01-02"), Leg: i, Name: job.query.DisplayName(), Stale: r.Forecast.Stale}
+			if r.Err != nil {
+				stop.Error = DetailOf(r.Err)
+			}
+			for _, d := range r.Forecast.Detail {
+				if matchDate(d.StartTime, day) {
+					stop.Detail = &d
+					break
+				}
+			}
+			itinerary.Timeline = append(itinerary.Timeline, stop)
+		}
+	}
+	sort.SliceStable(itinerary.Timeline, func(i, j int) bool {
+		return itinerary.Timeline[i].Date < itinerary.Timeline[j].Date
+	})
+	return itinerary, nil
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	alertsAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/alerts"
+	openStreetMapAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/openstreetmap"
+	weatherAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/weather"
+	repositoryMock "github.com/dibrito/ennismore-weather-app/gen/mock/repository/memory"
+	respository "github.com/dibrito/ennismore-weather-app/internal/repository"
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestGetItineraryForecast(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+	day := func(offset int) time.Time {
+		return time.Date(2024, 9, 23+offset, 0, 0, 0, 0, time.UTC)
+	}
+	period := func(city string, offset int) model.Period {
+		start := day(offset).Add(6 * time.Hour)
+		return model.Period{StartTime: start, EndTime: start.Add(12 * time.Hour), Description: city}
+	}
+	detail := func(city string, offset int) *model.Detail {
+		p := period(city, offset)
+		return &model.Detail{StartTime: p.StartTime, EndTime: p.EndTime, Description: city}
+	}
+
+	ctrl := gomock.NewController(t)
+	repoMock := repositoryMock.NewMockRepository(ctrl)
+	openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+	weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+	alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+	repoMock.EXPECT().GetLocation("atlantis").Return(model.Location{}, false).Times(1)
+	openStreetMapAPIMock.EXPECT().GetLocation(gomock.Any(), "atlantis", "").Return(nil, nil).Times(1)
+	repoMock.EXPECT().GetLocation(gomock.Any()).Return(location, true).Times(2)
+	// chicago on monday and tuesday, denver on tuesday and wednesday, the
+	// periods of other days are never read
+	for city, offsets := range map[string][]int{"chicago": {0, 1}, "denver": {1}} {
+		for _, offset := range offsets {
+			repoMock.EXPECT().GetPeriods(city, day(offset).Format("2006-01-02")).Return(
+				respository.CachedPeriod{Period: period(city, offset), FetchedAt: fakeTime}, true).Times(1)
+		}
+	}
+	// wednesday isn't cached, the denver forecast is fetched
+	repoMock.EXPECT().GetPeriods("denver", "2024-09-25").Return(respository.CachedPeriod{}, false).Times(1)
+	weatherAPIMock.EXPECT().GetForecast(gomock.Any(), location.Lat, location.Lon).Return(
+		[]model.Period{period("denver", 1), period("denver", 2), period("denver", 3)}, nil).Times(1)
+	repoMock.EXPECT().PutPeriods("denver", gomock.Any(), gomock.Any()).Times(3)
+
+	weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+	ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+
+	got, err := weatherAppController.GetItineraryForecast(ctx, []Leg{
+		{Location: model.LocationQuery{Name: "denver"}, From: day(1), To: day(2)},
+		{Location: model.LocationQuery{Name: "chicago"}, From: day(0), To: day(1)},
+		{Location: model.LocationQuery{Name: "atlantis"}, From: day(3), To: day(3)},
+	}, ForecastOptions{})
+	require.NoError(t, err)
+	require.Equal(t, model.Itinerary{Timeline: []model.ItineraryStop{
+		{Date: "2024-09-23", Leg: 1, Name: "chicago", Detail: detail("chicago", 0)},
+		{Date: "2024-09-24", Leg: 0, Name: "denver", Detail: detail("denver", 1)},
+		{Date: "2024-09-24", Leg: 1, Name: "chicago", Detail: detail("chicago", 1)},
+		{Date: "2024-09-25", Leg: 0, Name: "denver", Detail: detail("denver", 2)},
+		{Date: "2024-09-26", Leg: 2, Name: "atlantis", Error: "location not found"},
+	}}, got)
+}
+
+func TestGetItineraryForecastWithoutLegs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repoMock := repositoryMock.NewMockRepository(ctrl)
+	openStreetMapAPIMock := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+	weatherAPIMock := weatherAPIMock.NewMockWeatherGateway(ctrl)
+	alertsAPIMock := alertsAPIMock.NewMockAlertsGateway(ctrl)
+
+	repoMock.EXPECT().GetLocation("atlantis").Return(model.Location{}, false).Times(1)
+	openStreetMapAPIMock.EXPECT().GetLocation(gomock.Any(), "atlantis", "").Return(nil, nil).Times(1)
+
+	weatherAppController := New(openStreetMapAPIMock, weatherAPIMock, alertsAPIMock, repoMock)
+	ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+
+	today := time.Now().UTC()
+	_, err := weatherAppController.GetItineraryForecast(ctx, []Leg{
+		{Location: model.LocationQuery{Name: "atlantis"}, From: today, To: today},
+	}, ForecastOptions{})
+	require.ErrorIs(t, err, ErrLocationNotFound)
+}
@@ -0,0 +1,29 @@
+// Package docs serves the OpenAPI document of the weather-app API and a
+// documentation page that renders it.
+package docs
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// Spec is the OpenAPI 3 document of the API.
+//
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed index.html
+var page []byte
+
+// OpenAPI handles GET /openapi.json requests.
+func OpenAPI(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(Spec)
+}
+
+// UI handles GET /docs requests, the page is bundled and renders
+// /openapi.json without any external asset.
+func UI(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(page)
+}
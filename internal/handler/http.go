@@ -1,16 +1,32 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"net/url"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/dibrito/ennismore-weather-app/config"
+	"github.com/dibrito/ennismore-weather-app/internal/auth"
 	"github.com/dibrito/ennismore-weather-app/internal/controller"
+	"github.com/dibrito/ennismore-weather-app/internal/docs"
+	v2 "github.com/dibrito/ennismore-weather-app/pkg/api/v2"
+	"github.com/dibrito/ennismore-weather-app/pkg/format"
+	"github.com/dibrito/ennismore-weather-app/pkg/geo"
 	"github.com/dibrito/ennismore-weather-app/pkg/logging"
 	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/dibrito/ennismore-weather-app/pkg/problem"
+	"github.com/dibrito/ennismore-weather-app/pkg/units"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/cors"
@@ -20,54 +36,791 @@ import (
 var contentTypeKey = "Content-type"
 var contentTypeValue = "application/json"
 
+// streaming media types accepted on /weather.
+const (
+	ndjsonContentType = "application/x-ndjson"
+	sseContentType    = "text/event-stream"
+)
+
 // Handler defines weather-app HTTP handler.
 type Handler struct {
-	ctrl ServiceController
+	ctrl    ServiceController
+	cfg     config.APIConfig
+	limiter *rateLimiter
+	// formats holds the non JSON forecast encoders, see format.Registry.
+	formats *format.Registry
 }
 
 type ServiceController interface {
-	GetCache() model.CacheResponse
-	GetForecast(ctx context.Context, cities []string) (model.WeatherForecast, error)
+	GetForecast(ctx context.Context, cities []string, opts controller.ForecastOptions) (model.WeatherForecast, error)
+	GetBatchForecast(ctx context.Context, locations []model.LocationQuery, opts controller.ForecastOptions) (model.WeatherForecast, error)
+	GetAreaForecast(ctx context.Context, box geo.BBox, limit, maxFetches int, opts controller.ForecastOptions) (controller.AreaForecast, error)
+	GetItineraryForecast(ctx context.Context, legs []controller.Leg, opts controller.ForecastOptions) (model.Itinerary, error)
+	CompareForecast(ctx context.Context, cities []string, metric string, date time.Time, opts controller.ForecastOptions) (model.Comparison, error)
+	GetActivityForecast(ctx context.Context, cities []string, activity string, profile config.ActivityProfile, opts controller.ForecastOptions) (model.ActivityForecast, error)
+	GetNearbyForecast(ctx context.Context, p geo.Point, radius float64, limit, maxFetches int, opts controller.ForecastOptions) (controller.AreaForecast, error)
+	StreamForecast(ctx context.Context, locations []model.LocationQuery, opts controller.ForecastOptions) <-chan controller.ForecastResult
+	GetAlerts(ctx context.Context, cities []string) (model.WeatherAlerts, error)
+	GetCurrentConditions(ctx context.Context, cities []string, system units.System) (model.CurrentConditions, error)
+	GetGridForecast(ctx context.Context, cities []string, layers []string) (model.GridForecast, error)
 }
 
-// New creates a new movie metadata HTTP handler.
-func New(ctrl ServiceController) *Handler {
-	return &Handler{ctrl}
+// defaultGridLayers defines the gridpoint layers returned when none are requested.
+var defaultGridLayers = []string{"temperature"}
+
+// New creates a new weather-app HTTP handler.
+func New(ctrl ServiceController, cfg config.APIConfig) *Handler {
+	return &Handler{ctrl, cfg, newRateLimiter(cfg), format.Default()}
 }
 
 // GetForecast handles GET /weather requests.
 func (h *Handler) GetForecast(w http.ResponseWriter, req *http.Request) {
+	encoder, ok := h.negotiateFormat(w, req)
+	if !ok {
+		return
+	}
+	h.getForecast(w, req, encoder)
+}
+
+// GetForecastCalendar handles GET /weather.ics requests, the forecast is
+// always sent as an iCalendar feed so calendar apps can subscribe to the URL.
+func (h *Handler) GetForecastCalendar(w http.ResponseWriter, req *http.Request) {
+	h.getForecast(w, req, format.Calendar{})
+}
+
+// getForecast writes the forecast of the requested cities with the encoder, a
+// nil encoder means JSON or the requested streaming media type.
+func (h *Handler) getForecast(w http.ResponseWriter, req *http.Request, encoder format.Encoder) {
+	logger := logging.GetLoggerFromContext(req.Context())
+	logger = logger.With(zap.String("URI", req.RequestURI))
+
+	ctx := req.Context()
+	params, ok := requireCities(w, req, logger)
+	if !ok {
+		return
+	}
+	if !auth.CheckMaxCities(w, req, len(params)) {
+		return
+	}
+	logger = logger.With(zap.Strings("cities", params))
+	opts, err := parseForecastOptions(strings.Split(req.URL.Query().Get("include"), ","))
+	if err != nil {
+		invalidParam(w, req, "include", err)
+		return
+	}
+	if contentType := streamingContentType(req); contentType != "" && encoder == nil {
+		locations := make([]model.LocationQuery, 0, len(params))
+		for _, city := range params {
+			locations = append(locations, model.LocationQuery{Name: city})
+		}
+		h.streamForecast(w, req, locations, opts, contentType)
+		return
+	}
+	m, err := h.ctrl.GetForecast(ctx, params, opts)
+	if err != nil {
+		writeError(w, req, logger, "unable retrieve forecast", err)
+		return
+	}
+	setCacheHeaders(w, m)
+	writeForecast(w, req, logger, encoder, m)
+}
+
+// GetAreaForecast handles GET /weather/area requests, it forecasts the known
+// locations within the bbox. X-Total-Count reports how many are within the
+// bbox and X-Skipped-Count how many were left out by the upstream fetches cap.
+func (h *Handler) GetAreaForecast(w http.ResponseWriter, req *http.Request) {
+	logger := logging.GetLoggerFromContext(req.Context())
+	logger = logger.With(zap.String("URI", req.RequestURI))
+
+	query := req.URL.Query()
+	box, err := geo.ParseBBox(query.Get("bbox"))
+	if err != nil {
+		invalidParam(w, req, "bbox", err)
+		return
+	}
+	limit, ok := h.parseAreaLimit(w, req)
+	if !ok {
+		return
+	}
+	opts, err := parseForecastOptions(strings.Split(query.Get("include"), ","))
+	if err != nil {
+		invalidParam(w, req, "include", err)
+		return
+	}
+	encoder, ok := h.negotiateFormat(w, req)
+	if !ok {
+		return
+	}
+
+	m, err := h.ctrl.GetAreaForecast(req.Context(), box, limit, h.cfg.Area.MaxFetches, opts)
+	if err != nil {
+		writeError(w, req, logger, "unable retrieve area forecast", err)
+		return
+	}
+	writeAreaForecast(w, req, logger, encoder, m)
+}
+
+// GetNearbyForecast handles GET /v2/weather/nearby requests, it forecasts the
+// known locations within radius of lat and lon, the closest first. The radius
+// and the distances are in km, or miles when units=imperial.
+func (h *Handler) GetNearbyForecast(w http.ResponseWriter, req *http.Request) {
+	logger := logging.GetLoggerFromContext(req.Context())
+	logger = logger.With(zap.String("URI", req.RequestURI))
+
+	query := req.URL.Query()
+	var coordinates [2]float64
+	for i, c := range []struct {
+		field string
+		max   float64
+	}{{"lat", 90}, {"lon", 180}} {
+		v, err := strconv.ParseFloat(query.Get(c.field), 64)
+		// negated so NaN is rejected too
+		if err != nil || !(math.Abs(v) <= c.max) {
+			invalidParam(w, req, c.field, fmt.Errorf("%s must be a number between -%v and %v", c.field, c.max, c.max))
+			return
+		}
+		coordinates[i] = v
+	}
+	p := geo.Point{Lat: coordinates[0], Lon: coordinates[1]}
+	system, err := units.Parse(query.Get("units"))
+	if err != nil {
+		invalidParam(w, req, "units", err)
+		return
+	}
+	radius, err := strconv.ParseFloat(query.Get("radius"), 64)
+	if err != nil || !(radius > 0) || math.IsInf(radius, 0) {
+		invalidParam(w, req, "radius", errors.New("radius must be a positive number"))
+		return
+	}
+	// the radius is given in the distance unit of the response
+	km := units.Kilometers(radius, system)
+	if h.cfg.Area.MaxRadius > 0 && km > h.cfg.Area.MaxRadius {
+		maxRadius, unit := units.Convert(h.cfg.Area.MaxRadius*1000, "m", system)
+		invalidParam(w, req, "radius", fmt.Errorf("radius must not exceed %s %s",
+			strconv.FormatFloat(math.Round(maxRadius*10)/10, 'f', -1, 64), unit))
+		return
+	}
+	limit, ok := h.parseAreaLimit(w, req)
+	if !ok {
+		return
+	}
+	opts, err := parseForecastOptions(strings.Split(query.Get("include"), ","))
+	if err != nil {
+		invalidParam(w, req, "include", err)
+		return
+	}
+	opts.Units = system
+	encoder, ok := h.negotiateFormat(w, req)
+	if !ok {
+		return
+	}
+
+	m, err := h.ctrl.GetNearbyForecast(req.Context(), p, km, limit, h.cfg.Area.MaxFetches, opts)
+	if err != nil {
+		writeError(w, req, logger, "unable retrieve nearby forecast", err)
+		return
+	}
+	writeAreaForecast(w, req, logger, encoder, m)
+}
+
+// CompareForecast handles GET /v2/weather/compare requests, it ranks the
+// cities on the metric with the winners of each forecast day, or only of date
+// when it is set.
+func (h *Handler) CompareForecast(w http.ResponseWriter, req *http.Request) {
+	logger := logging.GetLoggerFromContext(req.Context())
+	logger = logger.With(zap.String("URI", req.RequestURI))
+
+	cities, ok := requireCities(w, req, logger)
+	if !ok {
+		return
+	}
+	if !auth.CheckMaxCities(w, req, len(cities)) {
+		return
+	}
+	query := req.URL.Query()
+	metric := query.Get("metric")
+	if !slices.Contains(controller.Metrics, metric) {
+		invalidParam(w, req, "metric", fmt.Errorf("supported metrics are %s", strings.Join(controller.Metrics, ", ")))
+		return
+	}
+	var date time.Time
+	if v := query.Get("date"); v != "" {
+		var err error
+		if date, err = time.Parse(time.DateOnly, v); err != nil {
+			invalidParam(w, req, "date", errors.New("date must be YYYY-MM-DD"))
+			return
+		}
+		if err := checkForecastDate(date); err != nil {
+			invalidParam(w, req, "date", err)
+			return
+		}
+	}
+	system, err := units.Parse(query.Get("units"))
+	if err != nil {
+		invalidParam(w, req, "units", err)
+		return
+	}
+
+	m, err := h.ctrl.CompareForecast(req.Context(), cities, metric, date, controller.ForecastOptions{Units: system})
+	if err != nil {
+		writeError(w, req, logger, "unable to compare forecast", err)
+		return
+	}
+	writeJSON(w, req, logger, v2.NewComparison(m))
+}
+
+// GetActivityForecast handles GET /v2/weather/activity requests, it scores
+// each forecast day of the cities for the activity following its configured
+// profile.
+func (h *Handler) GetActivityForecast(w http.ResponseWriter, req *http.Request) {
 	logger := logging.GetLoggerFromContext(req.Context())
 	logger = logger.With(zap.String("URI", req.RequestURI))
 
+	cities, ok := requireCities(w, req, logger)
+	if !ok {
+		return
+	}
+	if !auth.CheckMaxCities(w, req, len(cities)) {
+		return
+	}
+	activity := req.URL.Query().Get("activity")
+	profile, ok := h.cfg.Activities[activity]
+	if !ok {
+		activities := make([]string, 0, len(h.cfg.Activities))
+		for name := range h.cfg.Activities {
+			activities = append(activities, name)
+		}
+		sort.Strings(activities)
+		invalidParam(w, req, "activity", fmt.Errorf("supported activities are %s", strings.Join(activities, ", ")))
+		return
+	}
+
+	m, err := h.ctrl.GetActivityForecast(req.Context(), cities, activity, profile, controller.ForecastOptions{})
+	if err != nil {
+		writeError(w, req, logger, "unable to score activity forecast", err)
+		return
+	}
+	writeJSON(w, req, logger, v2.NewActivityForecast(m))
+}
+
+// checkForecastDate checks the date is within the forecast horizon.
+func checkForecastDate(date time.Time) error {
+	first, last := controller.ForecastHorizon()
+	if day := date.Format(time.DateOnly); day < first.Format(time.DateOnly) || day > last.Format(time.DateOnly) {
+		return fmt.Errorf("date must be between %s and %s", first.Format(time.DateOnly), last.Format(time.DateOnly))
+	}
+	return nil
+}
+
+// parseAreaLimit reads the limit query param of the area and nearby
+// forecasts, it defaults to the configured max. It replies with a problem
+// when it is invalid or exceeds the cities allowed to the client.
+func (h *Handler) parseAreaLimit(w http.ResponseWriter, req *http.Request) (int, bool) {
+	limit := h.cfg.Area.MaxLimit
+	if v := req.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		switch {
+		case err != nil || n < 1:
+			invalidParam(w, req, "limit", errors.New("limit must be a positive number"))
+			return 0, false
+		case h.cfg.Area.MaxLimit > 0 && n > h.cfg.Area.MaxLimit:
+			invalidParam(w, req, "limit", fmt.Errorf("limit must not exceed %d", h.cfg.Area.MaxLimit))
+			return 0, false
+		}
+		limit = n
+	}
+	return limit, auth.CheckMaxCities(w, req, limit)
+}
+
+// writeAreaForecast writes the forecast of an area or nearby search,
+// X-Total-Count reports how many locations matched the search and
+// X-Skipped-Count how many were left out by the upstream fetches cap.
+func writeAreaForecast(w http.ResponseWriter, req *http.Request, logger *zap.Logger, encoder format.Encoder, m controller.AreaForecast) {
+	if len(m.Skipped) > 0 {
+		logger.Info("locations skipped by the fetches cap", zap.Strings("skipped", m.Skipped))
+	}
+	setCacheHeaders(w, m.WeatherForecast)
+	w.Header().Set("X-Total-Count", strconv.Itoa(m.Total))
+	w.Header().Set("X-Skipped-Count", strconv.Itoa(len(m.Skipped)))
+	writeForecast(w, req, logger, encoder, m.WeatherForecast)
+}
+
+// requireCities reads the city query param, it replies with a problem when
+// it is missing or invalid.
+func requireCities(w http.ResponseWriter, req *http.Request, logger *zap.Logger) ([]string, bool) {
 	citiesQuery := req.URL.Query().Get("city")
 	if citiesQuery == "" {
-		http.Error(w, "Please provide a list of cities", http.StatusBadRequest)
+		invalidParam(w, req, "city", errors.New("please provide a list of cities"))
+		return nil, false
+	}
+	params, err := parseCities(citiesQuery)
+	if err != nil {
+		logger.Info("unable to parse cites", zap.String("cities_query", citiesQuery), zap.Error(err))
+		invalidParam(w, req, "city", err)
+		return nil, false
+	}
+	return params, true
+}
+
+// invalidParam replies BAD REQUEST with the invalid field.
+func invalidParam(w http.ResponseWriter, req *http.Request, field string, err error) {
+	problem.Error(w, req, http.StatusBadRequest, "invalid "+field,
+		model.FieldError{Field: field, Detail: err.Error()})
+}
+
+// statusClientClosedRequest is the non standard status, borrowed from nginx,
+// of the requests abandoned by their client.
+const statusClientClosedRequest = 499
+
+// writeError maps a controller error into its problem, unexpected failures
+// are logged and their cause is never sent to the client.
+func writeError(w http.ResponseWriter, req *http.Request, logger *zap.Logger, msg string, err error) {
+	status := http.StatusInternalServerError
+	switch controller.KindOf(err) {
+	case controller.KindCanceled:
+		// the client is gone, the status is only kept for the access logs
+		logger.Info(msg, zap.Error(err))
+		w.WriteHeader(statusClientClosedRequest)
 		return
+	case controller.KindNotFound:
+		status = http.StatusNotFound
+	case controller.KindUnavailable:
+		status = http.StatusBadGateway
+	case controller.KindTimeout:
+		status = http.StatusGatewayTimeout
+	case controller.KindRateLimited:
+		status = http.StatusServiceUnavailable
+		if d := controller.RetryAfterOf(err); d > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+		}
+	}
+	if status == http.StatusInternalServerError {
+		logger.Error(msg, zap.Error(err))
+	} else {
+		logger.Warn(msg, zap.Error(err))
 	}
-	logger = logger.With(zap.String("cities_query", citiesQuery))
+	problem.Error(w, req, status, controller.DetailOf(err))
+}
 
-	ctx := req.Context()
-	params, err := parseCities(citiesQuery)
+// writeJSON encodes the whole response before writing it, so an encoding
+// failure can still be reported as an error.
+func writeJSON(w http.ResponseWriter, req *http.Request, logger *zap.Logger, v any) {
+	bs, err := json.Marshal(v)
 	if err != nil {
-		logger.Error("unable to parse cites", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
+		logger.Error("unable to parse response", zap.Error(err))
+		problem.Error(w, req, http.StatusInternalServerError, "unable to encode the response")
 		return
 	}
-	m, err := h.ctrl.GetForecast(ctx, params)
-	if err != nil && errors.Is(err, controller.ErrNotFound) {
-		w.WriteHeader(http.StatusNotFound)
+	w.Header().Set(contentTypeKey, contentTypeValue)
+	if _, err := w.Write(append(bs, '\n')); err != nil {
+		logger.Info("unable to write response", zap.Error(err))
+	}
+}
+
+// negotiateFormat picks the forecast encoder from the format query param or
+// the Accept header, a nil encoder means JSON. It replies with a problem when
+// the format is unknown.
+func (h *Handler) negotiateFormat(w http.ResponseWriter, req *http.Request) (format.Encoder, bool) {
+	w.Header().Add("Vary", "Accept")
+	encoder, err := h.formats.Negotiate(req.URL.Query().Get("format"), req.Header.Get("Accept"))
+	if err != nil {
+		invalidParam(w, req, "format", err)
+		return nil, false
+	}
+	return encoder, true
+}
+
+// writeForecast writes the forecast with the negotiated encoder or as the JSON
+// of the API version when there is none. The forecast is encoded before
+// writing it, like writeJSON.
+func writeForecast(w http.ResponseWriter, req *http.Request, logger *zap.Logger, encoder format.Encoder, m model.WeatherForecast) {
+	if encoder == nil {
+		writeJSON(w, req, logger, versionFromContext(req.Context()).forecast(m))
 		return
-	} else if err != nil {
-		logger.Error("unable retrieve forecast", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+	}
+	var buf bytes.Buffer
+	if err := encoder.Encode(&buf, m); err != nil {
+		logger.Error("unable to encode forecast", zap.Error(err))
+		problem.Error(w, req, http.StatusInternalServerError, "unable to encode the response")
 		return
 	}
-	if err := json.NewEncoder(w).Encode(m); err != nil {
-		logger.Error("unable to parse response", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+	w.Header().Set(contentTypeKey, encoder.ContentType())
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		logger.Info("unable to write response", zap.Error(err))
+	}
+}
+
+// setCacheHeaders reports where the forecasts came from: X-Cache is STALE when
+// any forecast is stale, HIT when all of them were cached and MISS otherwise.
+// Age is the age in seconds of the oldest cached forecast.
+func setCacheHeaders(w http.ResponseWriter, m model.WeatherForecast) {
+	if len(m.Forecast) == 0 {
 		return
 	}
+	status := controller.CacheHit
+	var age int64
+	for _, f := range m.Forecast {
+		switch {
+		case f.Cache == controller.CacheStale:
+			status = controller.CacheStale
+		case f.Cache == controller.CacheMiss && status == controller.CacheHit:
+			status = controller.CacheMiss
+		}
+		age = max(age, f.Age)
+	}
+	w.Header().Set("X-Cache", status)
+	if status != controller.CacheMiss || age > 0 {
+		w.Header().Set("Age", strconv.FormatInt(age, 10))
+	}
+}
+
+// PostForecast handles POST /weather requests, the locations are sent as a
+// JSON body so names such as "Washington, D.C." don't need to be encoded.
+func (h *Handler) PostForecast(w http.ResponseWriter, req *http.Request) {
+	logger := logging.GetLoggerFromContext(req.Context())
+	logger = logger.With(zap.String("URI", req.RequestURI))
+
+	var body model.ForecastRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, req.Body, h.cfg.MaxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			problem.Error(w, req, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+			return
+		}
+		problem.Error(w, req, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if errs := validateForecastRequest(body, h.cfg.MaxBatchSize); len(errs) > 0 {
+		problem.Error(w, req, http.StatusBadRequest, "invalid forecast request", errs...)
+		return
+	}
+	if !auth.CheckMaxCities(w, req, len(body.Locations)) {
+		return
+	}
+
+	opts, err := parseForecastOptions(body.Options.Include)
+	if err != nil {
+		invalidParam(w, req, "options.include", err)
+		return
+	}
+	opts.Days = body.Options.Days
+	if body.Options.Units != "" {
+		if opts.Units, err = units.Parse(body.Options.Units); err != nil {
+			invalidParam(w, req, "options.units", err)
+			return
+		}
+	}
+
+	encoder, ok := h.negotiateFormat(w, req)
+	if !ok {
+		return
+	}
+	if contentType := streamingContentType(req); contentType != "" && encoder == nil {
+		h.streamForecast(w, req, body.Locations, opts, contentType)
+		return
+	}
+	m, err := h.ctrl.GetBatchForecast(req.Context(), body.Locations, opts)
+	if err != nil {
+		writeError(w, req, logger, "unable retrieve forecast", err)
+		return
+	}
+	setCacheHeaders(w, m)
+	writeForecast(w, req, logger, encoder, m)
+}
+
+// PostItinerary handles POST /v2/weather/itinerary requests, each leg
+// location is forecast on its days only and the stops are returned as a
+// timeline sorted by date.
+func (h *Handler) PostItinerary(w http.ResponseWriter, req *http.Request) {
+	logger := logging.GetLoggerFromContext(req.Context())
+	logger = logger.With(zap.String("URI", req.RequestURI))
+
+	var body model.ItineraryRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, req.Body, h.cfg.MaxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			problem.Error(w, req, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+			return
+		}
+		problem.Error(w, req, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	legs, errs := validateItineraryRequest(body, h.cfg.MaxBatchSize)
+	if len(errs) > 0 {
+		problem.Error(w, req, http.StatusBadRequest, "invalid itinerary request", errs...)
+		return
+	}
+	if !auth.CheckMaxCities(w, req, len(legs)) {
+		return
+	}
+	var opts controller.ForecastOptions
+	if body.Units != "" {
+		var err error
+		if opts.Units, err = units.Parse(body.Units); err != nil {
+			invalidParam(w, req, "units", err)
+			return
+		}
+	}
+
+	m, err := h.ctrl.GetItineraryForecast(req.Context(), legs, opts)
+	if err != nil {
+		writeError(w, req, logger, "unable retrieve itinerary forecast", err)
+		return
+	}
+	writeJSON(w, req, logger, v2.NewItinerary(m))
+}
+
+// streamingContentType returns the streaming media type requested on the
+// Accept header, empty when the client expects a single JSON document.
+func streamingContentType(req *http.Request) string {
+	for _, part := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		switch mediaType = strings.TrimSpace(mediaType); mediaType {
+		case ndjsonContentType, sseContentType:
+			return mediaType
+		}
+	}
+	return ""
+}
+
+// streamForecast writes each location forecast, or its error, as soon as it is
+// ready either as NDJSON lines or as Server-Sent Events. The forecast work is
+// cancelled when the client disconnects or a write fails.
+func (h *Handler) streamForecast(w http.ResponseWriter, req *http.Request, locations []model.LocationQuery,
+	opts controller.ForecastOptions, contentType string) {
+	logger := logging.GetLoggerFromContext(req.Context())
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	version := versionFromContext(ctx)
+	rc := http.NewResponseController(w)
+	w.Header().Set(contentTypeKey, contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for r := range h.ctrl.StreamForecast(ctx, locations, opts) {
+		event, payload := "forecast", version.stream(r.Forecast)
+		if r.Err != nil {
+			event, payload = "error", model.StreamError{Name: r.Forecast.Name, Error: streamErrorMessage(r.Err)}
+		}
+		if err := writeStreamEvent(w, contentType, event, payload); err != nil {
+			logger.Info("unable to write stream event, stopping stream", zap.Error(err))
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			logger.Info("unable to flush stream event, stopping stream", zap.Error(err))
+			return
+		}
+	}
+	if ctx.Err() != nil {
+		logger.Info("client disconnected, stream stopped")
+		return
+	}
+
+	// EventSource clients reconnect when the stream closes, done lets them stop
+	if contentType == sseContentType {
+		if err := writeStreamEvent(w, contentType, "done", struct{}{}); err == nil {
+			_ = rc.Flush()
+		}
+	}
+}
+
+// writeStreamEvent writes a single stream event, NDJSON has no event names so
+// errors are told apart by the error field.
+func writeStreamEvent(w io.Writer, contentType, event string, payload any) error {
+	bs, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if contentType == sseContentType {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, bs)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", bs)
+	return err
+}
+
+// streamErrorMessage maps a location failure into a message safe to be sent
+// to the client.
+func streamErrorMessage(err error) string {
+	if controller.KindOf(err) == controller.KindInternal {
+		return "unable to retrieve forecast"
+	}
+	return controller.DetailOf(err)
+}
+
+// validateForecastRequest checks the batch size, the requested locations and
+// days, it returns every invalid field.
+func validateForecastRequest(body model.ForecastRequest, maxBatchSize int) []model.FieldError {
+	var errs []model.FieldError
+	if len(body.Locations) == 0 {
+		errs = append(errs, model.FieldError{Field: "locations", Detail: "please provide a list of locations"})
+	}
+	if len(body.Locations) > maxBatchSize {
+		errs = append(errs, model.FieldError{Field: "locations",
+			Detail: fmt.Sprintf("too many locations: %d, max batch size is %d", len(body.Locations), maxBatchSize)})
+	}
+	for i, l := range body.Locations {
+		errs = append(errs, validateLocation(fmt.Sprintf("locations[%d]", i), l)...)
+	}
+	if body.Options.Days < 0 || body.Options.Days > controller.MaxForecastDays {
+		errs = append(errs, model.FieldError{Field: "options.days",
+			Detail: fmt.Sprintf("days must be between 1 and %d", controller.MaxForecastDays)})
+	}
+	return errs
+}
+
+// validateLocation checks a requested location, field names it on the errors.
+func validateLocation(field string, l model.LocationQuery) []model.FieldError {
+	var errs []model.FieldError
+	switch {
+	case l.HasCoordinates():
+		if *l.Lat < -90 || *l.Lat > 90 || *l.Lon < -180 || *l.Lon > 180 {
+			errs = append(errs, model.FieldError{Field: field, Detail: "coordinates out of range"})
+		}
+	case l.Lat != nil || l.Lon != nil:
+		errs = append(errs, model.FieldError{Field: field, Detail: "both lat and lon are required"})
+	case strings.TrimSpace(l.Name) == "":
+		errs = append(errs, model.FieldError{Field: field, Detail: "either name or coordinates are required"})
+	}
+	if l.Country != "" && len(l.Country) != 2 {
+		errs = append(errs, model.FieldError{Field: field + ".country", Detail: "country must be an ISO 3166-1 alpha-2 code"})
+	}
+	return errs
+}
+
+// validateItineraryRequest checks an itinerary body and maps its legs, every
+// leg day must be within the forecast horizon.
+func validateItineraryRequest(body model.ItineraryRequest, maxBatchSize int) ([]controller.Leg, []model.FieldError) {
+	var errs []model.FieldError
+	if len(body.Legs) == 0 {
+		errs = append(errs, model.FieldError{Field: "legs", Detail: "please provide a list of legs"})
+	}
+	if len(body.Legs) > maxBatchSize {
+		errs = append(errs, model.FieldError{Field: "legs",
+			Detail: fmt.Sprintf("too many legs: %d, max batch size is %d", len(body.Legs), maxBatchSize)})
+	}
+	legs := make([]controller.Leg, 0, len(body.Legs))
+	for i, l := range body.Legs {
+		field := fmt.Sprintf("legs[%d]", i)
+		errs = append(errs, validateLocation(field+".location", l.Location)...)
+		leg := controller.Leg{Location: l.Location}
+		valid := true
+		for _, d := range []struct {
+			name  string
+			value string
+			day   *time.Time
+		}{{"from", l.From, &leg.From}, {"to", l.To, &leg.To}} {
+			day, err := time.Parse(time.DateOnly, d.value)
+			if err != nil {
+				err = fmt.Errorf("%s must be YYYY-MM-DD", d.name)
+			} else {
+				err = checkForecastDate(day)
+			}
+			if err != nil {
+				errs = append(errs, model.FieldError{Field: field + "." + d.name, Detail: err.Error()})
+				valid = false
+			}
+			*d.day = day
+		}
+		if valid && leg.From.After(leg.To) {
+			errs = append(errs, model.FieldError{Field: field + ".to", Detail: "to must not be before from"})
+		}
+		legs = append(legs, leg)
+	}
+	return legs, errs
+}
+
+// GetAlerts handles GET /alerts requests.
+func (h *Handler) GetAlerts(w http.ResponseWriter, req *http.Request) {
+	logger := logging.GetLoggerFromContext(req.Context())
+	logger = logger.With(zap.String("URI", req.RequestURI))
+
+	params, ok := requireCities(w, req, logger)
+	if !ok {
+		return
+	}
+	if !auth.CheckMaxCities(w, req, len(params)) {
+		return
+	}
+	logger = logger.With(zap.Strings("cities", params))
+	m, err := h.ctrl.GetAlerts(req.Context(), params)
+	if err != nil {
+		writeError(w, req, logger, "unable retrieve alerts", err)
+		return
+	}
+	writeJSON(w, req, logger, versionFromContext(req.Context()).alerts(m))
+}
+
+// GetCurrentConditions handles GET /current requests.
+func (h *Handler) GetCurrentConditions(w http.ResponseWriter, req *http.Request) {
+	logger := logging.GetLoggerFromContext(req.Context())
+	logger = logger.With(zap.String("URI", req.RequestURI))
+
+	params, ok := requireCities(w, req, logger)
+	if !ok {
+		return
+	}
+	if !auth.CheckMaxCities(w, req, len(params)) {
+		return
+	}
+	logger = logger.With(zap.Strings("cities", params))
+	system, err := units.Parse(req.URL.Query().Get("units"))
+	if err != nil {
+		invalidParam(w, req, "units", err)
+		return
+	}
+	m, err := h.ctrl.GetCurrentConditions(req.Context(), params, system)
+	if err != nil {
+		writeError(w, req, logger, "unable retrieve current conditions", err)
+		return
+	}
+	writeJSON(w, req, logger, versionFromContext(req.Context()).current(m))
+}
+
+// GetGridForecast handles GET /weather/grid requests.
+func (h *Handler) GetGridForecast(w http.ResponseWriter, req *http.Request) {
+	logger := logging.GetLoggerFromContext(req.Context())
+	logger = logger.With(zap.String("URI", req.RequestURI))
+
+	params, ok := requireCities(w, req, logger)
+	if !ok {
+		return
+	}
+	if !auth.CheckMaxCities(w, req, len(params)) {
+		return
+	}
+	logger = logger.With(zap.Strings("cities", params))
+	layers := defaultGridLayers
+	if layersQuery := req.URL.Query().Get("layers"); layersQuery != "" {
+		layers = strings.Split(layersQuery, ",")
+	}
+	m, err := h.ctrl.GetGridForecast(req.Context(), params, layers)
+	if err != nil {
+		writeError(w, req, logger, "unable retrieve grid forecast", err)
+		return
+	}
+	writeJSON(w, req, logger, versionFromContext(req.Context()).grid(m))
+}
+
+// parseForecastOptions maps the include values, e.g. include=alerts, into the
+// controller forecast options.
+func parseForecastOptions(include []string) (controller.ForecastOptions, error) {
+	var opts controller.ForecastOptions
+	for _, part := range include {
+		switch strings.TrimSpace(part) {
+		case "":
+		case "alerts":
+			opts.IncludeAlerts = true
+		default:
+			return opts, fmt.Errorf("unsupported include value: %q", part)
+		}
+	}
+	return opts, nil
 }
 
 // Function to break down the city query string into a slice of strings
@@ -87,47 +840,147 @@ func parseCities(query string) ([]string, error) {
 	return decodedCities, nil
 }
 
+// Modules holds the subsystems served next to the weather routes.
+type Modules struct {
+	// Live serves the /ws WebSocket live updates.
+	Live http.Handler
+	// Rules serves the /rules webhook notification rules, it is optional.
+	Rules http.Handler
+	// Admin serves the /admin/cache API, it is optional.
+	Admin http.Handler
+	// Auth authenticates the API requests, it is optional.
+	Auth func(http.Handler) http.Handler
+}
+
 // TODO: I'm not happy about the handler calling routes!
-func (h *Handler) Routes(logger *zap.Logger) http.Handler {
+func (h *Handler) Routes(logger *zap.Logger, modules Modules) http.Handler {
 	mux := chi.NewRouter()
 
-	// specify who is allwoed to connect
-	mux.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", contentTypeKey, "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
-
 	// define HTTP routes
-	mux.Use(middleware.Heartbeat("/health"))
-	mux.With(LoggerInterceptor(logger)).Get("/weather", http.HandlerFunc(h.GetForecast))
-	mux.With(LoggerInterceptor(logger)).Get("/cache", http.HandlerFunc(h.GetCache))
+	mux.Use(middleware.Heartbeat("/health"), middleware.RequestID, requestIDHeader)
+	mux.NotFound(func(w http.ResponseWriter, req *http.Request) {
+		problem.Error(w, req, http.StatusNotFound, "no route for "+req.URL.Path)
+	})
+	mux.MethodNotAllowed(func(w http.ResponseWriter, req *http.Request) {
+		problem.Error(w, req, http.StatusMethodNotAllowed, req.Method+" is not allowed on "+req.URL.Path)
+	})
+	mux.Get("/openapi.json", docs.OpenAPI)
+	mux.Get("/docs", docs.UI)
+	// the weather routes are served under every API version, the unversioned
+	// ones are deprecated aliases of /v1
+	for _, version := range []apiVersion{apiV1, apiV2} {
+		mux.With(withVersion(version)).Route(version.prefix, func(r chi.Router) {
+			h.weatherRoutes(r, logger, modules)
+			if version.prefix == apiV2.prefix {
+				h.v2Routes(r, logger, modules)
+			}
+		})
+	}
+	mux.Group(func(r chi.Router) {
+		r.Use(deprecated(h.cfg.Deprecation))
+		h.weatherRoutes(r, logger, modules)
+	})
+	h.api(mux, "/ws", unitCost, modules).Get("/ws", modules.Live.ServeHTTP)
+	if modules.Rules != nil {
+		h.api(mux, "/rules", unitCost, modules).With(LoggerInterceptor(logger)).Mount("/rules", modules.Rules)
+	}
+	if modules.Admin != nil {
+		mux.With(LoggerInterceptor(logger)).Mount("/admin/cache", modules.Admin)
+	}
+
+	return h.cors(mux)
+}
+
+// weatherRoutes registers the versioned weather routes.
+func (h *Handler) weatherRoutes(r chi.Router, logger *zap.Logger, modules Modules) {
+	h.api(r, "/weather", cityCost, modules).With(LoggerInterceptor(logger)).Get("/weather", http.HandlerFunc(h.GetForecast))
+	h.api(r, "/weather", h.limiter.locationsCost, modules).With(LoggerInterceptor(logger)).Post("/weather", http.HandlerFunc(h.PostForecast))
+	h.api(r, "/weather", cityCost, modules).With(LoggerInterceptor(logger)).Get("/weather.ics", http.HandlerFunc(h.GetForecastCalendar))
+	h.api(r, "/weather/grid", unitCost, modules).With(LoggerInterceptor(logger)).Get("/weather/grid", http.HandlerFunc(h.GetGridForecast))
+	h.api(r, "/weather/area", unitCost, modules).With(LoggerInterceptor(logger)).Get("/weather/area", http.HandlerFunc(h.GetAreaForecast))
+	h.api(r, "/alerts", cityCost, modules).With(LoggerInterceptor(logger)).Get("/alerts", http.HandlerFunc(h.GetAlerts))
+	h.api(r, "/current", cityCost, modules).With(LoggerInterceptor(logger)).Get("/current", http.HandlerFunc(h.GetCurrentConditions))
+}
+
+// v2Routes registers the weather routes introduced by v2, their responses
+// need fields v1 doesn't have so they are neither served under /v1 nor as
+// unversioned aliases.
+func (h *Handler) v2Routes(r chi.Router, logger *zap.Logger, modules Modules) {
+	h.api(r, "/weather", cityCost, modules).With(LoggerInterceptor(logger)).Get("/weather/activity", http.HandlerFunc(h.GetActivityForecast))
+	h.api(r, "/weather", cityCost, modules).With(LoggerInterceptor(logger)).Get("/weather/compare", http.HandlerFunc(h.CompareForecast))
+	h.api(r, "/weather", h.limiter.legsCost, modules).With(LoggerInterceptor(logger)).Post("/weather/itinerary", http.HandlerFunc(h.PostItinerary))
+	h.api(r, "/weather/nearby", unitCost, modules).With(LoggerInterceptor(logger)).Get("/weather/nearby", http.HandlerFunc(h.GetNearbyForecast))
+}
 
-	return mux
+// api rate-limits the API routes by client IP, the limits are shared by every
+// version of a route and each request takes the tokens returned by cost. An API
+// key is required when auth is enabled.
+func (h *Handler) api(r chi.Router, route string, cost costFunc, modules Modules) chi.Router {
+	middlewares := chi.Middlewares{h.limiter.Limit(route, cost)}
+	if modules.Auth != nil {
+		middlewares = append(middlewares, modules.Auth)
+	}
+	return r.With(middlewares...)
+}
+
+// requestIDHeader sends the request ID back so clients can report it, it is
+// also sent on problem responses.
+func requestIDHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// exposedHeaders are the response headers browsers may read.
+var exposedHeaders = []string{"Link", "X-Cache", "Age", "Retry-After", "Deprecation", "Sunset", "X-Request-Id",
+	"X-Total-Count", "X-Skipped-Count", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"}
+
+// cors specifies who is allowed to connect, the allowed methods are the
+// configured ones that have a route or every routed method by default.
+func (h *Handler) cors(mux chi.Router) http.Handler {
+	cfg := h.cfg.CORS
+	if len(cfg.AllowedOrigins) == 0 {
+		return mux
+	}
+
+	configured := make(map[string]bool, len(cfg.AllowedMethods))
+	for _, method := range cfg.AllowedMethods {
+		configured[strings.ToUpper(method)] = true
+	}
+	routed := make(map[string]bool)
+	_ = chi.Walk(mux, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if len(configured) == 0 || configured[method] {
+			routed[method] = true
+		}
+		return nil
+	})
+	methods := make([]string, 0, len(routed))
+	for method := range routed {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	return cors.Handler(cors.Options{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   methods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   exposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	})(mux)
 }
 
 // LoggerInterceptor adds tracing info to the logger and puts the logger into request Context
 func LoggerInterceptor(logger *zap.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set(contentTypeKey, contentTypeValue)
+			logger := logger
+			if id := middleware.GetReqID(r.Context()); id != "" {
+				logger = logger.With(zap.String("request_id", id))
+			}
 			ctx := context.WithValue(r.Context(), logging.LoggetCtxKey{}, logger)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
-
-// TODO: please disegard this endpoint, this was used to test purpose only to act like get all from cache!
-func (h *Handler) GetCache(w http.ResponseWriter, req *http.Request) {
-	logger := logging.GetLoggerFromContext(req.Context())
-	cache := h.ctrl.GetCache()
-	w.Header().Set(contentTypeKey, contentTypeValue)
-	if err := json.NewEncoder(w).Encode(cache); err != nil {
-		logger.Error("unable to parse response", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-}
@@ -1,21 +1,50 @@
 package http
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/dibrito/ennismore-weather-app/config"
+	alertsAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/alerts"
+	openStreetMapAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/openstreetmap"
+	weatherAPIMock "github.com/dibrito/ennismore-weather-app/gen/mock/clients/weather"
 	controllerMock "github.com/dibrito/ennismore-weather-app/gen/mock/controller"
+	"github.com/dibrito/ennismore-weather-app/internal/clients/upstream"
+	weatherclient "github.com/dibrito/ennismore-weather-app/internal/clients/weather"
 	"github.com/dibrito/ennismore-weather-app/internal/controller"
+	respository "github.com/dibrito/ennismore-weather-app/internal/repository"
+	"github.com/dibrito/ennismore-weather-app/pkg/geo"
 	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/dibrito/ennismore-weather-app/pkg/problem"
+	"github.com/dibrito/ennismore-weather-app/pkg/units"
+	"github.com/go-chi/chi"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
 )
 
+var maxWind = 30.0
+
+var apiConfig = config.APIConfig{
+	MaxBatchSize: 2,
+	MaxBodyBytes: 1024,
+	Activities: map[string]config.ActivityProfile{
+		"running": {MaxWind: &maxWind},
+		"beach":   {},
+	},
+}
+
 var want = model.WeatherForecast{
 	Forecast: []model.Forecast{
 		{
@@ -64,7 +93,7 @@ func TestGetForecast(t *testing.T) {
 				require.Equal(t, recorder.Result().StatusCode, http.StatusInternalServerError)
 			},
 			setupMock: func(mock *controllerMock.MockServiceController) {
-				mock.EXPECT().GetForecast(gomock.Any(), []string{"london"}).Return(want, errors.New("service-error")).Times(1)
+				mock.EXPECT().GetForecast(gomock.Any(), []string{"london"}, controller.ForecastOptions{}).Return(want, errors.New("service-error")).Times(1)
 			},
 		},
 		{
@@ -74,7 +103,27 @@ func TestGetForecast(t *testing.T) {
 				require.Equal(t, recorder.Result().StatusCode, http.StatusNotFound)
 			},
 			setupMock: func(mock *controllerMock.MockServiceController) {
-				mock.EXPECT().GetForecast(gomock.Any(), []string{"london"}).Return(want, controller.ErrNotFound).Times(1)
+				mock.EXPECT().GetForecast(gomock.Any(), []string{"london"}, controller.ForecastOptions{}).Return(want, controller.ErrLocationNotFound).Times(1)
+			},
+		},
+		{
+			name:        "when unsupported include should return BAD REQUEST",
+			queryParams: "?city=london&include=radar",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, recorder.Result().StatusCode, http.StatusBadRequest)
+			},
+			setupMock: func(mock *controllerMock.MockServiceController) {
+			},
+		},
+		{
+			name:        "when include alerts should request alerts",
+			queryParams: "?city=london&include=alerts",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, recorder.Result().StatusCode, http.StatusOK)
+			},
+			setupMock: func(mock *controllerMock.MockServiceController) {
+				mock.EXPECT().GetForecast(gomock.Any(), []string{"london"},
+					controller.ForecastOptions{IncludeAlerts: true}).Return(want, nil).Times(1)
 			},
 		},
 		{
@@ -88,7 +137,49 @@ func TestGetForecast(t *testing.T) {
 				require.Equal(t, want, got)
 			},
 			setupMock: func(mock *controllerMock.MockServiceController) {
-				mock.EXPECT().GetForecast(gomock.Any(), []string{"london"}).Return(want, nil).Times(1)
+				mock.EXPECT().GetForecast(gomock.Any(), []string{"london"}, controller.ForecastOptions{}).Return(want, nil).Times(1)
+			},
+		},
+		{
+			name:        "when format csv should return a row per period",
+			queryParams: "?city=london&format=csv",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+				require.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+				require.Equal(t, "Accept", recorder.Header().Get("Vary"))
+				lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+				require.Len(t, lines, 1+len(want.Forecast[0].Detail))
+				require.True(t, strings.HasPrefix(lines[1], "london,"))
+			},
+			setupMock: func(mock *controllerMock.MockServiceController) {
+				mock.EXPECT().GetForecast(gomock.Any(), []string{"london"}, controller.ForecastOptions{}).Return(want, nil).Times(1)
+			},
+		},
+		{
+			name:        "when unknown format should return BAD REQUEST",
+			queryParams: "?city=london&format=yaml",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Result().StatusCode)
+				require.Contains(t, recorder.Body.String(), "supported formats are json, csv, geojson, ics, text, xml")
+			},
+			setupMock: func(mock *controllerMock.MockServiceController) {
+			},
+		},
+		{
+			name:        "when forecast is stale should report cache headers",
+			queryParams: "?city=london,paris",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+				require.Equal(t, controller.CacheStale, recorder.Header().Get("X-Cache"))
+				require.Equal(t, "1900", recorder.Header().Get("Age"))
+				require.Contains(t, recorder.Body.String(), `"stale":true`)
+			},
+			setupMock: func(mock *controllerMock.MockServiceController) {
+				mock.EXPECT().GetForecast(gomock.Any(), []string{"london", "paris"}, controller.ForecastOptions{}).Return(
+					model.WeatherForecast{Forecast: []model.Forecast{
+						{Name: "london", Cache: controller.CacheHit, Age: 60},
+						{Name: "paris", Cache: controller.CacheStale, Age: 1900, Stale: true},
+					}}, nil).Times(1)
 			},
 		},
 	}
@@ -100,7 +191,7 @@ func TestGetForecast(t *testing.T) {
 
 			serviceControllerMock := controllerMock.NewMockServiceController(ctrl)
 			tc.setupMock(serviceControllerMock)
-			handler := New(serviceControllerMock)
+			handler := New(serviceControllerMock, apiConfig)
 
 			recorder := httptest.NewRecorder()
 			url := fmt.Sprintf("/weather%v", tc.queryParams)
@@ -117,3 +208,851 @@ func TestGetForecast(t *testing.T) {
 		})
 	}
 }
+
+func TestForecastCalendar(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctrlMock := controllerMock.NewMockServiceController(ctrl)
+	ctrlMock.EXPECT().GetForecast(gomock.Any(), []string{"london"}, controller.ForecastOptions{}).Return(want, nil).Times(1)
+	routes := New(ctrlMock, apiConfig).Routes(zaptest.NewLogger(t), Modules{
+		Live:  http.NotFoundHandler(),
+		Rules: http.NotFoundHandler(),
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/weather.ics?city=london", nil)
+	// the feed ignores the Accept header
+	req.Header.Set("Accept", "application/json")
+	recorder := httptest.NewRecorder()
+	routes.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "text/calendar; charset=utf-8", recorder.Header().Get("Content-Type"))
+	require.True(t, strings.HasPrefix(recorder.Body.String(), "BEGIN:VCALENDAR\r\n"))
+	require.Equal(t, len(want.Forecast[0].Detail), strings.Count(recorder.Body.String(), "BEGIN:VEVENT\r\n"))
+}
+
+func TestGetAreaForecast(t *testing.T) {
+	cfg := apiConfig
+	cfg.Area = config.AreaConfig{MaxLimit: 10, MaxFetches: 2}
+	tcs := []struct {
+		name       string
+		query      string
+		wantStatus int
+		setupMock  func(mock *controllerMock.MockServiceController)
+	}{
+		{
+			name:       "when bbox is valid should forecast the area with the configured caps",
+			query:      "?bbox=-0.51,51.28,0.33,51.69",
+			wantStatus: http.StatusOK,
+			setupMock: func(mock *controllerMock.MockServiceController) {
+				mock.EXPECT().GetAreaForecast(gomock.Any(), geo.BBox{MinLon: -0.51, MinLat: 51.28, MaxLon: 0.33, MaxLat: 51.69},
+					10, 2, controller.ForecastOptions{}).Return(controller.AreaForecast{WeatherForecast: want, Total: 4, Skipped: []string{"croydon"}}, nil).Times(1)
+			},
+		},
+		{
+			name:       "when limit is set should forecast at most limit locations",
+			query:      "?bbox=-0.51,51.28,0.33,51.69&limit=3",
+			wantStatus: http.StatusOK,
+			setupMock: func(mock *controllerMock.MockServiceController) {
+				mock.EXPECT().GetAreaForecast(gomock.Any(), gomock.Any(), 3, 2, controller.ForecastOptions{}).Return(
+					controller.AreaForecast{WeatherForecast: want, Total: 4, Skipped: []string{"croydon"}}, nil).Times(1)
+			},
+		},
+		{
+			name:       "when bbox is missing should return BAD REQUEST",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name:       "when limit exceeds the max should return BAD REQUEST",
+			query:      "?bbox=-0.51,51.28,0.33,51.69&limit=11",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serviceControllerMock := controllerMock.NewMockServiceController(ctrl)
+			tc.setupMock(serviceControllerMock)
+
+			recorder := httptest.NewRecorder()
+			New(serviceControllerMock, cfg).GetAreaForecast(recorder, httptest.NewRequest(http.MethodGet, "/weather/area"+tc.query, nil))
+			require.Equal(t, tc.wantStatus, recorder.Code)
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			require.Equal(t, "4", recorder.Header().Get("X-Total-Count"))
+			require.Equal(t, "1", recorder.Header().Get("X-Skipped-Count"))
+		})
+	}
+}
+
+func TestGetNearbyForecast(t *testing.T) {
+	cfg := apiConfig
+	cfg.Area = config.AreaConfig{MaxLimit: 10, MaxFetches: 2, MaxRadius: 500}
+	nearby := model.WeatherForecast{Forecast: []model.Forecast{{
+		Name:     "london",
+		Distance: &model.Measurement{Value: 0.2, Unit: "km"},
+		Bearing:  &model.Measurement{Value: 248.8, Unit: "deg"},
+	}}}
+	tcs := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantBody   string
+		setupMock  func(mock *controllerMock.MockServiceController)
+	}{
+		{
+			name:       "when v2 should forecast the nearby locations with their distance",
+			target:     "/v2/weather/nearby?lat=51.508&lon=-0.1247&radius=50",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"forecasts":[{"location":{"name":"london"},"periods":[],"alerts":[],"stale":false,"distance":{"value":0.2,"unit":"km"},"bearing":{"value":248.8,"unit":"deg"}}]}`,
+			setupMock: func(mock *controllerMock.MockServiceController) {
+				mock.EXPECT().GetNearbyForecast(gomock.Any(), geo.Point{Lat: 51.508, Lon: -0.1247}, 50.0, 10, 2,
+					controller.ForecastOptions{Units: units.Metric}).Return(controller.AreaForecast{WeatherForecast: nearby, Total: 1}, nil).Times(1)
+			},
+		},
+		{
+			name:       "when imperial should search the radius in miles",
+			target:     "/v2/weather/nearby?lat=51.508&lon=-0.1247&radius=100&units=imperial&limit=3",
+			wantStatus: http.StatusOK,
+			setupMock: func(mock *controllerMock.MockServiceController) {
+				mock.EXPECT().GetNearbyForecast(gomock.Any(), gomock.Any(), 160.9344, 3, 2,
+					controller.ForecastOptions{Units: units.Imperial}).Return(controller.AreaForecast{WeatherForecast: nearby, Total: 1}, nil).Times(1)
+			},
+		},
+		{
+			name:       "when coordinates are out of range should return BAD REQUEST",
+			target:     "/v2/weather/nearby?lat=91&lon=-0.1247&radius=50",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name:       "when radius is missing should return BAD REQUEST",
+			target:     "/v2/weather/nearby?lat=51.508&lon=-0.1247",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name:       "when radius exceeds the max should return BAD REQUEST",
+			target:     "/v2/weather/nearby?lat=51.508&lon=-0.1247&radius=320&units=imperial",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "radius must not exceed 310.7 mi",
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name:       "when v1 should not be routed",
+			target:     "/v1/weather/nearby?lat=51.508&lon=-0.1247&radius=50",
+			wantStatus: http.StatusNotFound,
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serviceControllerMock := controllerMock.NewMockServiceController(ctrl)
+			tc.setupMock(serviceControllerMock)
+			routes := New(serviceControllerMock, cfg).Routes(zaptest.NewLogger(t), Modules{
+				Live:  http.NotFoundHandler(),
+				Rules: http.NotFoundHandler(),
+			})
+
+			recorder := httptest.NewRecorder()
+			routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			require.Equal(t, tc.wantStatus, recorder.Code)
+			switch {
+			case tc.wantStatus != http.StatusOK:
+				require.Contains(t, recorder.Body.String(), tc.wantBody)
+			case tc.wantBody != "":
+				require.JSONEq(t, tc.wantBody, recorder.Body.String())
+				require.Equal(t, "1", recorder.Header().Get("X-Total-Count"))
+			}
+		})
+	}
+}
+
+func TestGetActivityForecast(t *testing.T) {
+	activity := model.ActivityForecast{Activity: "running", Forecast: []model.CityActivity{{
+		Name: "paris",
+		Days: []model.ActivityDay{{
+			Date:    "2024-09-23",
+			Score:   60,
+			Rating:  "fair",
+			Reasons: []string{"wind up to 50 km/h is above the 30 km/h maximum"},
+			Detail:  model.Detail{StartTime: time.Date(2024, 9, 23, 6, 0, 0, 0, time.UTC), EndTime: time.Date(2024, 9, 23, 18, 0, 0, 0, time.UTC), Description: "windy"},
+		}},
+	}}}
+	tcs := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantBody   string
+		setupMock  func(mock *controllerMock.MockServiceController)
+	}{
+		{
+			name:       "when v2 should return the scored days",
+			target:     "/v2/weather/activity?city=paris&activity=running",
+			wantStatus: http.StatusOK,
+			wantBody: `{"activity":"running","locations":[{"location":{"name":"paris"},"stale":false,"days":[{"date":"2024-09-23","score":60,"rating":"fair",
+				"reasons":["wind up to 50 km/h is above the 30 km/h maximum"],"period":{"start":"2024-09-23T06:00:00Z","end":"2024-09-23T18:00:00Z","description":"windy"}}]}]}`,
+			setupMock: func(mock *controllerMock.MockServiceController) {
+				mock.EXPECT().GetActivityForecast(gomock.Any(), []string{"paris"}, "running", apiConfig.Activities["running"],
+					controller.ForecastOptions{}).Return(activity, nil).Times(1)
+			},
+		},
+		{
+			name:       "when activity is not configured should return BAD REQUEST",
+			target:     "/v2/weather/activity?city=paris&activity=skiing",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "supported activities are beach, running",
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name:       "when city is missing should return BAD REQUEST",
+			target:     "/v2/weather/activity?activity=running",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name:       "when no forecast should return NOT FOUND",
+			target:     "/v2/weather/activity?city=atlantis&activity=beach",
+			wantStatus: http.StatusNotFound,
+			setupMock: func(mock *controllerMock.MockServiceController) {
+				mock.EXPECT().GetActivityForecast(gomock.Any(), gomock.Any(), "beach", gomock.Any(), gomock.Any()).Return(model.ActivityForecast{}, controller.ErrForecastNotFound).Times(1)
+			},
+		},
+		{
+			name:       "when v1 should not be routed",
+			target:     "/v1/weather/activity?city=paris&activity=running",
+			wantStatus: http.StatusNotFound,
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serviceControllerMock := controllerMock.NewMockServiceController(ctrl)
+			tc.setupMock(serviceControllerMock)
+			routes := New(serviceControllerMock, apiConfig).Routes(zaptest.NewLogger(t), Modules{
+				Live:  http.NotFoundHandler(),
+				Rules: http.NotFoundHandler(),
+			})
+
+			recorder := httptest.NewRecorder()
+			routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			require.Equal(t, tc.wantStatus, recorder.Code)
+			switch {
+			case tc.wantStatus != http.StatusOK:
+				require.Contains(t, recorder.Body.String(), tc.wantBody)
+			case tc.wantBody != "":
+				require.JSONEq(t, tc.wantBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestCompareForecast(t *testing.T) {
+	comparison := model.Comparison{
+		Metric:  controller.MetricPrecipitation,
+		Best:    "lowest",
+		Ranking: []model.CityRank{{Rank: 1, Name: "paris", Value: &model.Measurement{Value: 20, Unit: "%"}, Days: 1, Wins: 1}, {Rank: 2, Name: "rome"}},
+		Days: []model.DayComparison{{Date: "2024-09-23", Values: []model.CityValue{
+			{Name: "paris", Value: &model.Measurement{Value: 20, Unit: "%"}}, {Name: "rome"},
+		}, Winners: []string{"paris"}}},
+	}
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1)
+	tcs := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantBody   string
+		setupMock  func(mock *controllerMock.MockServiceController)
+	}{
+		{
+			name:       "when v2 should return the ranking",
+			target:     "/v2/weather/compare?city=paris,rome&metric=precipitation",
+			wantStatus: http.StatusOK,
+			wantBody: `{"metric":"precipitation","best":"lowest","ranking":[{"rank":1,"location":{"name":"paris"},"value":{"value":20,"unit":"%"},"days":1,"wins":1},{"rank":2,"location":{"name":"rome"},"days":0,"wins":0}],
+				"days":[{"date":"2024-09-23","values":[{"location":{"name":"paris"},"value":{"value":20,"unit":"%"}},{"location":{"name":"rome"}}],"winners":[{"name":"paris"}],"tie":false}]}`,
+			setupMock: func(mock *controllerMock.MockServiceController) {
+				mock.EXPECT().CompareForecast(gomock.Any(), []string{"paris", "rome"}, controller.MetricPrecipitation, time.Time{},
+					controller.ForecastOptions{Units: units.Metric}).Return(comparison, nil).Times(1)
+			},
+		},
+		{
+			name:       "when date is within the horizon should only compare the date",
+			target:     "/v2/weather/compare?city=paris,rome&metric=wind&date=" + tomorrow.Format(time.DateOnly),
+			wantStatus: http.StatusOK,
+			setupMock: func(mock *controllerMock.MockServiceController) {
+				mock.EXPECT().CompareForecast(gomock.Any(), gomock.Any(), controller.MetricWind, tomorrow.Truncate(24*time.Hour), gomock.Any()).Return(comparison, nil).Times(1)
+			},
+		},
+		{
+			name:       "when metric is unsupported should return BAD REQUEST",
+			target:     "/v2/weather/compare?city=paris,rome&metric=humidity",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "supported metrics are temperature, precipitation, wind",
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name:       "when date is past the horizon should return BAD REQUEST",
+			target:     "/v2/weather/compare?city=paris,rome&metric=wind&date=" + tomorrow.AddDate(0, 0, 7).Format(time.DateOnly),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "date must be between",
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name:       "when v1 should not be routed",
+			target:     "/v1/weather/compare?city=paris,rome&metric=wind",
+			wantStatus: http.StatusNotFound,
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serviceControllerMock := controllerMock.NewMockServiceController(ctrl)
+			tc.setupMock(serviceControllerMock)
+			routes := New(serviceControllerMock, apiConfig).Routes(zaptest.NewLogger(t), Modules{
+				Live:  http.NotFoundHandler(),
+				Rules: http.NotFoundHandler(),
+			})
+
+			recorder := httptest.NewRecorder()
+			routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			require.Equal(t, tc.wantStatus, recorder.Code)
+			switch {
+			case tc.wantStatus != http.StatusOK:
+				require.Contains(t, recorder.Body.String(), tc.wantBody)
+			case tc.wantBody != "":
+				require.JSONEq(t, tc.wantBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestPostItinerary(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	date := func(offset int) string { return today.AddDate(0, 0, offset).Format(time.DateOnly) }
+	itinerary := model.Itinerary{Timeline: []model.ItineraryStop{{Date: date(0), Name: "chicago"}}}
+	tcs := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+		setupMock  func(mock *controllerMock.MockServiceController)
+	}{
+		{
+			name: "when legs are valid should return the timeline",
+			body: fmt.Sprintf(`{"legs":[{"location":{"name":"chicago"},"from":%q,"to":%q},{"location":{"lat":39.74,"lon":-104.99},"from":%q,"to":%q}],"units":"metric"}`,
+				date(0), date(1), date(2), date(2)),
+			wantStatus: http.StatusOK,
+			setupMock: func(mock *controllerMock.MockServiceController) {
+				lat, lon := 39.74, -104.99
+				mock.EXPECT().GetItineraryForecast(gomock.Any(), []controller.Leg{
+					{Location: model.LocationQuery{Name: "chicago"}, From: today, To: today.AddDate(0, 0, 1)},
+					{Location: model.LocationQuery{Lat: &lat, Lon: &lon}, From: today.AddDate(0, 0, 2), To: today.AddDate(0, 0, 2)},
+				}, controller.ForecastOptions{Units: units.Metric}).Return(itinerary, nil).Times(1)
+			},
+		},
+		{
+			name:       "when a leg is past the forecast horizon should return BAD REQUEST",
+			body:       fmt.Sprintf(`{"legs":[{"location":{"name":"chicago"},"from":%q,"to":%q}]}`, date(5), date(7)),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `"field":"legs[0].to"`,
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name:       "when a leg ends before it starts should return BAD REQUEST",
+			body:       fmt.Sprintf(`{"legs":[{"location":{"name":"chicago"},"from":%q,"to":%q}]}`, date(2), date(1)),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "to must not be before from",
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name:       "when a leg has no location should return BAD REQUEST",
+			body:       fmt.Sprintf(`{"legs":[{"location":{},"from":%q,"to":"monday"}]}`, date(0)),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "to must be YYYY-MM-DD",
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name:       "when no legs should return BAD REQUEST",
+			body:       `{"legs":[]}`,
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func(mock *controllerMock.MockServiceController) {},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serviceControllerMock := controllerMock.NewMockServiceController(ctrl)
+			tc.setupMock(serviceControllerMock)
+
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/v2/weather/itinerary", strings.NewReader(tc.body))
+			New(serviceControllerMock, apiConfig).PostItinerary(recorder, req)
+			require.Equal(t, tc.wantStatus, recorder.Code)
+			require.Contains(t, recorder.Body.String(), tc.wantBody)
+		})
+	}
+}
+
+func TestPostForecast(t *testing.T) {
+	lat, lon := 38.8951, -77.0364
+	tcs := []struct {
+		name          string
+		body          string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+		setupMock     func(mock *controllerMock.MockServiceController)
+	}{
+		{
+			name: "when invalid JSON should return BAD REQUEST",
+			body: `{"locations":`,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Result().StatusCode)
+			},
+			setupMock: func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name: "when body is too large should return REQUEST ENTITY TOO LARGE",
+			body: fmt.Sprintf(`{"locations":[{"name":"%s"}]}`, bytes.Repeat([]byte("a"), 2048)),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Result().StatusCode)
+			},
+			setupMock: func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name: "when batch is too large should return BAD REQUEST",
+			body: `{"locations":[{"name":"a"},{"name":"b"},{"name":"c"}]}`,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Result().StatusCode)
+			},
+			setupMock: func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name: "when location has only lat should return BAD REQUEST",
+			body: `{"locations":[{"lat":38.8}]}`,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Result().StatusCode)
+			},
+			setupMock: func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name: "when days over the forecast horizon should return BAD REQUEST",
+			body: `{"locations":[{"name":"london"}],"options":{"days":10}}`,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Result().StatusCode)
+			},
+			setupMock: func(mock *controllerMock.MockServiceController) {},
+		},
+		{
+			name: "when valid body should return forecast",
+			body: `{"locations":[{"name":"Washington, D.C.","country":"us"},{"lat":38.8951,"lon":-77.0364}],
+				"options":{"days":2,"units":"metric","include":["alerts"]}}`,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+				var got model.WeatherForecast
+				require.NoError(t, json.NewDecoder(recorder.Body).Decode(&got))
+				require.Equal(t, want, got)
+			},
+			setupMock: func(mock *controllerMock.MockServiceController) {
+				mock.EXPECT().GetBatchForecast(gomock.Any(),
+					[]model.LocationQuery{
+						{Name: "Washington, D.C.", Country: "us"},
+						{Lat: &lat, Lon: &lon},
+					},
+					controller.ForecastOptions{IncludeAlerts: true, Days: 2, Units: units.Metric},
+				).Return(want, nil).Times(1)
+			},
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			serviceControllerMock := controllerMock.NewMockServiceController(ctrl)
+			tc.setupMock(serviceControllerMock)
+			handler := New(serviceControllerMock, apiConfig)
+
+			recorder := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodPost, "/weather", bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+
+			handler.PostForecast(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestStreamForecast(t *testing.T) {
+	tcs := []struct {
+		name         string
+		accept       string
+		wantBody     string
+		wantMimeType string
+	}{
+		{
+			name:         "when accept NDJSON should write one line per city",
+			accept:       "application/x-ndjson",
+			wantMimeType: "application/x-ndjson",
+			wantBody: `{"name":"london","detail":null}` + "\n" +
+				`{"name":"atlantis","error":"location not found"}` + "\n",
+		},
+		{
+			name:         "when accept event stream should write one event per city",
+			accept:       "text/event-stream;q=0.9, application/json",
+			wantMimeType: "text/event-stream",
+			wantBody: "event: forecast\ndata: {\"name\":\"london\",\"detail\":null}\n\n" +
+				"event: error\ndata: {\"name\":\"atlantis\",\"error\":\"location not found\"}\n\n" +
+				"event: done\ndata: {}\n\n",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			serviceControllerMock := controllerMock.NewMockServiceController(ctrl)
+			serviceControllerMock.EXPECT().StreamForecast(gomock.Any(),
+				[]model.LocationQuery{{Name: "london"}, {Name: "atlantis"}}, controller.ForecastOptions{},
+			).DoAndReturn(func(ctx context.Context, locations []model.LocationQuery, opts controller.ForecastOptions) <-chan controller.ForecastResult {
+				results := make(chan controller.ForecastResult, 2)
+				results <- controller.ForecastResult{Index: 0, Forecast: model.Forecast{Name: "london"}}
+				results <- controller.ForecastResult{Index: 1, Forecast: model.Forecast{Name: "atlantis"}, Err: controller.ErrLocationNotFound}
+				close(results)
+				return results
+			}).Times(1)
+			handler := New(serviceControllerMock, apiConfig)
+
+			recorder := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, "/weather?city=london,atlantis", nil)
+			require.NoError(t, err)
+			req.Header.Set("Accept", tc.accept)
+
+			handler.GetForecast(recorder, req)
+			require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+			require.Equal(t, tc.wantMimeType, recorder.Result().Header.Get(contentTypeKey))
+			require.True(t, recorder.Flushed)
+			require.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestRoutesCORS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cfg := apiConfig
+	cfg.CORS = config.CORSConfig{
+		AllowedOrigins: []string{"https://*.example.com"},
+		AllowedMethods: []string{"get", "post", "patch"},
+		AllowedHeaders: []string{"Authorization"},
+		MaxAge:         60,
+	}
+	rules := chi.NewRouter()
+	rules.Get("/", http.NotFound)
+	routes := New(controllerMock.NewMockServiceController(ctrl), cfg).Routes(zaptest.NewLogger(t), Modules{
+		Live:  http.NotFoundHandler(),
+		Rules: rules,
+	})
+
+	tcs := []struct {
+		name        string
+		origin      string
+		method      string
+		wantAllowed bool
+	}{
+		{name: "when subdomain origin and routed method should allow", origin: "https://app.example.com", method: http.MethodPost, wantAllowed: true},
+		{name: "when method not configured should deny", origin: "https://app.example.com", method: http.MethodDelete},
+		{name: "when method has no route should deny", origin: "https://app.example.com", method: http.MethodPatch},
+		{name: "when origin not allowed should deny", origin: "https://example.org", method: http.MethodGet},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/weather", nil)
+			req.Header.Set("Origin", tc.origin)
+			req.Header.Set("Access-Control-Request-Method", tc.method)
+			recorder := httptest.NewRecorder()
+			routes.ServeHTTP(recorder, req)
+
+			if !tc.wantAllowed {
+				require.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+				return
+			}
+			require.Equal(t, tc.origin, recorder.Header().Get("Access-Control-Allow-Origin"))
+			require.Equal(t, tc.method, recorder.Header().Get("Access-Control-Allow-Methods"))
+			require.Equal(t, "60", recorder.Header().Get("Access-Control-Max-Age"))
+			require.Empty(t, recorder.Header().Get("Access-Control-Allow-Credentials"))
+		})
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	tcs := []struct {
+		name       string
+		target     string
+		ctrlErr    error
+		wantStatus int
+		wantType   string
+		wantDetail string
+		wantErrors []model.FieldError
+		// wantRetryAfter is the expected Retry-After header, empty when not sent
+		wantRetryAfter string
+		expectsCall    bool
+	}{
+		{
+			name:       "when city is missing should list the invalid field",
+			target:     "/v1/weather",
+			wantStatus: http.StatusBadRequest,
+			wantType:   problem.TypeInvalidRequest,
+			wantDetail: "invalid city",
+			wantErrors: []model.FieldError{{Field: "city", Detail: "please provide a list of cities"}},
+		},
+		{
+			name:        "when location not found should return NOT FOUND",
+			target:      "/v1/weather?city=atlantis",
+			ctrlErr:     fmt.Errorf("lookup: %w", controller.ErrLocationNotFound),
+			wantStatus:  http.StatusNotFound,
+			wantType:    problem.TypeNotFound,
+			wantDetail:  "location not found",
+			expectsCall: true,
+		},
+		{
+			name:        "when upstream unavailable should return BAD GATEWAY",
+			target:      "/v1/weather?city=london",
+			ctrlErr:     &controller.Error{Kind: controller.KindUnavailable, Detail: "unable to retrieve forecast", Err: errors.New("503")},
+			wantStatus:  http.StatusBadGateway,
+			wantType:    problem.TypeUpstreamUnavailable,
+			wantDetail:  "unable to retrieve forecast",
+			expectsCall: true,
+		},
+		{
+			name:           "when upstream rate limited should return SERVICE UNAVAILABLE",
+			target:         "/v1/weather?city=london",
+			ctrlErr:        &controller.Error{Kind: controller.KindRateLimited, Detail: "weather.gov rate limit reached", RetryAfter: 1500 * time.Millisecond},
+			wantStatus:     http.StatusServiceUnavailable,
+			wantType:       problem.TypeUpstreamUnavailable,
+			wantDetail:     "weather.gov rate limit reached",
+			wantRetryAfter: "2",
+			expectsCall:    true,
+		},
+		{
+			name:        "when upstream timed out should return GATEWAY TIMEOUT",
+			target:      "/v1/weather?city=london",
+			ctrlErr:     context.DeadlineExceeded,
+			wantStatus:  http.StatusGatewayTimeout,
+			wantType:    problem.TypeUpstreamTimeout,
+			wantDetail:  "timed out",
+			expectsCall: true,
+		},
+		{
+			name:        "when unexpected error should not leak its cause",
+			target:      "/v1/weather?city=london",
+			ctrlErr:     errors.New("dial tcp 10.0.0.1:5432: connection refused"),
+			wantStatus:  http.StatusInternalServerError,
+			wantType:    problem.TypeInternal,
+			wantDetail:  "unexpected failure",
+			expectsCall: true,
+		},
+		{
+			name:       "when route is unknown should return NOT FOUND",
+			target:     "/v3/weather",
+			wantStatus: http.StatusNotFound,
+			wantType:   problem.TypeNotFound,
+			wantDetail: "no route for /v3/weather",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ctrlMock := controllerMock.NewMockServiceController(ctrl)
+			if tc.expectsCall {
+				ctrlMock.EXPECT().GetForecast(gomock.Any(), gomock.Any(), gomock.Any()).Return(model.WeatherForecast{}, tc.ctrlErr)
+			}
+			routes := New(ctrlMock, apiConfig).Routes(zaptest.NewLogger(t), Modules{
+				Live:  http.NotFoundHandler(),
+				Rules: http.NotFoundHandler(),
+			})
+
+			recorder := httptest.NewRecorder()
+			routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			require.Equal(t, tc.wantStatus, recorder.Code)
+			require.Equal(t, problem.ContentType, recorder.Header().Get("Content-Type"))
+			require.Equal(t, tc.wantRetryAfter, recorder.Header().Get("Retry-After"))
+
+			var got model.Problem
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+			require.Equal(t, tc.wantType, got.Type)
+			require.Equal(t, tc.wantStatus, got.Status)
+			require.Equal(t, http.StatusText(tc.wantStatus), got.Title)
+			require.Equal(t, tc.wantDetail, got.Detail)
+			require.Equal(t, tc.wantErrors, got.Errors)
+			require.NotEmpty(t, got.RequestID)
+			require.Equal(t, recorder.Header().Get("X-Request-Id"), got.RequestID)
+		})
+	}
+}
+
+func TestCanceledRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctrlMock := controllerMock.NewMockServiceController(ctrl)
+	ctrlMock.EXPECT().GetForecast(gomock.Any(), gomock.Any(), gomock.Any()).Return(model.WeatherForecast{}, context.Canceled)
+	core, logs := observer.New(zap.InfoLevel)
+	routes := New(ctrlMock, apiConfig).Routes(zap.New(core), Modules{
+		Live:  http.NotFoundHandler(),
+		Rules: http.NotFoundHandler(),
+	})
+
+	recorder := httptest.NewRecorder()
+	routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/weather?city=london", nil))
+	require.Equal(t, statusClientClosedRequest, recorder.Code)
+	require.Empty(t, recorder.Body.String())
+	// a client disconnect isn't a server error
+	require.Zero(t, logs.FilterLevelExact(zap.ErrorLevel).Len())
+	require.Equal(t, 1, logs.FilterMessage("unable retrieve forecast").Len())
+}
+
+func TestControllerErrorStatus(t *testing.T) {
+	london := model.Location{Lat: "51.5073", Lon: "-0.1276", DisplayName: "London"}
+	unavailable := &upstream.Error{Service: "weather.gov", Kind: upstream.ErrUnavailable, StatusCode: http.StatusInternalServerError}
+	tcs := []struct {
+		name       string
+		target     string
+		setupMocks func(osm *openStreetMapAPIMock.MockOpenstreetmapperGateway, weather *weatherAPIMock.MockWeatherGateway,
+			alerts *alertsAPIMock.MockAlertsGateway)
+		wantStatus int
+		wantDetail string
+	}{
+		{
+			name:   "when the only city is unknown should fail with 404",
+			target: "/v2/weather?city=atlantis",
+			setupMocks: func(osm *openStreetMapAPIMock.MockOpenstreetmapperGateway, _ *weatherAPIMock.MockWeatherGateway, _ *alertsAPIMock.MockAlertsGateway) {
+				osm.EXPECT().GetLocation(gomock.Any(), "atlantis", "").Return(nil, nil)
+			},
+			wantStatus: http.StatusNotFound,
+			wantDetail: "location not found",
+		},
+		{
+			name:   "when weather.gov fails should fail with 502",
+			target: "/v2/weather?city=london",
+			setupMocks: func(osm *openStreetMapAPIMock.MockOpenstreetmapperGateway, weather *weatherAPIMock.MockWeatherGateway, _ *alertsAPIMock.MockAlertsGateway) {
+				osm.EXPECT().GetLocation(gomock.Any(), "london", "").Return([]model.Location{london}, nil)
+				weather.EXPECT().GetForecast(gomock.Any(), london.Lat, london.Lon).Return(nil, unavailable)
+			},
+			wantStatus: http.StatusBadGateway,
+			wantDetail: "unable to retrieve forecast",
+		},
+		{
+			name:   "when some cities can be forecast should skip the others",
+			target: "/v2/weather?city=atlantis,london",
+			setupMocks: func(osm *openStreetMapAPIMock.MockOpenstreetmapperGateway, weather *weatherAPIMock.MockWeatherGateway, _ *alertsAPIMock.MockAlertsGateway) {
+				osm.EXPECT().GetLocation(gomock.Any(), "atlantis", "").Return(nil, nil)
+				osm.EXPECT().GetLocation(gomock.Any(), "london", "").Return([]model.Location{london}, nil)
+				now := time.Now().UTC()
+				weather.EXPECT().GetForecast(gomock.Any(), london.Lat, london.Lon).Return([]model.Period{
+					{StartTime: now, EndTime: now, Description: "gray"},
+				}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "when alerts fail should fail with 502",
+			target: "/v2/alerts?city=london",
+			setupMocks: func(osm *openStreetMapAPIMock.MockOpenstreetmapperGateway, _ *weatherAPIMock.MockWeatherGateway, alerts *alertsAPIMock.MockAlertsGateway) {
+				osm.EXPECT().GetLocation(gomock.Any(), "london", "").Return([]model.Location{london}, nil)
+				alerts.EXPECT().GetAlerts(gomock.Any(), london.Lat, london.Lon).Return(nil, unavailable)
+			},
+			wantStatus: http.StatusBadGateway,
+			wantDetail: "unable to retrieve alerts",
+		},
+		{
+			name:   "when the current conditions city is unknown should fail with 404",
+			target: "/v2/current?city=atlantis",
+			setupMocks: func(osm *openStreetMapAPIMock.MockOpenstreetmapperGateway, _ *weatherAPIMock.MockWeatherGateway, _ *alertsAPIMock.MockAlertsGateway) {
+				osm.EXPECT().GetLocation(gomock.Any(), "atlantis", "").Return(nil, nil)
+			},
+			wantStatus: http.StatusNotFound,
+			wantDetail: "location not found",
+		},
+		{
+			name:   "when the gridpoint fails should fail with 502",
+			target: "/v2/weather/grid?city=london",
+			setupMocks: func(osm *openStreetMapAPIMock.MockOpenstreetmapperGateway, weather *weatherAPIMock.MockWeatherGateway, _ *alertsAPIMock.MockAlertsGateway) {
+				osm.EXPECT().GetLocation(gomock.Any(), "london", "").Return([]model.Location{london}, nil)
+				weather.EXPECT().GetGridpoint(gomock.Any(), london.Lat, london.Lon).Return(nil, unavailable)
+			},
+			wantStatus: http.StatusBadGateway,
+			wantDetail: "unable to retrieve gridpoint",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			osm := openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl)
+			weather := weatherAPIMock.NewMockWeatherGateway(ctrl)
+			alerts := alertsAPIMock.NewMockAlertsGateway(ctrl)
+			tc.setupMocks(osm, weather, alerts)
+
+			// the real controller classifies the failures of its gateways
+			weatherAppController := controller.New(osm, weather, alerts, respository.New(time.Minute, time.Minute, 0, 0))
+			routes := New(weatherAppController, apiConfig).Routes(zaptest.NewLogger(t), Modules{
+				Live:  http.NotFoundHandler(),
+				Rules: http.NotFoundHandler(),
+			})
+
+			recorder := httptest.NewRecorder()
+			routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			require.Equal(t, tc.wantStatus, recorder.Code)
+			if tc.wantStatus == http.StatusOK {
+				return
+			}
+			var got model.Problem
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+			require.Equal(t, tc.wantDetail, got.Detail)
+		})
+	}
+}
+
+func TestUpstreamRateLimited(t *testing.T) {
+	// a fake weather.gov rate limiting every call
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"type":"https://api.weather.gov/problems/RateLimitExceeded","title":"Rate limit exceeded"}`)
+	}))
+	defer server.Close()
+
+	ctrl := gomock.NewController(t)
+	weatherClient := weatherclient.New(config.WeatherAPIConfig{URL: server.URL, Timeout: 5})
+	weatherAppController := controller.New(openStreetMapAPIMock.NewMockOpenstreetmapperGateway(ctrl), weatherClient,
+		weatherClient, respository.New(time.Minute, time.Minute, 0, 0))
+	routes := New(weatherAppController, apiConfig).Routes(zaptest.NewLogger(t), Modules{
+		Live:  http.NotFoundHandler(),
+		Rules: http.NotFoundHandler(),
+	})
+
+	recorder := httptest.NewRecorder()
+	// coordinates skip the OpenStreetMap lookup
+	routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v2/weather",
+		strings.NewReader(`{"locations":[{"lat":38.8951,"lon":-77.0364}]}`)))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	require.Equal(t, "120", recorder.Header().Get("Retry-After"))
+	require.EqualValues(t, 1, calls.Load())
+
+	var got model.Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	require.Equal(t, problem.TypeUpstreamUnavailable, got.Type)
+	require.Equal(t, "weather.gov rate limit reached", got.Detail)
+}
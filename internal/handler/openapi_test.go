@@ -0,0 +1,337 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	controllerMock "github.com/dibrito/ennismore-weather-app/gen/mock/controller"
+	"github.com/dibrito/ennismore-weather-app/internal/admin"
+	"github.com/dibrito/ennismore-weather-app/internal/controller"
+	"github.com/dibrito/ennismore-weather-app/internal/docs"
+	respository "github.com/dibrito/ennismore-weather-app/internal/repository"
+	"github.com/dibrito/ennismore-weather-app/internal/rules"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/dibrito/ennismore-weather-app/pkg/problem"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap/zaptest"
+)
+
+// openAPI is the subset of the OpenAPI document the tests check against.
+type openAPI struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	Responses map[string]struct {
+		Content map[string]struct {
+			Schema *schema `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+}
+
+// schema is the subset of the OpenAPI schema object the responses use.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *schema            `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+}
+
+func loadSpec(t *testing.T) openAPI {
+	var spec openAPI
+	require.NoError(t, json.Unmarshal(docs.Spec, &spec))
+	return spec
+}
+
+// validate checks a decoded JSON value against a schema, objects with
+// declared properties can't hold undeclared ones.
+func (spec openAPI) validate(s *schema, v any, at string) error {
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		ref, ok := spec.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", at, s.Ref)
+		}
+		return spec.validate(ref, v, at)
+	}
+	if v == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not nullable", at)
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			found = found || e == v
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", at, v, s.Enum)
+		}
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", at, v)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required %q", at, name)
+			}
+		}
+		for name, value := range obj {
+			prop, ok := s.Properties[name]
+			if !ok {
+				prop = s.AdditionalProperties
+			}
+			if prop == nil {
+				if len(s.Properties) == 0 {
+					continue
+				}
+				return fmt.Errorf("%s: undeclared property %q", at, name)
+			}
+			if err := spec.validate(prop, value, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %T", at, v)
+		}
+		for i, item := range arr {
+			if err := spec.validate(s.Items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string, got %T", at, v)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", at, str)
+			}
+		}
+	case "number", "integer":
+		n, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("%s: expected %s, got %T", at, s.Type, v)
+		}
+		if s.Type == "integer" && n != float64(int64(n)) {
+			return fmt.Errorf("%s: %v is not an integer", at, n)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean, got %T", at, v)
+		}
+	}
+	return nil
+}
+
+// validateResponse checks the recorded response is documented for the route
+// and, when JSON or GeoJSON, that its body matches the documented schema.
+func (spec openAPI) validateResponse(t *testing.T, method, route string, recorder *httptest.ResponseRecorder) {
+	raw, ok := spec.Paths[route][strings.ToLower(method)]
+	require.True(t, ok, "%s %s is not documented", method, route)
+	var op operation
+	require.NoError(t, json.Unmarshal(raw, &op))
+
+	status := fmt.Sprint(recorder.Code)
+	response, ok := op.Responses[status]
+	require.True(t, ok, "%s %s %s is not documented", method, route, status)
+
+	contentType := strings.TrimSpace(strings.Split(recorder.Header().Get("Content-Type"), ";")[0])
+	if (contentType != "application/json" && contentType != "application/geo+json" && contentType != problem.ContentType) || recorder.Body.Len() == 0 {
+		return
+	}
+	media, ok := response.Content[contentType]
+	require.True(t, ok, "%s %s %s %s is not documented", method, route, status, contentType)
+
+	var body any
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	require.NoError(t, spec.validate(media.Schema, body, "body"), "%s %s %s", method, route, status)
+}
+
+func newDocumentedRoutes(t *testing.T, ctrl ServiceController) http.Handler {
+	store, err := rules.NewFileStore("")
+	require.NoError(t, err)
+	cache := respository.New(time.Minute, time.Minute, 0, 0)
+	temperature := 20.5
+	cache.PutLocation("london", model.Location{Lat: "51.5", Lon: "-0.1", DisplayName: "London"})
+	cache.PutPeriods("london", "2024-09-23", model.Period{StartTime: time.Now().UTC(), EndTime: time.Now().UTC(), Temperature: &temperature})
+
+	return New(ctrl, apiConfig).Routes(zaptest.NewLogger(t), Modules{
+		Live:  http.NotFoundHandler(),
+		Rules: rules.NewHandler(store, rules.NewNotifier(config.RulesConfig{}, zaptest.NewLogger(t))).Routes(),
+		Admin: admin.NewHandler(cache, config.AdminConfig{Token: "secret"}).Routes(),
+	})
+}
+
+func TestOpenAPIDocumentsRoutes(t *testing.T) {
+	spec := loadSpec(t)
+	routes := newDocumentedRoutes(t, controllerMock.NewMockServiceController(gomock.NewController(t)))
+
+	var registered []string
+	require.NoError(t, chi.Walk(routes.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		registered = append(registered, method+" "+route)
+		_, ok := spec.Paths[route][strings.ToLower(method)]
+		require.True(t, ok, "%s %s is not documented", method, route)
+		return nil
+	}))
+
+	// every documented operation is routed, /health is served by a middleware
+	for route, item := range spec.Paths {
+		for method := range item {
+			if method == "parameters" || route == "/health" {
+				continue
+			}
+			require.Contains(t, registered, strings.ToUpper(method)+" "+route)
+		}
+	}
+}
+
+func TestOpenAPIResponses(t *testing.T) {
+	spec := loadSpec(t)
+	ctrl := gomock.NewController(t)
+	ctrlMock := controllerMock.NewMockServiceController(ctrl)
+	routes := newDocumentedRoutes(t, ctrlMock)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	value := 12.3
+	forecast := model.WeatherForecast{Forecast: []model.Forecast{{
+		Name:   "london",
+		Detail: []model.Detail{{StartTime: now, EndTime: now.Add(time.Hour), Description: "gray", Temperature: &value, TemperatureUnit: "C", PrecipitationProbability: &value}},
+		Alerts: []model.Alert{{ID: "1", Event: "Heat Advisory", Effective: now, Expires: now}},
+		Stale:  true,
+		Cache:  controller.CacheStale,
+	}}}
+	ctrlMock.EXPECT().GetForecast(gomock.Any(), gomock.Any(), gomock.Any()).Return(forecast, nil).AnyTimes()
+	ctrlMock.EXPECT().GetBatchForecast(gomock.Any(), gomock.Any(), gomock.Any()).Return(forecast, nil).AnyTimes()
+	ctrlMock.EXPECT().GetAreaForecast(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(
+		controller.AreaForecast{WeatherForecast: forecast, Total: 3, Skipped: []string{"paris"}}, nil).AnyTimes()
+	nearby := forecast
+	nearby.Forecast = []model.Forecast{forecast.Forecast[0]}
+	nearby.Forecast[0].Distance = &model.Measurement{Value: 1.2, Unit: "km"}
+	nearby.Forecast[0].Bearing = &model.Measurement{Value: 248.8, Unit: "deg"}
+	nearby.Forecast[0].Location = &model.Location{Lat: "51.5073", Lon: "-0.1276", DisplayName: "London", BoundingBox: []string{"51.2867", "51.6918", "-0.5103", "0.3340"}}
+	ctrlMock.EXPECT().GetNearbyForecast(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(
+		controller.AreaForecast{WeatherForecast: nearby, Total: 1}, nil).AnyTimes()
+	ctrlMock.EXPECT().CompareForecast(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(model.Comparison{
+		Metric:  controller.MetricTemperature,
+		Best:    "highest",
+		Ranking: []model.CityRank{{Rank: 1, Name: "london", Value: &model.Measurement{Value: value, Unit: "degF"}, Days: 1, Wins: 1}, {Rank: 2, Name: "paris"}},
+		Days: []model.DayComparison{{Date: now.Format(time.DateOnly), Values: []model.CityValue{
+			{Name: "london", Value: &model.Measurement{Value: value, Unit: "degF"}}, {Name: "paris"},
+		}, Winners: []string{"london"}}},
+	}, nil).AnyTimes()
+	ctrlMock.EXPECT().GetActivityForecast(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(model.ActivityForecast{
+		Activity: "running",
+		Forecast: []model.CityActivity{{Name: "london", Stale: true, Days: []model.ActivityDay{{
+			Date: now.Format(time.DateOnly), Score: 80, Rating: controller.RatingGood, Reasons: []string{"forecast mentions sunny"}, Detail: forecast.Forecast[0].Detail[0],
+		}}}},
+	}, nil).AnyTimes()
+	ctrlMock.EXPECT().GetItineraryForecast(gomock.Any(), gomock.Any(), gomock.Any()).Return(model.Itinerary{Timeline: []model.ItineraryStop{
+		{Date: now.Format(time.DateOnly), Name: "london", Detail: &forecast.Forecast[0].Detail[0], Stale: true},
+		{Date: now.Format(time.DateOnly), Leg: 1, Name: "atlantis", Error: "location not found"},
+	}}, nil).AnyTimes()
+	ctrlMock.EXPECT().GetAlerts(gomock.Any(), gomock.Any()).Return(model.WeatherAlerts{Alerts: []model.CityAlerts{
+		{Name: "london", Alerts: []model.Alert{{ID: "1", Effective: now, Expires: now}}},
+		{Name: "paris"},
+	}}, nil).AnyTimes()
+	ctrlMock.EXPECT().GetCurrentConditions(gomock.Any(), gomock.Any(), gomock.Any()).Return(model.CurrentConditions{Current: []model.CityConditions{{
+		Name: "london", Timestamp: now, Age: 60, Temperature: &model.Measurement{Value: value, Unit: "C"},
+	}}}, nil).AnyTimes()
+	ctrlMock.EXPECT().GetGridForecast(gomock.Any(), gomock.Any(), gomock.Any()).Return(model.GridForecast{Grid: []model.CityGrid{{
+		Name: "london", Layers: map[string]model.GridLayer{"temperature": {Unit: "wmoUnit:degC", Values: []model.GridValue{{Time: now, Value: &value}, {Time: now}}}},
+	}}}, nil).AnyTimes()
+
+	itinerary := fmt.Sprintf(`{"legs":[{"location":{"name":"london"},"from":%[1]q,"to":%[1]q},{"location":{"name":"atlantis"},"from":%[1]q,"to":%[1]q}]}`,
+		now.Format(time.DateOnly))
+	rule := `{"city":"london","url":"https://93.184.216.34/hook","condition":{"type":"precipitation","threshold":70,"withinHours":24}}`
+	tcs := []struct {
+		method string
+		target string
+		route  string
+		body   string
+	}{
+		{method: http.MethodGet, target: "/weather?city=london&include=alerts", route: "/weather"},
+		{method: http.MethodGet, target: "/weather?city=london&include=radar", route: "/weather"},
+		{method: http.MethodPost, target: "/weather", route: "/weather", body: `{"locations":[{"name":"london"}]}`},
+		{method: http.MethodPost, target: "/weather", route: "/weather", body: `{"locations":[]}`},
+		{method: http.MethodGet, target: "/weather/grid?city=london", route: "/weather/grid"},
+		{method: http.MethodGet, target: "/alerts?city=london,paris", route: "/alerts"},
+		{method: http.MethodGet, target: "/current?city=london", route: "/current"},
+		{method: http.MethodGet, target: "/v1/weather?city=london&include=alerts", route: "/v1/weather"},
+		{method: http.MethodPost, target: "/v1/weather", route: "/v1/weather", body: `{"locations":[{"name":"london"}]}`},
+		{method: http.MethodGet, target: "/v1/weather/grid?city=london", route: "/v1/weather/grid"},
+		{method: http.MethodGet, target: "/v1/alerts?city=london,paris", route: "/v1/alerts"},
+		{method: http.MethodGet, target: "/v1/current?city=london", route: "/v1/current"},
+		{method: http.MethodGet, target: "/v2/weather?city=london&include=alerts", route: "/v2/weather"},
+		{method: http.MethodPost, target: "/v2/weather", route: "/v2/weather", body: `{"locations":[{"name":"london"}]}`},
+		{method: http.MethodGet, target: "/v2/weather/grid?city=london", route: "/v2/weather/grid"},
+		{method: http.MethodGet, target: "/v2/weather/area?bbox=-0.5,51.2,0.3,51.7&limit=2", route: "/v2/weather/area"},
+		{method: http.MethodGet, target: "/v2/weather/area?bbox=-0.5,51.2,0.3,51.7&format=geojson", route: "/v2/weather/area"},
+		{method: http.MethodGet, target: "/v2/weather/nearby?lat=51.5&lon=-0.12&radius=10&format=geojson", route: "/v2/weather/nearby"},
+		{method: http.MethodGet, target: "/v1/weather/area?bbox=-0.5,51.2,0.3", route: "/v1/weather/area"},
+		{method: http.MethodGet, target: "/v2/weather/nearby?lat=51.5&lon=-0.12&radius=10", route: "/v2/weather/nearby"},
+		{method: http.MethodGet, target: "/v2/weather/nearby?lat=51.5&lon=-0.12&radius=-1", route: "/v2/weather/nearby"},
+		{method: http.MethodGet, target: "/v2/weather/activity?city=london&activity=running", route: "/v2/weather/activity"},
+		{method: http.MethodGet, target: "/v2/weather/activity?city=london&activity=skiing", route: "/v2/weather/activity"},
+		{method: http.MethodGet, target: "/v2/weather/compare?city=london,paris&metric=temperature", route: "/v2/weather/compare"},
+		{method: http.MethodGet, target: "/v2/weather/compare?city=london,paris&metric=humidity", route: "/v2/weather/compare"},
+		{method: http.MethodPost, target: "/v2/weather/itinerary", route: "/v2/weather/itinerary", body: itinerary},
+		{method: http.MethodPost, target: "/v2/weather/itinerary", route: "/v2/weather/itinerary", body: `{"legs":[{"location":{"name":"london"},"from":"tomorrow"}]}`},
+		{method: http.MethodGet, target: "/v2/alerts?city=london,paris", route: "/v2/alerts"},
+		{method: http.MethodGet, target: "/v2/current?city=london", route: "/v2/current"},
+		{method: http.MethodPost, target: "/rules", route: "/rules", body: rule},
+		{method: http.MethodGet, target: "/rules", route: "/rules"},
+		{method: http.MethodGet, target: "/rules/deadletters", route: "/rules/deadletters"},
+		{method: http.MethodGet, target: "/rules/missing", route: "/rules/{id}"},
+		{method: http.MethodGet, target: "/admin/cache", route: "/admin/cache"},
+		{method: http.MethodGet, target: "/admin/cache/london", route: "/admin/cache/{key}"},
+		{method: http.MethodGet, target: "/admin/cache/snapshot", route: "/admin/cache/snapshot"},
+		{method: http.MethodGet, target: "/v1/weather", route: "/v1/weather"},
+		{method: http.MethodGet, target: "/v2/weather?city=london&include=radar", route: "/v2/weather"},
+		{method: http.MethodPost, target: "/v2/weather", route: "/v2/weather", body: `{"locations":[{"lat":91},{"name":"paris","country":"fra"}]}`},
+		{method: http.MethodPost, target: "/weather", route: "/weather", body: `{"locations":`},
+		{method: http.MethodPost, target: "/rules", route: "/rules", body: `{"city":"london"}`},
+		{method: http.MethodGet, target: "/admin/cache?limit=0", route: "/admin/cache"},
+		{method: http.MethodGet, target: "/admin/cache/missing", route: "/admin/cache/{key}"},
+		{method: http.MethodGet, target: "/openapi.json", route: "/openapi.json"},
+		{method: http.MethodGet, target: "/docs", route: "/docs"},
+		{method: http.MethodGet, target: "/health", route: "/health"},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.method+" "+tc.target, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			req.Header.Set("Authorization", "Bearer secret")
+			recorder := httptest.NewRecorder()
+			routes.ServeHTTP(recorder, req)
+			spec.validateResponse(t, tc.method, tc.route, recorder)
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/dibrito/ennismore-weather-app/pkg/problem"
+	"golang.org/x/time/rate"
+)
+
+// nowFunc will get the now time when the client buckets are checked.
+var nowFunc = time.Now
+
+// defaultIdleTimeout is how long an unused client bucket is kept when not
+// configured.
+const defaultIdleTimeout = 10 * time.Minute
+
+// rateLimiter limits the requests of every client IP with a token bucket per
+// route.
+type rateLimiter struct {
+	trusted      []*net.IPNet
+	idleTimeout  time.Duration
+	maxBodyBytes int64
+	limits       config.RateLimitConfig
+
+	mu sync.Mutex
+	// buckets holds the client buckets by route and client IP.
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket is a client token bucket.
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newRateLimiter creates the client IP rate limiter, the trusted proxies are
+// expected to be validated already.
+func newRateLimiter(cfg config.APIConfig) *rateLimiter {
+	rl := &rateLimiter{
+		idleTimeout:  time.Duration(cfg.RateLimit.IdleTimeout) * time.Second,
+		maxBodyBytes: cfg.MaxBodyBytes,
+		limits:       cfg.RateLimit,
+		buckets:      make(map[string]*bucket),
+		lastSweep:    nowFunc(),
+	}
+	if rl.idleTimeout <= 0 {
+		rl.idleTimeout = defaultIdleTimeout
+	}
+	for _, proxy := range cfg.RateLimit.TrustedProxies {
+		if network, err := config.ParseCIDR(proxy); err == nil {
+			rl.trusted = append(rl.trusted, network)
+		}
+	}
+	return rl
+}
+
+// costFunc returns how many tokens a request takes from the client bucket.
+type costFunc func(req *http.Request) int
+
+// Limit rate-limits the route by client IP, every request takes the tokens
+// returned by cost. Requests over the limit get TOO MANY REQUESTS with a
+// Retry-After header, requests costing more than the burst can never be
+// allowed and get BAD REQUEST. Routes without a configured limit use the
+// default one.
+func (rl *rateLimiter) Limit(route string, cost costFunc) func(http.Handler) http.Handler {
+	limit := rl.limits.Limit(route)
+	return func(next http.Handler) http.Handler {
+		if limit.Rate <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			tokens := cost(req)
+			if burst := max(limit.Burst, 1); tokens > burst {
+				problem.Error(w, req, http.StatusBadRequest,
+					fmt.Sprintf("request costs %d rate limit tokens, more than the %d allowed at once", tokens, burst))
+				return
+			}
+			allowed, retryAfter := rl.allow(route+"|"+rl.clientIP(req), limit, tokens)
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				problem.Error(w, req, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// allow takes cost tokens from the client bucket, it returns how long to wait
+// otherwise. The cost is expected to fit in the bucket.
+func (rl *rateLimiter) allow(key string, limit config.RouteLimit, cost int) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := nowFunc()
+	rl.sweep(now)
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(limit.Rate), max(limit.Burst, 1))}
+		rl.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	r := b.limiter.ReserveN(now, cost)
+	if !r.OK() {
+		return false, time.Duration(float64(b.limiter.Burst()) / limit.Rate * float64(time.Second))
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep drops the buckets unused for the idle timeout, at most once per idle
+// timeout. The lock must be held.
+func (rl *rateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rl.idleTimeout {
+		return
+	}
+	rl.lastSweep = now
+	for key, b := range rl.buckets {
+		if now.Sub(b.lastSeen) >= rl.idleTimeout {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// clientIP returns the request client IP, X-Forwarded-For is walked from the
+// closest hop only while the hops are trusted proxies.
+func (rl *rateLimiter) clientIP(req *http.Request) string {
+	ip := remoteIP(req.RemoteAddr)
+	if !rl.isTrusted(ip) {
+		return ip
+	}
+
+	var hops []string
+	for _, header := range req.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !rl.isTrusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func (rl *rateLimiter) isTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range rl.trusted {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// unitCost takes one token per request.
+func unitCost(*http.Request) int {
+	return 1
+}
+
+// cityCost takes one token per city on the city query param.
+func cityCost(req *http.Request) int {
+	if cities := req.URL.Query().Get("city"); cities != "" {
+		if params, err := parseCities(cities); err == nil {
+			return max(len(params), 1)
+		}
+	}
+	return 1
+}
+
+// locationsCost takes one token per location of the POST /weather body.
+func (rl *rateLimiter) locationsCost(req *http.Request) int {
+	var body model.ForecastRequest
+	if !rl.peekBody(req, &body) {
+		return 1
+	}
+	return max(len(body.Locations), 1)
+}
+
+// legsCost takes one token per leg of the POST /weather/itinerary body.
+func (rl *rateLimiter) legsCost(req *http.Request) int {
+	var body model.ItineraryRequest
+	if !rl.peekBody(req, &body) {
+		return 1
+	}
+	return max(len(body.Legs), 1)
+}
+
+// peekBody decodes the request body into v and puts it back for the handler,
+// it reports false when the body can't be decoded.
+func (rl *rateLimiter) peekBody(req *http.Request, v any) bool {
+	if req.Body == nil || rl.maxBodyBytes <= 0 {
+		return false
+	}
+
+	bs, err := io.ReadAll(io.LimitReader(req.Body, rl.maxBodyBytes+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(bs), req.Body), req.Body}
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(bs, v) == nil
+}
+
+// remoteIP strips the port of a request remote address.
+func remoteIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
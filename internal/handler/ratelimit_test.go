@@ -0,0 +1,148 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIP(t *testing.T) {
+	rl := newRateLimiter(config.APIConfig{RateLimit: config.RateLimitConfig{
+		TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"},
+	}})
+	tcs := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		wantIP       string
+	}{
+		{
+			name:       "when no proxy should use remote address",
+			remoteAddr: "203.0.113.7:5000",
+			wantIP:     "203.0.113.7",
+		},
+		{
+			name:         "when untrusted remote should ignore X-Forwarded-For",
+			remoteAddr:   "203.0.113.7:5000",
+			forwardedFor: "198.51.100.1",
+			wantIP:       "203.0.113.7",
+		},
+		{
+			name:         "when trusted proxy should use forwarded client",
+			remoteAddr:   "10.0.0.2:5000",
+			forwardedFor: "198.51.100.1",
+			wantIP:       "198.51.100.1",
+		},
+		{
+			name:         "when chained trusted proxies should skip them",
+			remoteAddr:   "10.0.0.2:5000",
+			forwardedFor: "1.2.3.4, 198.51.100.1, 192.168.1.1",
+			wantIP:       "198.51.100.1",
+		},
+		{
+			name:         "when forwarded hop is invalid should stop at the last valid one",
+			remoteAddr:   "10.0.0.2:5000",
+			forwardedFor: "unknown",
+			wantIP:       "10.0.0.2",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/weather", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			}
+			require.Equal(t, tc.wantIP, rl.clientIP(req))
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+
+	rl := newRateLimiter(config.APIConfig{
+		MaxBodyBytes: 1024,
+		RateLimit: config.RateLimitConfig{
+			IdleTimeout: 60,
+			Default:     config.RouteLimit{Rate: 1, Burst: 4},
+			Routes: map[string]config.RouteLimit{
+				"/alerts": {},
+			},
+		},
+	})
+	var served int
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		served++
+	})
+	weather := rl.Limit("/weather", cityCost)(next)
+
+	serve := func(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+		req.RemoteAddr = "203.0.113.7:5000"
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// five cities cost more than a full bucket, they can never be allowed so
+	// no retry is suggested
+	recorder := serve(weather, httptest.NewRequest(http.MethodGet, "/weather?city=london,paris,rome,oslo,lima", nil))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Empty(t, recorder.Header().Get("Retry-After"))
+	require.Zero(t, served)
+
+	// three cities cost three tokens, the rejected request took none
+	recorder = serve(weather, httptest.NewRequest(http.MethodGet, "/weather?city=london,paris,rome", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// the POST body locations are counted and kept for the handler
+	body := `{"locations":[{"name":"london"},{"name":"paris"}]}`
+	recorder = serve(rl.Limit("/weather", rl.locationsCost)(next), httptest.NewRequest(http.MethodPost, "/weather", strings.NewReader(body)))
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "1", recorder.Header().Get("Retry-After"))
+
+	fakeTime = fakeTime.Add(time.Second)
+	var got string
+	recorder = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/weather", strings.NewReader(body))
+	req.RemoteAddr = "203.0.113.7:5000"
+	rl.Limit("/weather", rl.locationsCost)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		bs, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		got = string(bs)
+	})).ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, body, got)
+
+	// the itinerary legs are counted on the same route bucket
+	fakeTime = fakeTime.Add(2 * time.Second)
+	legs := `{"legs":[{"location":{"name":"london"}},{"location":{"name":"paris"}},{"location":{"name":"rome"}}]}`
+	recorder = serve(rl.Limit("/weather", rl.legsCost)(next), httptest.NewRequest(http.MethodPost, "/weather/itinerary", strings.NewReader(legs)))
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "1", recorder.Header().Get("Retry-After"))
+
+	// routes without rate are unlimited
+	alerts := rl.Limit("/alerts", cityCost)(next)
+	for i := 0; i < 10; i++ {
+		require.Equal(t, http.StatusOK, serve(alerts, httptest.NewRequest(http.MethodGet, "/alerts?city=london", nil)).Code)
+	}
+
+	// idle buckets are dropped
+	require.Len(t, rl.buckets, 1)
+	fakeTime = fakeTime.Add(2 * time.Minute)
+	serve(rl.Limit("/current", cityCost)(next), httptest.NewRequest(http.MethodGet, "/current?city=london", nil))
+	require.Len(t, rl.buckets, 1)
+	require.Contains(t, rl.buckets, "/current|203.0.113.7")
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	v1 "github.com/dibrito/ennismore-weather-app/pkg/api/v1"
+	v2 "github.com/dibrito/ennismore-weather-app/pkg/api/v2"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+)
+
+// apiVersion maps the controller models into the response shapes of an API
+// version, so the models can change without breaking the released versions.
+type apiVersion struct {
+	prefix   string
+	forecast func(model.WeatherForecast) any
+	// stream maps a single forecast of streamed responses.
+	stream  func(model.Forecast) any
+	alerts  func(model.WeatherAlerts) any
+	current func(model.CurrentConditions) any
+	grid    func(model.GridForecast) any
+}
+
+var apiV1 = apiVersion{
+	prefix:   "/v1",
+	forecast: func(m model.WeatherForecast) any { return v1.NewWeatherForecast(m) },
+	stream:   func(f model.Forecast) any { return v1.NewForecast(f) },
+	alerts:   func(m model.WeatherAlerts) any { return v1.NewWeatherAlerts(m) },
+	current:  func(m model.CurrentConditions) any { return v1.NewCurrentConditions(m) },
+	grid:     func(m model.GridForecast) any { return v1.NewGridForecast(m) },
+}
+
+var apiV2 = apiVersion{
+	prefix:   "/v2",
+	forecast: func(m model.WeatherForecast) any { return v2.NewWeatherForecast(m) },
+	stream:   func(f model.Forecast) any { return v2.NewForecast(f) },
+	alerts:   func(m model.WeatherAlerts) any { return v2.NewWeatherAlerts(m) },
+	current:  func(m model.CurrentConditions) any { return v2.NewCurrentConditions(m) },
+	grid:     func(m model.GridForecast) any { return v2.NewGridForecast(m) },
+}
+
+type versionCtxKey struct{}
+
+// withVersion puts the API version into the request context.
+func withVersion(v apiVersion) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionCtxKey{}, v)))
+		})
+	}
+}
+
+// versionFromContext returns the requested API version, v1 by default.
+func versionFromContext(ctx context.Context) apiVersion {
+	if v, ok := ctx.Value(versionCtxKey{}).(apiVersion); ok {
+		return v
+	}
+	return apiV1
+}
+
+// deprecated marks the unversioned routes as deprecated aliases of /v1 with
+// the Deprecation (RFC 9745) and Sunset (RFC 8594) headers, the /v1 route is
+// linked as the successor version.
+func deprecated(cfg config.DeprecationConfig) func(http.Handler) http.Handler {
+	// the dates are checked when the configuration is loaded
+	deprecation, sunset, _ := cfg.Dates()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !deprecation.IsZero() {
+				w.Header().Set("Deprecation", fmt.Sprintf("@%d", deprecation.Unix()))
+			}
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.Format(http.TimeFormat))
+			}
+			w.Header().Add("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, apiV1.prefix, r.URL.Path))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
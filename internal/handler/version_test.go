@@ -0,0 +1,96 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	controllerMock "github.com/dibrito/ennismore-weather-app/gen/mock/controller"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestVersionedRoutes(t *testing.T) {
+	start := time.Date(2024, 9, 23, 6, 0, 0, 0, time.UTC)
+	temperature, precipitation := 18.0, 40.0
+	forecast := model.WeatherForecast{Forecast: []model.Forecast{{
+		Name: "london",
+		Detail: []model.Detail{{
+			StartTime:                start,
+			EndTime:                  start.Add(12 * time.Hour),
+			Description:              "gray",
+			Temperature:              &temperature,
+			TemperatureUnit:          "C",
+			PrecipitationProbability: &precipitation,
+		}},
+	}}}
+
+	tcs := []struct {
+		name           string
+		target         string
+		wantBody       string
+		wantDeprecated bool
+	}{
+		{
+			name:     "when v1 should keep the frozen shape",
+			target:   "/v1/weather?city=london",
+			wantBody: `{"forecast":[{"name":"london","detail":[{"startTime":"2024-09-23T06:00:00Z","endTime":"2024-09-23T18:00:00Z","description":"gray","temperature":18,"temperatureUnit":"C"}]}]}`,
+		},
+		{
+			name:     "when v2 should return the structured shape",
+			target:   "/v2/weather?city=london",
+			wantBody: `{"forecasts":[{"location":{"name":"london"},"periods":[{"start":"2024-09-23T06:00:00Z","end":"2024-09-23T18:00:00Z","description":"gray","temperature":{"value":18,"unit":"degC"},"precipitationProbability":{"value":40,"unit":"%"}}],"alerts":[],"stale":false}]}`,
+		},
+		{
+			name:           "when unversioned should alias v1 and be deprecated",
+			target:         "/weather?city=london",
+			wantBody:       `{"forecast":[{"name":"london","detail":[{"startTime":"2024-09-23T06:00:00Z","endTime":"2024-09-23T18:00:00Z","description":"gray","temperature":18,"temperatureUnit":"C"}]}]}`,
+			wantDeprecated: true,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			ctrlMock := controllerMock.NewMockServiceController(ctrl)
+			ctrlMock.EXPECT().GetForecast(gomock.Any(), []string{"london"}, gomock.Any()).Return(forecast, nil)
+
+			cfg := apiConfig
+			cfg.Deprecation = config.DeprecationConfig{Date: "2026-10-19", Sunset: "2027-04-30"}
+			routes := New(ctrlMock, cfg).Routes(zaptest.NewLogger(t), Modules{
+				Live:  http.NotFoundHandler(),
+				Rules: http.NotFoundHandler(),
+			})
+
+			recorder := httptest.NewRecorder()
+			routes.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			require.Equal(t, http.StatusOK, recorder.Code)
+			require.JSONEq(t, tc.wantBody, recorder.Body.String())
+
+			if !tc.wantDeprecated {
+				require.Empty(t, recorder.Header().Get("Deprecation"))
+				require.Empty(t, recorder.Header().Get("Sunset"))
+				return
+			}
+			require.Equal(t, "@1792368000", recorder.Header().Get("Deprecation"))
+			require.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", recorder.Header().Get("Sunset"))
+			require.Equal(t, `</v1/weather>; rel="successor-version"`, recorder.Header().Get("Link"))
+		})
+	}
+}
+
+func TestVersionedNullLists(t *testing.T) {
+	// v1 keeps sending null when nothing was found, v2 sends empty lists
+	v1, err := json.Marshal(apiV1.alerts(model.WeatherAlerts{}))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"alerts":null}`, string(v1))
+
+	v2, err := json.Marshal(apiV2.alerts(model.WeatherAlerts{Alerts: []model.CityAlerts{{Name: "paris"}}}))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"alerts":[{"location":{"name":"paris"},"alerts":[]}]}`, string(v2))
+}
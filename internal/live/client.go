@@ -0,0 +1,172 @@
+package live
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/dibrito/ennismore-weather-app/pkg/api/v1"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	// writeWait is the time allowed to write a message to the client.
+	writeWait = 10 * time.Second
+	// maxMessageSize is the maximum size of a client message.
+	maxMessageSize = 4096
+)
+
+// client is the hub side of a WebSocket connection, messages are queued on
+// send and written by the write pump so a slow client never blocks the hub.
+type client struct {
+	hub  *Hub
+	conn *websocket.Conn
+	send chan []byte
+	// cities are the client subscriptions, guarded by the hub mutex.
+	cities map[string]struct{}
+
+	closeOnce sync.Once
+	done      chan struct{}
+	closeMsg  []byte
+}
+
+func newClient(h *Hub, conn *websocket.Conn) *client {
+	return &client{
+		hub:    h,
+		conn:   conn,
+		send:   make(chan []byte, h.cfg.SendBuffer),
+		cities: make(map[string]struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// enqueue queues a message without blocking, a client whose send buffer is
+// full is not keeping up and gets disconnected.
+func (c *client) enqueue(msg []byte) {
+	select {
+	case c.send <- msg:
+	case <-c.done:
+	default:
+		c.hub.logger.Warn("live client send buffer full, disconnecting",
+			zap.String("remote", c.conn.RemoteAddr().String()))
+		c.close(websocket.ClosePolicyViolation, "send buffer full")
+	}
+}
+
+// close asks the write pump to send a close frame and close the connection.
+func (c *client) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeMsg = websocket.FormatCloseMessage(code, reason)
+		close(c.done)
+	})
+}
+
+// readPump reads the subscription requests, the read deadline is extended
+// every time the client answers a ping.
+func (c *client) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.close(websocket.CloseNormalClosure, "")
+	}()
+
+	pongWait := 2 * time.Duration(c.hub.cfg.PingInterval) * time.Second
+	c.conn.SetReadLimit(maxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.hub.logger.Info("live client read failed", zap.Error(err))
+			}
+			return
+		}
+
+		var req model.LiveRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.sendError("invalid message")
+			continue
+		}
+		cities, ok := cleanCities(req.Cities)
+		if !ok {
+			c.sendError("invalid city")
+			continue
+		}
+		switch req.Action {
+		case "subscribe":
+			if c.subscriptions()+len(cities) > c.hub.cfg.MaxCities {
+				c.sendError("too many cities")
+				continue
+			}
+			c.hub.subscribe(c, cities)
+		case "unsubscribe":
+			c.hub.unsubscribe(c, cities)
+		default:
+			c.sendError("unsupported action")
+		}
+	}
+}
+
+// cleanCities trims the requested city names, ok is false when one of them is
+// empty so nobody subscribes to a city without name.
+func cleanCities(cities []string) ([]string, bool) {
+	cleaned := make([]string, 0, len(cities))
+	for _, city := range cities {
+		city = strings.TrimSpace(city)
+		if city == "" {
+			return nil, false
+		}
+		cleaned = append(cleaned, city)
+	}
+	return cleaned, true
+}
+
+// writePump writes the queued messages and pings the client, it owns the
+// connection and closes it once the client is done.
+func (c *client) writePump() {
+	ticker := time.NewTicker(time.Duration(c.hub.cfg.PingInterval) * time.Second)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.done:
+			_ = c.conn.WriteControl(websocket.CloseMessage, c.closeMsg, time.Now().Add(writeWait))
+			return
+		}
+	}
+}
+
+// subscriptions returns the number of cities the client is subscribed to.
+func (c *client) subscriptions() int {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+
+	return len(c.cities)
+}
+
+// sendError queues an error message for the client.
+func (c *client) sendError(msg string) {
+	bs, _ := json.Marshal(v1.LiveMessage{Type: "error", Error: msg})
+	c.enqueue(bs)
+}
@@ -0,0 +1,258 @@
+package live
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	"github.com/dibrito/ennismore-weather-app/internal/controller"
+	v1 "github.com/dibrito/ennismore-weather-app/pkg/api/v1"
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/dibrito/ennismore-weather-app/pkg/problem"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// ForecastProvider defines how the hub retrieves the city forecasts.
+type ForecastProvider interface {
+	GetForecast(ctx context.Context, cities []string, opts controller.ForecastOptions) (model.WeatherForecast, error)
+}
+
+// lastForecast holds the last forecast message pushed for a city.
+type lastForecast struct {
+	hash [sha256.Size]byte
+	msg  []byte
+}
+
+// Hub keeps track of the WebSocket clients and their subscriptions, a
+// background refresher pushes a message to the subscribers whenever the
+// forecast or the active alerts of a city change.
+type Hub struct {
+	provider ForecastProvider
+	cfg      config.LiveConfig
+	logger   *zap.Logger
+	upgrader websocket.Upgrader
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu          sync.Mutex
+	closed      bool
+	clients     map[*client]struct{}
+	subscribers map[string]map[*client]struct{}
+	last        map[string]lastForecast
+}
+
+// New creates a live updates hub, browsers may only connect from the API
+// origin or the CORS allowed origins.
+func New(provider ForecastProvider, cfg config.LiveConfig, cors config.CORSConfig, logger *zap.Logger) *Hub {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Hub{
+		provider: provider,
+		cfg:      cfg,
+		logger:   logger,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				// only browsers send an origin
+				return origin == "" || sameOrigin(origin, r.Host) || cors.AllowsOrigin(origin)
+			},
+			Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+				problem.Error(w, r, status, reason.Error())
+			},
+		},
+		ctx:         context.WithValue(ctx, logging.LoggetCtxKey{}, logger),
+		cancel:      cancel,
+		clients:     make(map[*client]struct{}),
+		subscribers: make(map[string]map[*client]struct{}),
+		last:        make(map[string]lastForecast),
+	}
+}
+
+// ServeHTTP handles GET /ws requests upgrading them to WebSocket connections.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		// the upgrader already replied to the client
+		h.logger.Info("unable to upgrade connection", zap.Error(err))
+		return
+	}
+
+	c := newClient(h, conn)
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		c.close(websocket.CloseGoingAway, "server shutting down")
+		return
+	}
+	h.clients[c] = struct{}{}
+	// added under the lock so Shutdown never waits while clients are added
+	h.wg.Add(2)
+	h.mu.Unlock()
+
+	go func() {
+		defer h.wg.Done()
+		c.writePump()
+	}()
+	go func() {
+		defer h.wg.Done()
+		c.readPump()
+	}()
+}
+
+// Run refreshes the subscribed cities every refresh interval until the hub
+// is shut down.
+func (h *Hub) Run() {
+	ticker := time.NewTicker(time.Duration(h.cfg.RefreshInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, city := range h.subscribedCities() {
+				h.refreshCity(city)
+			}
+		case <-h.ctx.Done():
+			return
+		}
+	}
+}
+
+// Shutdown closes every client connection and waits for them to finish, it
+// is meant to be registered with http.Server.RegisterOnShutdown since
+// hijacked connections are not tracked by the server.
+func (h *Hub) Shutdown() {
+	h.mu.Lock()
+	h.closed = true
+	clients := make([]*client, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+
+	h.cancel()
+	for _, c := range clients {
+		c.close(websocket.CloseGoingAway, "server shutting down")
+	}
+	h.wg.Wait()
+	h.logger.Info("live updates hub stopped", zap.Int("clients", len(clients)))
+}
+
+// subscribe adds the cities to the client subscriptions, the last known
+// forecast is sent right away or fetched when the city is new to the hub.
+func (h *Hub) subscribe(c *client, cities []string) {
+	var missing []string
+	h.mu.Lock()
+	for _, city := range cities {
+		if _, ok := h.subscribers[city]; !ok {
+			h.subscribers[city] = make(map[*client]struct{})
+		}
+		h.subscribers[city][c] = struct{}{}
+		c.cities[city] = struct{}{}
+
+		if last, ok := h.last[city]; ok {
+			c.enqueue(last.msg)
+			continue
+		}
+		missing = append(missing, city)
+	}
+	if h.closed {
+		missing = nil
+	}
+	h.wg.Add(len(missing))
+	h.mu.Unlock()
+
+	for _, city := range missing {
+		go func(city string) {
+			defer h.wg.Done()
+			h.refreshCity(city)
+		}(city)
+	}
+}
+
+// unsubscribe removes the cities from the client subscriptions.
+func (h *Hub) unsubscribe(c *client, cities []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, city := range cities {
+		delete(c.cities, city)
+		delete(h.subscribers[city], c)
+		if len(h.subscribers[city]) == 0 {
+			delete(h.subscribers, city)
+			delete(h.last, city)
+		}
+	}
+}
+
+// unregister removes the client and all of its subscriptions.
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	cities := make([]string, 0, len(c.cities))
+	for city := range c.cities {
+		cities = append(cities, city)
+	}
+	delete(h.clients, c)
+	h.mu.Unlock()
+
+	h.unsubscribe(c, cities)
+}
+
+// subscribedCities returns the cities with at least one subscriber.
+func (h *Hub) subscribedCities() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cities := make([]string, 0, len(h.subscribers))
+	for city := range h.subscribers {
+		cities = append(cities, city)
+	}
+	return cities
+}
+
+// refreshCity fetches the city forecast with its active alerts and pushes it
+// to the subscribers when it differs from the last pushed forecast.
+func (h *Hub) refreshCity(city string) {
+	forecast, err := h.provider.GetForecast(h.ctx, []string{city}, controller.ForecastOptions{IncludeAlerts: true})
+	if err != nil || len(forecast.Forecast) == 0 {
+		h.logger.Warn("unable to refresh live forecast",
+			zap.String("location", city),
+			zap.Error(err))
+		return
+	}
+
+	// /ws is unversioned, it pushes the v1 forecast like the unversioned routes
+	f := v1.NewForecast(forecast.Forecast[0])
+	msg, err := json.Marshal(v1.LiveMessage{Type: "forecast", Forecast: &f})
+	if err != nil {
+		h.logger.Error("unable to parse live forecast", zap.Error(err))
+		return
+	}
+	hash := sha256.Sum256(msg)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subscribers, ok := h.subscribers[city]
+	if !ok || h.last[city].hash == hash {
+		// nobody is listening anymore or nothing changed
+		return
+	}
+	h.last[city] = lastForecast{hash: hash, msg: msg}
+	for c := range subscribers {
+		c.enqueue(msg)
+	}
+}
+
+// sameOrigin reports whether the origin is the host the request was sent to.
+func sameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, host)
+}
@@ -0,0 +1,162 @@
+package live
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	controllerMock "github.com/dibrito/ennismore-weather-app/gen/mock/controller"
+	"github.com/dibrito/ennismore-weather-app/internal/controller"
+	v1 "github.com/dibrito/ennismore-weather-app/pkg/api/v1"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestHub(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	providerMock := controllerMock.NewMockServiceController(ctrl)
+
+	var mu sync.Mutex
+	description := "gray"
+	providerMock.EXPECT().GetForecast(gomock.Any(), []string{"london"}, controller.ForecastOptions{IncludeAlerts: true}).
+		DoAndReturn(func(_, _, _ any) (model.WeatherForecast, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return model.WeatherForecast{
+				Forecast: []model.Forecast{{Name: "london", Detail: []model.Detail{{Description: description, Summary: "Cloudy"}}, Cache: controller.CacheHit}},
+			}, nil
+		}).AnyTimes()
+
+	hub := New(providerMock, config.LiveConfig{
+		RefreshInterval: 3600,
+		PingInterval:    30,
+		SendBuffer:      4,
+		MaxCities:       2,
+	}, config.CORSConfig{}, zaptest.NewLogger(t))
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	readMessage := func() v1.LiveMessage {
+		var msg v1.LiveMessage
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		require.NoError(t, conn.ReadJSON(&msg))
+		return msg
+	}
+
+	// subscribing sends the current forecast in its v1 shape
+	require.NoError(t, conn.WriteJSON(model.LiveRequest{Action: "subscribe", Cities: []string{"london"}}))
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, raw, err := conn.ReadMessage()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"forecast","forecast":{"name":"london","detail":[{"startTime":"0001-01-01T00:00:00Z","endTime":"0001-01-01T00:00:00Z","description":"gray"}]}}`, string(raw))
+
+	// a changed forecast is pushed
+	mu.Lock()
+	description = "sunny"
+	mu.Unlock()
+	hub.refreshCity("london")
+	msg := readMessage()
+	require.Equal(t, "sunny", msg.Forecast.Detail[0].Description)
+
+	// an unchanged forecast is not pushed, the next message is the error
+	hub.refreshCity("london")
+	require.NoError(t, conn.WriteJSON(model.LiveRequest{Action: "subscribe", Cities: []string{"paris", "rome"}}))
+	msg = readMessage()
+	require.Equal(t, v1.LiveMessage{Type: "error", Error: "too many cities"}, msg)
+
+	// cities without name are never subscribed
+	require.NoError(t, conn.WriteJSON(model.LiveRequest{Action: "subscribe", Cities: []string{" "}}))
+	msg = readMessage()
+	require.Equal(t, v1.LiveMessage{Type: "error", Error: "invalid city"}, msg)
+	require.ElementsMatch(t, []string{"london"}, hub.subscribedCities())
+
+	// shutdown closes the connection with going away
+	hub.Shutdown()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+}
+
+func TestHubDisconnectsSlowClient(t *testing.T) {
+	hub := New(nil, config.LiveConfig{PingInterval: 30, SendBuffer: 1}, config.CORSConfig{}, zaptest.NewLogger(t))
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// wait for the client to be registered and fill its send buffer
+	require.Eventually(t, func() bool {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		return len(hub.clients) == 1
+	}, time.Second, 10*time.Millisecond)
+	hub.mu.Lock()
+	for c := range hub.clients {
+		for i := 0; i < 100; i++ {
+			c.enqueue([]byte(`{"type":"forecast"}`))
+		}
+	}
+	hub.mu.Unlock()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	for {
+		_, _, err = conn.ReadMessage()
+		if err != nil {
+			break
+		}
+	}
+	require.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), err)
+	hub.Shutdown()
+}
+
+func TestHubCheckOrigin(t *testing.T) {
+	hub := New(nil, config.LiveConfig{PingInterval: 30, SendBuffer: 1}, config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"},
+	}, zaptest.NewLogger(t))
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+	defer hub.Shutdown()
+
+	tcs := []struct {
+		name   string
+		origin string
+		want   bool
+	}{
+		{name: "when no origin should connect", want: true},
+		{name: "when same origin should connect", origin: srv.URL, want: true},
+		{name: "when allowed origin should connect", origin: "https://app.example.com", want: true},
+		{name: "when wildcard origin should connect", origin: "https://maps.example.org", want: true},
+		{name: "when other origin should be forbidden", origin: "https://evil.example.net"},
+		{name: "when wildcard without subdomain should be forbidden", origin: "https://example.org"},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			if tc.origin != "" {
+				header.Set("Origin", tc.origin)
+			}
+			conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), header)
+			if !tc.want {
+				require.Error(t, err)
+				require.Equal(t, http.StatusForbidden, resp.StatusCode)
+				return
+			}
+			require.NoError(t, err)
+			conn.Close()
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package respository
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/pkg/geo"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+)
+
+// cache entry kinds reported on the cache keys.
+const (
+	KindLocation = "location"
+	KindPeriods  = "periods"
+	KindAlerts   = "alerts"
+	KindStation  = "station"
+)
+
+// Keys lists every cached location key sorted, with what is cached under it
+// and its age.
+func (r *repository) Keys() []model.CacheKey {
+	r.RLock()
+	defer r.RUnlock()
+
+	now := nowFunc()
+	keys := r.keys()
+	result := make([]model.CacheKey, 0, len(keys))
+	for _, key := range keys {
+		ck := model.CacheKey{Key: key, Kinds: []string{}}
+		if _, ok := r.Location[key]; ok {
+			ck.Kinds = append(ck.Kinds, KindLocation)
+		}
+		if periods, ok := r.Periods[key]; ok && len(periods) > 0 {
+			ck.Kinds = append(ck.Kinds, KindPeriods)
+			var oldest time.Time
+			for _, p := range periods {
+				if oldest.IsZero() || p.fetchedAt.Before(oldest) {
+					oldest = p.fetchedAt
+				}
+			}
+			age := int64(now.Sub(oldest).Seconds())
+			ttl := int64(r.forecastTTL.Seconds()) - age
+			ck.Age, ck.TTL = &age, &ttl
+		}
+		if entry, ok := r.Alerts[key]; ok && now.Before(entry.expiresAt) {
+			ck.Kinds = append(ck.Kinds, KindAlerts)
+			ttl := int64(entry.expiresAt.Sub(now).Seconds())
+			ck.AlertTTL = &ttl
+		}
+		if _, ok := r.Stations[key]; ok {
+			ck.Kinds = append(ck.Kinds, KindStation)
+		}
+		result = append(result, ck)
+	}
+	return result
+}
+
+// Entry returns a copy of everything cached under a location key.
+func (r *repository) Entry(key string) (model.CacheEntry, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if !r.has(key) {
+		return model.CacheEntry{}, false
+	}
+	return r.entry(key), true
+}
+
+// Entries returns a copy of every cache entry sorted by key.
+func (r *repository) Entries() []model.CacheEntry {
+	r.RLock()
+	defer r.RUnlock()
+
+	keys := r.keys()
+	entries := make([]model.CacheEntry, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, r.entry(key))
+	}
+	return entries
+}
+
+// Delete removes everything cached under a location key.
+func (r *repository) Delete(key string) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	ok := r.has(key)
+	r.delete(key)
+	return ok
+}
+
+// DeletePrefix removes every location key starting with prefix, it returns
+// how many keys were removed.
+func (r *repository) DeletePrefix(prefix string) int {
+	r.Lock()
+	defer r.Unlock()
+
+	var deleted int
+	for _, key := range r.keys() {
+		if strings.HasPrefix(key, prefix) {
+			r.delete(key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Flush removes every cached entry, it returns how many keys were removed.
+func (r *repository) Flush() int {
+	r.Lock()
+	defer r.Unlock()
+
+	deleted := len(r.keys())
+	r.Location = make(map[string]model.Location)
+	r.locations = geo.NewIndex()
+	r.Periods = make(map[string]map[string]periodEntry)
+	r.Alerts = make(map[string]alertsEntry)
+	r.Stations = make(map[string]model.Station)
+	return deleted
+}
+
+// Import stores the given entries keeping their fetch and expiration times,
+// an imported entry replaces whatever was cached under its key.
+func (r *repository) Import(entries []model.CacheEntry) {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, e := range entries {
+		r.delete(e.Key)
+		if e.Location != nil {
+			r.putLocation(e.Key, copyLocation(*e.Location))
+		}
+		if e.Station != nil {
+			r.Stations[e.Key] = *e.Station
+		}
+		if len(e.Periods) > 0 {
+			periods := make(map[string]periodEntry, len(e.Periods))
+			for startTime, p := range e.Periods {
+				periods[startTime] = periodEntry{period: copyPeriod(p.Period), fetchedAt: p.FetchedAt}
+			}
+			r.Periods[e.Key] = periods
+		}
+		if e.AlertsExpiresAt != nil {
+			r.Alerts[e.Key] = alertsEntry{
+				alerts:    append([]model.Alert(nil), e.Alerts...),
+				expiresAt: *e.AlertsExpiresAt,
+			}
+		}
+	}
+}
+
+// keys returns every cached location key sorted, the lock must be held.
+func (r *repository) keys() []string {
+	set := make(map[string]struct{})
+	for key := range r.Location {
+		set[key] = struct{}{}
+	}
+	for key := range r.Periods {
+		set[key] = struct{}{}
+	}
+	for key := range r.Alerts {
+		set[key] = struct{}{}
+	}
+	for key := range r.Stations {
+		set[key] = struct{}{}
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// has reports whether anything is cached under key, the lock must be held.
+func (r *repository) has(key string) bool {
+	_, location := r.Location[key]
+	_, periods := r.Periods[key]
+	_, alerts := r.Alerts[key]
+	_, station := r.Stations[key]
+	return location || periods || alerts || station
+}
+
+// entry copies everything cached under key, the lock must be held.
+func (r *repository) entry(key string) model.CacheEntry {
+	e := model.CacheEntry{Key: key}
+	if location, ok := r.Location[key]; ok {
+		location = copyLocation(location)
+		e.Location = &location
+	}
+	if station, ok := r.Stations[key]; ok {
+		e.Station = &station
+	}
+	if periods, ok := r.Periods[key]; ok {
+		e.Periods = make(map[string]model.PeriodEntry, len(periods))
+		for startTime, p := range periods {
+			e.Periods[startTime] = model.PeriodEntry{Period: copyPeriod(p.period), FetchedAt: p.fetchedAt}
+		}
+	}
+	if entry, ok := r.Alerts[key]; ok {
+		e.Alerts = append([]model.Alert(nil), entry.alerts...)
+		expiresAt := entry.expiresAt
+		e.AlertsExpiresAt = &expiresAt
+	}
+	return e
+}
+
+// delete removes everything cached under key, the lock must be held.
+func (r *repository) delete(key string) {
+	delete(r.Location, key)
+	r.locations.Delete(key)
+	delete(r.Periods, key)
+	delete(r.Alerts, key)
+	delete(r.Stations, key)
+}
+
+// copyPeriod deep copies a period so the copy doesn't share its pointers.
+func copyPeriod(p model.Period) model.Period {
+	if p.Temperature != nil {
+		temperature := *p.Temperature
+		p.Temperature = &temperature
+	}
+	if p.ProbabilityOfPrecipitation.Value != nil {
+		value := *p.ProbabilityOfPrecipitation.Value
+		p.ProbabilityOfPrecipitation.Value = &value
+	}
+	return p
+}
+
+// copyLocation deep copies a location so the copy doesn't share its bounding
+// box.
+func copyLocation(l model.Location) model.Location {
+	l.BoundingBox = append([]string(nil), l.BoundingBox...)
+	return l
+}
@@ -2,30 +2,91 @@ package respository
 
 import (
 	"sync"
+	"time"
 
+	"github.com/dibrito/ennismore-weather-app/pkg/geo"
 	"github.com/dibrito/ennismore-weather-app/pkg/model"
 )
 
+// nowFunc will get the now time when checking alerts and periods expiration.
+var nowFunc = time.Now
+
 type Repository interface {
 	GetLocation(city string) (model.Location, bool)
 	PutLocation(city string, location model.Location)
-	GetPeriods(city, startTime string) (model.Period, bool)
+	SearchLocations(box geo.BBox) []string
+	NearestLocations(p geo.Point, radius float64, limit int) []geo.Neighbor
+	GetPeriods(city, startTime string) (CachedPeriod, bool)
 	PutPeriods(city, startTime string, periods model.Period)
-	GetCache() model.CacheResponse
+	GetAlerts(city string) ([]model.Alert, bool)
+	PutAlerts(city string, alerts []model.Alert)
+	GetStation(city string) (model.Station, bool)
+	PutStation(city string, station model.Station)
+}
+
+// alertsEntry holds the cached alerts for a city until they expire.
+type alertsEntry struct {
+	alerts    []model.Alert
+	expiresAt time.Time
+}
+
+// periodEntry holds a cached forecast period and when it was fetched.
+type periodEntry struct {
+	period    model.Period
+	fetchedAt time.Time
+}
+
+// Freshness tells how a cached entry may be served.
+type Freshness int
+
+const (
+	// Fresh entries are within the TTL.
+	Fresh Freshness = iota
+	// Stale entries are past the TTL but within the stale-while-revalidate
+	// window, they are served while refreshed in background.
+	Stale
+	// StaleIfError entries are past the stale-while-revalidate window but
+	// within the max staleness, they are only served when the upstream fails.
+	StaleIfError
+)
+
+// CachedPeriod is a cached forecast period with its fetch time.
+type CachedPeriod struct {
+	Period    model.Period
+	FetchedAt time.Time
+	Freshness Freshness
 }
 
 // Repository defines a in-memory weather-app repository.
 type repository struct {
 	sync.RWMutex
 	Location map[string]model.Location
-	Periods  map[string]map[string]model.Period
+	Periods  map[string]map[string]periodEntry
+	Alerts   map[string]alertsEntry
+	Stations map[string]model.Station
+	// locations indexes the Location coordinates by city.
+	locations   *geo.Index
+	alertsTTL   time.Duration
+	forecastTTL time.Duration
+	// staleWhileRevalidate and maxStaleness extend the forecast TTL.
+	staleWhileRevalidate time.Duration
+	maxStaleness         time.Duration
 }
 
-// New creates a new memory repository.
-func New() *repository {
+// New creates a new memory repository, staleWhileRevalidate and maxStaleness
+// extend the forecast TTL.
+func New(forecastTTL, alertsTTL, staleWhileRevalidate, maxStaleness time.Duration) *repository {
 	return &repository{
-		Location: make(map[string]model.Location, 0),
-		Periods:  make(map[string]map[string]model.Period, 0),
+		Location:    make(map[string]model.Location, 0),
+		Periods:     make(map[string]map[string]periodEntry, 0),
+		Alerts:      make(map[string]alertsEntry, 0),
+		Stations:    make(map[string]model.Station, 0),
+		locations:   geo.NewIndex(),
+		alertsTTL:   alertsTTL,
+		forecastTTL: forecastTTL,
+
+		staleWhileRevalidate: staleWhileRevalidate,
+		maxStaleness:         maxStaleness,
 	}
 }
 
@@ -43,20 +104,67 @@ func (r *repository) PutLocation(city string, location model.Location) {
 	r.Lock()
 	defer r.Unlock()
 
+	r.putLocation(city, location)
+}
+
+// putLocation stores and indexes a Location, locations with invalid
+// coordinates are not indexed. The lock must be held.
+func (r *repository) putLocation(city string, location model.Location) {
 	r.Location[city] = location
+	if p, err := geo.ParsePoint(location.Lat, location.Lon); err == nil {
+		r.locations.Put(city, p)
+	} else {
+		r.locations.Delete(city)
+	}
+}
+
+// SearchLocations returns the cities of the cached locations within the box,
+// the closest to its center first.
+func (r *repository) SearchLocations(box geo.BBox) []string {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.locations.Search(box)
+}
+
+// NearestLocations returns the limit cached locations closest to p within
+// radius km, the closest first.
+func (r *repository) NearestLocations(p geo.Point, radius float64, limit int) []geo.Neighbor {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.locations.Nearest(p, radius, limit)
 }
 
-// GetPeriods retrieves the forecast periods by city name.
-func (r *repository) GetPeriods(city, startTime string) (model.Period, bool) {
+// GetPeriods retrieves the forecast periods by city name, periods fetched
+// longer than the forecast TTL ago are reported as stale until they are past
+// both the stale-while-revalidate window and the max staleness.
+func (r *repository) GetPeriods(city, startTime string) (CachedPeriod, bool) {
 	r.RLock()
 	defer r.RUnlock()
 
 	periods, ok := r.Periods[city]
 	if !ok {
-		return model.Period{}, ok
+		return CachedPeriod{}, ok
 	}
 	p, ok := periods[startTime]
-	return p, ok
+	if !ok {
+		return CachedPeriod{}, ok
+	}
+
+	cached := CachedPeriod{Period: p.period, FetchedAt: p.fetchedAt}
+	age := nowFunc().Sub(p.fetchedAt)
+	switch {
+	case age < r.forecastTTL:
+		cached.Freshness = Fresh
+	case age < r.forecastTTL+r.staleWhileRevalidate:
+		cached.Freshness = Stale
+	case age < r.forecastTTL+r.maxStaleness:
+		cached.Freshness = StaleIfError
+	default:
+		return CachedPeriod{}, false
+	}
+	return cached, true
 }
 
 // PutPeriods stores forecast periods for a city.
@@ -64,17 +172,51 @@ func (r *repository) PutPeriods(city, startTime string, periods model.Period) {
 	r.Lock()
 	defer r.Unlock()
 	if _, ok := r.Periods[city]; !ok {
-		r.Periods[city] = make(map[string]model.Period)
+		r.Periods[city] = make(map[string]periodEntry)
+	}
+	r.Periods[city][startTime] = periodEntry{
+		period:    periods,
+		fetchedAt: nowFunc(),
 	}
-	r.Periods[city][startTime] = periods
 }
 
-// GetLocation retrieves the Location by city name.
-func (r *repository) GetCache() model.CacheResponse {
+// GetAlerts retrieves the active alerts by city name, expired entries are
+// reported as not found.
+func (r *repository) GetAlerts(city string) ([]model.Alert, bool) {
 	r.RLock()
 	defer r.RUnlock()
-	return model.CacheResponse{
-		Location: r.Location,
-		Periods:  r.Periods,
+
+	entry, ok := r.Alerts[city]
+	if !ok || !nowFunc().Before(entry.expiresAt) {
+		return nil, false
 	}
+	return entry.alerts, true
+}
+
+// PutAlerts stores the active alerts for a city for the configured TTL.
+func (r *repository) PutAlerts(city string, alerts []model.Alert) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.Alerts[city] = alertsEntry{
+		alerts:    alerts,
+		expiresAt: nowFunc().Add(r.alertsTTL),
+	}
+}
+
+// GetStation retrieves the nearest observation station by city name.
+func (r *repository) GetStation(city string) (model.Station, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	station, ok := r.Stations[city]
+	return station, ok
+}
+
+// PutStation stores the nearest observation station for a city.
+func (r *repository) PutStation(city string, station model.Station) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.Stations[city] = station
 }
@@ -0,0 +1,258 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+
+	"github.com/dibrito/ennismore-weather-app/internal/auth"
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/dibrito/ennismore-weather-app/pkg/problem"
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+// maxWithinHours is the weather.gov forecast horizon.
+const maxWithinHours = 7 * 24
+
+// maxRuleBytes caps the rule request bodies.
+const maxRuleBytes = 4 << 10
+
+// Handler defines the rules CRUD HTTP handler, rules are scoped to the API
+// key that created them.
+type Handler struct {
+	store    Store
+	notifier *Notifier
+	// lookup resolves the webhook hosts.
+	lookup func(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// NewHandler creates a new rules HTTP handler.
+func NewHandler(store Store, notifier *Notifier) *Handler {
+	return &Handler{store, notifier, net.DefaultResolver.LookupNetIP}
+}
+
+// Routes defines the rules HTTP routes, meant to be mounted on /rules.
+func (h *Handler) Routes() http.Handler {
+	mux := chi.NewRouter()
+	mux.Post("/", h.Create)
+	mux.Get("/", h.List)
+	mux.Get("/deadletters", h.DeadLetters)
+	mux.Get("/{id}", h.Get)
+	mux.Put("/{id}", h.Update)
+	mux.Delete("/{id}", h.Delete)
+	return mux
+}
+
+// Create handles POST /rules requests.
+func (h *Handler) Create(w http.ResponseWriter, req *http.Request) {
+	rule, ok := h.decodeRule(w, req)
+	if !ok {
+		return
+	}
+	rule.Owner = owner(req)
+	rule.CreatedAt = nowFunc().UTC()
+
+	created, err := h.store.Create(rule)
+	if err != nil {
+		writeStoreError(w, req, err)
+		return
+	}
+	writeJSON(w, req, http.StatusCreated, created)
+}
+
+// List handles GET /rules requests.
+func (h *Handler) List(w http.ResponseWriter, req *http.Request) {
+	rules, err := h.store.List()
+	if err != nil {
+		writeStoreError(w, req, err)
+		return
+	}
+	owned := make([]model.Rule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Owner == owner(req) {
+			owned = append(owned, rule)
+		}
+	}
+	writeJSON(w, req, http.StatusOK, owned)
+}
+
+// Get handles GET /rules/{id} requests.
+func (h *Handler) Get(w http.ResponseWriter, req *http.Request) {
+	rule, err := h.get(req)
+	if err != nil {
+		writeStoreError(w, req, err)
+		return
+	}
+	writeJSON(w, req, http.StatusOK, rule)
+}
+
+// Update handles PUT /rules/{id} requests.
+func (h *Handler) Update(w http.ResponseWriter, req *http.Request) {
+	existing, err := h.get(req)
+	if err != nil {
+		writeStoreError(w, req, err)
+		return
+	}
+	rule, ok := h.decodeRule(w, req)
+	if !ok {
+		return
+	}
+	rule.ID = existing.ID
+	rule.Owner = existing.Owner
+	rule.CreatedAt = existing.CreatedAt
+
+	updated, err := h.store.Update(rule)
+	if err != nil {
+		writeStoreError(w, req, err)
+		return
+	}
+	writeJSON(w, req, http.StatusOK, updated)
+}
+
+// Delete handles DELETE /rules/{id} requests.
+func (h *Handler) Delete(w http.ResponseWriter, req *http.Request) {
+	rule, err := h.get(req)
+	if err != nil {
+		writeStoreError(w, req, err)
+		return
+	}
+	if err := h.store.Delete(rule.ID); err != nil {
+		writeStoreError(w, req, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeadLetters handles GET /rules/deadletters requests.
+func (h *Handler) DeadLetters(w http.ResponseWriter, req *http.Request) {
+	deadLetters := []model.DeadLetter{}
+	for _, d := range h.notifier.DeadLetters() {
+		if d.Owner == owner(req) {
+			deadLetters = append(deadLetters, d)
+		}
+	}
+	writeJSON(w, req, http.StatusOK, deadLetters)
+}
+
+// get retrieves the {id} rule, the rules of other API keys are not found.
+func (h *Handler) get(req *http.Request) (model.Rule, error) {
+	rule, err := h.store.Get(chi.URLParam(req, "id"))
+	if err != nil {
+		return model.Rule{}, err
+	}
+	if rule.Owner != owner(req) {
+		return model.Rule{}, ErrNotFound
+	}
+	return rule, nil
+}
+
+// owner returns the ID of the request API key, it is empty when auth is
+// disabled and every rule is then shared.
+func owner(req *http.Request) string {
+	key, _ := auth.KeyFromContext(req.Context())
+	return key.ID
+}
+
+// decodeRule reads and validates a rule from the request body, it replies
+// with BAD REQUEST when the rule is invalid and REQUEST ENTITY TOO LARGE when
+// the body exceeds maxRuleBytes.
+func (h *Handler) decodeRule(w http.ResponseWriter, req *http.Request) (model.Rule, bool) {
+	var rule model.Rule
+	decoder := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxRuleBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rule); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			problem.Error(w, req, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+			return rule, false
+		}
+		problem.Error(w, req, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return rule, false
+	}
+	errs := validateRule(rule)
+	if len(errs) == 0 {
+		errs = h.checkWebhook(req.Context(), rule.URL)
+	}
+	if len(errs) > 0 {
+		problem.Error(w, req, http.StatusBadRequest, "invalid rule", errs...)
+		return rule, false
+	}
+	return rule, true
+}
+
+// checkWebhook resolves the webhook host, every address must be public. The
+// notifier checks the address again on delivery, the host may be rebound.
+func (h *Handler) checkWebhook(ctx context.Context, rawURL string) []model.FieldError {
+	u, _ := url.Parse(rawURL)
+	addrs, err := h.lookup(ctx, "ip", u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return []model.FieldError{{Field: "url", Detail: "url host can't be resolved"}}
+	}
+	for _, addr := range addrs {
+		if !h.notifier.allowAddr(addr) {
+			return []model.FieldError{{Field: "url", Detail: "url must not point to a private, loopback or link-local address"}}
+		}
+	}
+	return nil
+}
+
+// validateRule checks the rule city, webhook URL and condition, it returns
+// every invalid field.
+func validateRule(r model.Rule) []model.FieldError {
+	var errs []model.FieldError
+	if r.City == "" {
+		errs = append(errs, model.FieldError{Field: "city", Detail: "city is required"})
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, model.FieldError{Field: "url", Detail: "url must be an absolute http(s) URL"})
+	}
+
+	c := r.Condition
+	switch c.Type {
+	case ConditionPrecipitation:
+		if c.Threshold < 0 || c.Threshold > 100 {
+			errs = append(errs, model.FieldError{Field: "condition.threshold", Detail: "threshold must be a percentage between 0 and 100"})
+		}
+		if c.WithinHours < 1 || c.WithinHours > maxWithinHours {
+			errs = append(errs, model.FieldError{Field: "condition.withinHours", Detail: fmt.Sprintf("withinHours must be between 1 and %d", maxWithinHours)})
+		}
+	case ConditionAlert:
+		if _, ok := severityRank[c.Severity]; !ok {
+			errs = append(errs, model.FieldError{Field: "condition.severity", Detail: "severity must be one of Minor, Moderate, Severe or Extreme"})
+		}
+	default:
+		errs = append(errs, model.FieldError{Field: "condition.type", Detail: fmt.Sprintf("condition type must be %q or %q", ConditionPrecipitation, ConditionAlert)})
+	}
+	return errs
+}
+
+func writeStoreError(w http.ResponseWriter, req *http.Request, err error) {
+	if errors.Is(err, ErrNotFound) {
+		problem.Error(w, req, http.StatusNotFound, "rule not found")
+		return
+	}
+	logging.GetLoggerFromContext(req.Context()).Error("rules store failure", zap.Error(err))
+	problem.Error(w, req, http.StatusInternalServerError, "unable to access the rules")
+}
+
+// writeJSON encodes the whole response before writing it, so an encoding
+// failure can still be reported as an error.
+func writeJSON(w http.ResponseWriter, req *http.Request, status int, v any) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		logging.GetLoggerFromContext(req.Context()).Error("unable to parse response", zap.Error(err))
+		problem.Error(w, req, http.StatusInternalServerError, "unable to encode the response")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(bs, '\n'))
+}
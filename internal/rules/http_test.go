@@ -0,0 +1,91 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	"github.com/dibrito/ennismore-weather-app/internal/auth"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// keys is an in-memory API keys store.
+type keys map[string]auth.Key
+
+func (k keys) Lookup(hash string) (auth.Key, error) {
+	key, ok := k[hash]
+	if !ok {
+		return auth.Key{}, auth.ErrNotFound
+	}
+	return key, nil
+}
+
+func TestHandlerRules(t *testing.T) {
+	store, err := NewFileStore("")
+	require.NoError(t, err)
+	handler := NewHandler(store, NewNotifier(config.RulesConfig{}, zaptest.NewLogger(t)))
+	handler.lookup = func(_ context.Context, _, host string) ([]netip.Addr, error) {
+		if addr, err := netip.ParseAddr(host); err == nil {
+			return []netip.Addr{addr}, nil
+		}
+		hosts := map[string][]netip.Addr{
+			"hooks.example.com":    {netip.MustParseAddr("93.184.216.34")},
+			"internal.example.com": {netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("10.0.0.7")},
+		}
+		return hosts[host], nil
+	}
+	routes := auth.New(keys{
+		auth.HashKey("alice-key"): {ID: "alice"},
+		auth.HashKey("bob-key"):   {ID: "bob"},
+	}).Middleware(handler.Routes())
+	do := func(method, target, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		recorder := httptest.NewRecorder()
+		routes.ServeHTTP(recorder, req)
+		return recorder
+	}
+	rule := func(url string) string {
+		return `{"city":"chicago","url":"` + url + `","condition":{"type":"alert","severity":"Severe"}}`
+	}
+
+	t.Run("when webhook resolves to private addresses should return BAD REQUEST", func(t *testing.T) {
+		for _, url := range []string{"http://169.254.169.254/latest", "http://127.0.0.1:8080/hook", "https://internal.example.com/hook"} {
+			recorder := do(http.MethodPost, "/", "alice-key", rule(url))
+			require.Equal(t, http.StatusBadRequest, recorder.Code, url)
+			require.Contains(t, recorder.Body.String(), "private, loopback or link-local", url)
+		}
+	})
+
+	t.Run("when body is too large should return REQUEST ENTITY TOO LARGE", func(t *testing.T) {
+		recorder := do(http.MethodPost, "/", "alice-key", rule("https://hooks.example.com/"+strings.Repeat("a", maxRuleBytes)))
+		require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	})
+
+	t.Run("when rule belongs to another key should not be found", func(t *testing.T) {
+		recorder := do(http.MethodPost, "/", "alice-key", `{"city":"chicago","url":"https://hooks.example.com/hook","owner":"bob",
+			"condition":{"type":"alert","severity":"Severe"}}`)
+		require.Equal(t, http.StatusCreated, recorder.Code)
+		var created model.Rule
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+		require.Equal(t, "alice", created.Owner)
+
+		require.Equal(t, http.StatusOK, do(http.MethodGet, "/"+created.ID, "alice-key", "").Code)
+		require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/"+created.ID, "bob-key", "").Code)
+		require.Equal(t, http.StatusNotFound, do(http.MethodPut, "/"+created.ID, "bob-key", rule("https://hooks.example.com/bob")).Code)
+		require.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/"+created.ID, "bob-key", "").Code)
+		require.JSONEq(t, `[]`, do(http.MethodGet, "/", "bob-key", "").Body.String())
+
+		var listed []model.Rule
+		require.NoError(t, json.Unmarshal(do(http.MethodGet, "/", "alice-key", "").Body.Bytes(), &listed))
+		require.Equal(t, []model.Rule{created}, listed)
+		require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/"+created.ID, "alice-key", "").Code)
+	})
+}
@@ -0,0 +1,205 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"go.uber.org/zap"
+)
+
+// SignatureHeader holds the HMAC-SHA256 signature of the webhook body.
+const SignatureHeader = "X-Signature"
+
+// maxDeadLetters bounds the dead-letter log, the oldest entries are dropped.
+const maxDeadLetters = 1000
+
+// errPermanent marks a delivery failure that won't be retried, e.g. a 4xx.
+var errPermanent = errors.New("permanent delivery failure")
+
+// errPrivateAddress is returned when a webhook resolves to an address it
+// can't be delivered to.
+var errPrivateAddress = errors.New("webhook address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range, RFC 6598.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddr reports whether webhooks may be delivered to addr, loopback,
+// private, link-local, shared and unspecified addresses are refused so rules
+// can't reach internal services, e.g. 169.254.169.254.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// Notifier delivers the rule events to their webhooks, failed deliveries are
+// retried with exponential backoff and kept on the dead-letter log.
+type Notifier struct {
+	client *http.Client
+	secret []byte
+	// allowAddr tells the addresses webhooks may be delivered to.
+	allowAddr   func(netip.Addr) bool
+	maxAttempts int
+	backoff     time.Duration
+	logger      *zap.Logger
+
+	mu          sync.Mutex
+	deadLetters []model.DeadLetter
+}
+
+// NewNotifier creates a webhook notifier. Webhooks are only delivered to
+// public addresses, the address is checked on every connection so a host
+// can't be rebound to a private address once its rule is created.
+func NewNotifier(cfg config.RulesConfig, logger *zap.Logger) *Notifier {
+	n := &Notifier{
+		secret:      []byte(cfg.SigningSecret),
+		allowAddr:   publicAddr,
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     time.Duration(cfg.RetryBackoff) * time.Second,
+		logger:      logger,
+	}
+	dialer := &net.Dialer{Control: func(_, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		addr, err := netip.ParseAddr(host)
+		if err != nil || !n.allowAddr(addr) {
+			return fmt.Errorf("%w: %s", errPrivateAddress, host)
+		}
+		return nil
+	}}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// through a proxy the proxy address would be checked instead of the webhook
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	n.client = &http.Client{
+		Timeout:   time.Duration(cfg.Timeout) * time.Second,
+		Transport: transport,
+	}
+	return n
+}
+
+// Sign returns the signature of a webhook body, receivers recompute it with
+// the shared secret and compare it with the X-Signature header.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver posts the event to the rule webhook, once all the attempts fail
+// the event is added to the dead-letter log and the last error is returned.
+func (n *Notifier) Deliver(ctx context.Context, rule model.Rule, event model.RuleEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	attempts := 0
+	backoff := n.backoff
+	for {
+		attempts++
+		err = n.post(ctx, rule.URL, body)
+		if err == nil {
+			n.logger.Info("webhook delivered",
+				zap.String("rule", rule.ID),
+				zap.Int("attempts", attempts))
+			return nil
+		}
+		if errors.Is(err, errPermanent) || attempts >= n.maxAttempts {
+			break
+		}
+
+		n.logger.Info("webhook delivery failed, retrying",
+			zap.String("rule", rule.ID),
+			zap.Int("attempt", attempts),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	n.logger.Warn("webhook delivery failed, moving to dead-letter log",
+		zap.String("rule", rule.ID),
+		zap.Int("attempts", attempts),
+		zap.Error(err))
+	n.addDeadLetter(model.DeadLetter{
+		RuleID:   rule.ID,
+		Owner:    rule.Owner,
+		URL:      rule.URL,
+		Payload:  event,
+		Error:    err.Error(),
+		Attempts: attempts,
+		FailedAt: nowFunc().UTC(),
+	})
+	return err
+}
+
+// DeadLetters returns the failed deliveries, oldest first.
+func (n *Notifier) DeadLetters() []model.DeadLetter {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return append([]model.DeadLetter{}, n.deadLetters...)
+}
+
+// post sends a single signed delivery attempt, server errors and rate
+// limiting are retryable while any other non 2xx status is permanent.
+func (n *Notifier) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(n.secret, body))
+
+	resp, err := n.client.Do(req)
+	if errors.Is(err, errPrivateAddress) {
+		return fmt.Errorf("%w: %w", errPermanent, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhook: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: unexpected status code: %d", errPermanent, resp.StatusCode)
+	}
+}
+
+func (n *Notifier) addDeadLetter(d model.DeadLetter) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.deadLetters = append(n.deadLetters, d)
+	if len(n.deadLetters) > maxDeadLetters {
+		n.deadLetters = n.deadLetters[len(n.deadLetters)-maxDeadLetters:]
+	}
+}
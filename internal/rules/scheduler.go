@@ -0,0 +1,242 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"go.uber.org/zap"
+)
+
+// nowFunc will get the now time when rules are evaluated.
+var nowFunc = time.Now
+
+// rule condition types.
+const (
+	ConditionPrecipitation = "precipitation"
+	ConditionAlert         = "alert"
+)
+
+// severityRank orders the CAP alert severities.
+var severityRank = map[string]int{
+	"Minor":    1,
+	"Moderate": 2,
+	"Severe":   3,
+	"Extreme":  4,
+}
+
+// WeatherProvider defines how the scheduler retrieves fresh forecasts and
+// active alerts.
+type WeatherProvider interface {
+	RefreshPeriods(ctx context.Context, city string) ([]model.Period, error)
+	GetAlerts(ctx context.Context, cities []string) (model.WeatherAlerts, error)
+}
+
+// Scheduler evaluates the stored rules against fresh forecasts and notifies
+// the webhooks. A rule is notified once per matching forecast, it fires again
+// only after the match changes.
+type Scheduler struct {
+	store    Store
+	provider WeatherProvider
+	notifier *Notifier
+	interval time.Duration
+
+	mu sync.Mutex
+	// fired holds the fingerprint of the last notified match by rule ID.
+	fired map[string]string
+}
+
+// NewScheduler creates a rules scheduler, interval is in seconds.
+func NewScheduler(store Store, provider WeatherProvider, notifier *Notifier, interval int) *Scheduler {
+	return &Scheduler{
+		store:    store,
+		provider: provider,
+		notifier: notifier,
+		interval: time.Duration(interval) * time.Second,
+		fired:    make(map[string]string),
+	}
+}
+
+// Run evaluates the rules every interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.Evaluate(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Evaluate checks every rule once, forecasts and alerts are fetched once per
+// city and the triggered webhooks are delivered concurrently.
+func (s *Scheduler) Evaluate(ctx context.Context) {
+	logger := logging.GetLoggerFromContext(ctx)
+
+	rules, err := s.store.List()
+	if err != nil {
+		logger.Error("unable to list rules", zap.Error(err))
+		return
+	}
+	s.prune(rules)
+
+	byCity := make(map[string][]model.Rule)
+	for _, r := range rules {
+		byCity[r.City] = append(byCity[r.City], r)
+	}
+
+	var wg sync.WaitGroup
+	now := nowFunc().UTC()
+	for city, cityRules := range byCity {
+		data, err := s.fetch(ctx, city, cityRules)
+		if err != nil {
+			logger.Warn("unable to evaluate rules",
+				zap.String("location", city),
+				zap.Error(err))
+			continue
+		}
+
+		for _, r := range cityRules {
+			event, fingerprint, ok := evaluate(r, data, now)
+			if !s.shouldNotify(r.ID, fingerprint, ok) {
+				continue
+			}
+			wg.Add(1)
+			go func(r model.Rule, event model.RuleEvent) {
+				defer wg.Done()
+				_ = s.notifier.Deliver(ctx, r, event)
+			}(r, event)
+		}
+	}
+	wg.Wait()
+}
+
+// cityData holds what the city rules are evaluated against.
+type cityData struct {
+	periods []model.Period
+	alerts  []model.Alert
+}
+
+// fetch retrieves only the data needed by the city rules.
+func (s *Scheduler) fetch(ctx context.Context, city string, rules []model.Rule) (cityData, error) {
+	var data cityData
+	var needPeriods, needAlerts bool
+	for _, r := range rules {
+		needPeriods = needPeriods || r.Condition.Type == ConditionPrecipitation
+		needAlerts = needAlerts || r.Condition.Type == ConditionAlert
+	}
+
+	if needPeriods {
+		periods, err := s.provider.RefreshPeriods(ctx, city)
+		if err != nil {
+			return data, err
+		}
+		data.periods = periods
+	}
+	if needAlerts {
+		alerts, err := s.provider.GetAlerts(ctx, []string{city})
+		if err != nil {
+			return data, err
+		}
+		if len(alerts.Alerts) == 0 {
+			return data, fmt.Errorf("no alerts for %s", city)
+		}
+		data.alerts = alerts.Alerts[0].Alerts
+	}
+	return data, nil
+}
+
+// shouldNotify records the rule match and reports whether it is a new one.
+func (s *Scheduler) shouldNotify(ruleID, fingerprint string, matched bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !matched {
+		delete(s.fired, ruleID)
+		return false
+	}
+	if s.fired[ruleID] == fingerprint {
+		return false
+	}
+	s.fired[ruleID] = fingerprint
+	return true
+}
+
+// prune forgets the matches of the rules no longer stored, so deleted rules
+// don't stay in memory.
+func (s *Scheduler) prune(rules []model.Rule) {
+	stored := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		stored[r.ID] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.fired {
+		if !stored[id] {
+			delete(s.fired, id)
+		}
+	}
+}
+
+// evaluate checks a rule condition, the fingerprint identifies the match so
+// the same forecast is not notified twice.
+func evaluate(r model.Rule, data cityData, now time.Time) (model.RuleEvent, string, bool) {
+	event := model.RuleEvent{
+		RuleID:      r.ID,
+		City:        r.City,
+		Condition:   r.Condition,
+		TriggeredAt: now,
+	}
+
+	switch r.Condition.Type {
+	case ConditionPrecipitation:
+		until := now.Add(time.Duration(r.Condition.WithinHours) * time.Hour)
+		var keys []string
+		for _, p := range data.periods {
+			v := p.ProbabilityOfPrecipitation.Value
+			if !p.EndTime.After(now) || !p.StartTime.Before(until) || v == nil || *v < r.Condition.Threshold {
+				continue
+			}
+			event.Periods = append(event.Periods, p)
+			keys = append(keys, fmt.Sprintf("%s=%.0f", p.StartTime.UTC().Format(time.RFC3339), *v))
+		}
+		if len(event.Periods) == 0 {
+			return event, "", false
+		}
+		event.Reason = fmt.Sprintf("precipitation probability >= %.0f%% within %dh", r.Condition.Threshold, r.Condition.WithinHours)
+		return event, fingerprint(r, keys), true
+
+	case ConditionAlert:
+		var keys []string
+		for _, a := range data.alerts {
+			if severityRank[a.Severity] < severityRank[r.Condition.Severity] {
+				continue
+			}
+			event.Alerts = append(event.Alerts, a)
+			keys = append(keys, a.ID)
+		}
+		if len(event.Alerts) == 0 {
+			return event, "", false
+		}
+		event.Reason = fmt.Sprintf("%s or more severe alert active", r.Condition.Severity)
+		return event, fingerprint(r, keys), true
+	}
+	return event, "", false
+}
+
+// fingerprint combines the rule condition with the matched keys, so editing
+// a rule notifies again.
+func fingerprint(r model.Rule, keys []string) string {
+	sort.Strings(keys)
+	return fmt.Sprintf("%+v|%s", r.Condition, strings.Join(keys, ","))
+}
@@ -0,0 +1,234 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	rulesMock "github.com/dibrito/ennismore-weather-app/gen/mock/rules"
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+// newLoopbackNotifier creates a notifier that delivers to the test receivers,
+// they listen on loopback addresses.
+func newLoopbackNotifier(cfg config.RulesConfig, logger *zap.Logger) *Notifier {
+	notifier := NewNotifier(cfg, logger)
+	notifier.allowAddr = func(netip.Addr) bool { return true }
+	return notifier
+}
+
+// receiver is a local webhook receiver that records the verified deliveries.
+type receiver struct {
+	mu     sync.Mutex
+	events []model.RuleEvent
+	status []int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+	if req.Header.Get(SignatureHeader) != Sign([]byte("secret"), body) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	status := http.StatusOK
+	if len(r.status) > 0 {
+		status, r.status = r.status[0], r.status[1:]
+	}
+	if status == http.StatusOK {
+		var event model.RuleEvent
+		_ = json.Unmarshal(body, &event)
+		r.events = append(r.events, event)
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) received() []model.RuleEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]model.RuleEvent(nil), r.events...)
+}
+
+func TestSchedulerEvaluate(t *testing.T) {
+	originalNowFunc := nowFunc
+	defer func() { nowFunc = originalNowFunc }()
+
+	fakeTime := time.Date(2024, 9, 23, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return fakeTime
+	}
+
+	rcv := &receiver{status: []int{http.StatusServiceUnavailable}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	ctrl := gomock.NewController(t)
+	providerMock := rulesMock.NewMockWeatherProvider(ctrl)
+
+	wet, dry := 80.0, 10.0
+	periods := []model.Period{
+		{StartTime: fakeTime, EndTime: fakeTime.Add(12 * time.Hour),
+			ProbabilityOfPrecipitation: model.QuantitativeValue{Value: &dry}},
+		{StartTime: fakeTime.Add(24 * time.Hour), EndTime: fakeTime.Add(36 * time.Hour),
+			ProbabilityOfPrecipitation: model.QuantitativeValue{Value: &wet}},
+		// out of the 48h window
+		{StartTime: fakeTime.Add(72 * time.Hour), EndTime: fakeTime.Add(84 * time.Hour),
+			ProbabilityOfPrecipitation: model.QuantitativeValue{Value: &wet}},
+	}
+	providerMock.EXPECT().RefreshPeriods(gomock.Any(), "chicago").Return(periods, nil).Times(2)
+	providerMock.EXPECT().GetAlerts(gomock.Any(), []string{"miami"}).Return(model.WeatherAlerts{
+		Alerts: []model.CityAlerts{{Name: "miami", Alerts: []model.Alert{
+			{ID: "minor", Severity: "Minor"},
+		}}},
+	}, nil).Times(3)
+
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "rules.json"))
+	require.NoError(t, err)
+	precipitation, err := store.Create(model.Rule{
+		City:      "chicago",
+		URL:       srv.URL,
+		Condition: model.RuleCondition{Type: ConditionPrecipitation, Threshold: 70, WithinHours: 48},
+	})
+	require.NoError(t, err)
+	_, err = store.Create(model.Rule{
+		City:      "miami",
+		URL:       srv.URL,
+		Condition: model.RuleCondition{Type: ConditionAlert, Severity: "Severe"},
+	})
+	require.NoError(t, err)
+
+	logger := zaptest.NewLogger(t)
+	notifier := newLoopbackNotifier(config.RulesConfig{SigningSecret: "secret", MaxAttempts: 3, Timeout: 1}, logger)
+	scheduler := NewScheduler(store, providerMock, notifier, 60)
+	ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, logger)
+
+	// the first delivery fails with 503 and is retried
+	scheduler.Evaluate(ctx)
+	events := rcv.received()
+	require.Len(t, events, 1)
+	require.Equal(t, precipitation.ID, events[0].RuleID)
+	require.Equal(t, []model.Period{periods[1]}, events[0].Periods)
+
+	// the same forecast is not notified twice
+	scheduler.Evaluate(ctx)
+	require.Len(t, rcv.received(), 1)
+	require.Empty(t, notifier.DeadLetters())
+	require.Contains(t, scheduler.fired, precipitation.ID)
+
+	// the match of a deleted rule is forgotten on the next evaluation
+	require.NoError(t, store.Delete(precipitation.ID))
+	scheduler.Evaluate(ctx)
+	require.Empty(t, scheduler.fired)
+}
+
+func TestNotifierDeadLetter(t *testing.T) {
+	tcs := []struct {
+		name         string
+		status       []int
+		wantAttempts int
+	}{
+		{
+			name:         "when server keeps failing should retry until max attempts",
+			status:       []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable},
+			wantAttempts: 3,
+		},
+		{
+			name:         "when client error should not retry",
+			status:       []int{http.StatusGone},
+			wantAttempts: 1,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			rcv := &receiver{status: tc.status}
+			srv := httptest.NewServer(rcv)
+			defer srv.Close()
+
+			notifier := newLoopbackNotifier(config.RulesConfig{SigningSecret: "secret", MaxAttempts: 3, Timeout: 1}, zaptest.NewLogger(t))
+			rule := model.Rule{ID: "rule", URL: srv.URL}
+			err := notifier.Deliver(context.Background(), rule, model.RuleEvent{RuleID: "rule"})
+			require.Error(t, err)
+
+			deadLetters := notifier.DeadLetters()
+			require.Len(t, deadLetters, 1)
+			require.Equal(t, "rule", deadLetters[0].RuleID)
+			require.Equal(t, tc.wantAttempts, deadLetters[0].Attempts)
+		})
+	}
+}
+
+func TestFileStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+
+	rule, err := store.Create(model.Rule{City: "chicago", URL: "https://example.com/hook"})
+	require.NoError(t, err)
+	require.NotEmpty(t, rule.ID)
+
+	reloaded, err := NewFileStore(path)
+	require.NoError(t, err)
+	got, err := reloaded.Get(rule.ID)
+	require.NoError(t, err)
+	require.Equal(t, rule, got)
+
+	require.NoError(t, reloaded.Delete(rule.ID))
+	_, err = reloaded.Get(rule.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestNotifierPrivateAddress(t *testing.T) {
+	rcv := &receiver{}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	notifier := NewNotifier(config.RulesConfig{SigningSecret: "secret", MaxAttempts: 3, Timeout: 1}, zaptest.NewLogger(t))
+	err := notifier.Deliver(context.Background(), model.Rule{ID: "rule", URL: srv.URL}, model.RuleEvent{RuleID: "rule"})
+	require.ErrorIs(t, err, errPrivateAddress)
+	require.Empty(t, rcv.received())
+	require.Len(t, notifier.DeadLetters(), 1)
+	require.Equal(t, 1, notifier.DeadLetters()[0].Attempts)
+}
+
+func TestPublicAddr(t *testing.T) {
+	tcs := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.0.0.1"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "169.254.169.254"},
+		{addr: "100.64.0.1"},
+		{addr: "0.0.0.0"},
+		{addr: "fd00::1"},
+		{addr: "fe80::1"},
+		{addr: "::ffff:127.0.0.1"},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.addr, func(t *testing.T) {
+			require.Equal(t, tc.want, publicAddr(netip.MustParseAddr(tc.addr)))
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package rules
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+)
+
+// ErrNotFound is returned when a requested rule is not found.
+var ErrNotFound = errors.New("rule not found")
+
+// Store defines the notification rules persistence.
+type Store interface {
+	Create(rule model.Rule) (model.Rule, error)
+	Get(id string) (model.Rule, error)
+	List() ([]model.Rule, error)
+	Update(rule model.Rule) (model.Rule, error)
+	Delete(id string) error
+}
+
+// fileStore keeps the rules in memory and, when a path is given, persists
+// them as a JSON file on every change.
+type fileStore struct {
+	sync.RWMutex
+	path  string
+	rules map[string]model.Rule
+}
+
+// NewFileStore creates a rules store backed by the JSON file at path, the
+// file is loaded when it exists. An empty path keeps the rules in memory only.
+func NewFileStore(path string) (*fileStore, error) {
+	s := &fileStore{
+		path:  path,
+		rules: make(map[string]model.Rule),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	bs, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read rules file: %w", err)
+	}
+	var rules []model.Rule
+	if err := json.Unmarshal(bs, &rules); err != nil {
+		return nil, fmt.Errorf("unable to parse rules file: %w", err)
+	}
+	for _, r := range rules {
+		s.rules[r.ID] = r
+	}
+	return s, nil
+}
+
+// Create stores a new rule with a generated ID.
+func (s *fileStore) Create(rule model.Rule) (model.Rule, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	id, err := newID()
+	if err != nil {
+		return model.Rule{}, err
+	}
+	rule.ID = id
+	s.rules[id] = rule
+	if err := s.save(); err != nil {
+		delete(s.rules, id)
+		return model.Rule{}, err
+	}
+	return rule, nil
+}
+
+// Get retrieves a rule by ID.
+func (s *fileStore) Get(id string) (model.Rule, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	rule, ok := s.rules[id]
+	if !ok {
+		return model.Rule{}, ErrNotFound
+	}
+	return rule, nil
+}
+
+// List retrieves all the rules sorted by creation time.
+func (s *fileStore) List() ([]model.Rule, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.sorted(), nil
+}
+
+// Update replaces an existing rule.
+func (s *fileStore) Update(rule model.Rule) (model.Rule, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	previous, ok := s.rules[rule.ID]
+	if !ok {
+		return model.Rule{}, ErrNotFound
+	}
+	s.rules[rule.ID] = rule
+	if err := s.save(); err != nil {
+		s.rules[rule.ID] = previous
+		return model.Rule{}, err
+	}
+	return rule, nil
+}
+
+// Delete removes a rule by ID.
+func (s *fileStore) Delete(id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	previous, ok := s.rules[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.rules, id)
+	if err := s.save(); err != nil {
+		s.rules[id] = previous
+		return err
+	}
+	return nil
+}
+
+// sorted returns the rules sorted by creation time, callers must hold the lock.
+func (s *fileStore) sorted() []model.Rule {
+	rules := make([]model.Rule, 0, len(s.rules))
+	for _, r := range s.rules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].CreatedAt.Equal(rules[j].CreatedAt) {
+			return rules[i].ID < rules[j].ID
+		}
+		return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+	})
+	return rules
+}
+
+// save writes the rules file through a temporary file so a crash never leaves
+// a partial file behind, callers must hold the lock.
+func (s *fileStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	bs, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("unable to save rules file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to save rules file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to save rules file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("unable to save rules file: %w", err)
+	}
+	return nil
+}
+
+// newID generates a random rule ID.
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
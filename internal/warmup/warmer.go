@@ -0,0 +1,130 @@
+package warmup
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// retryDelay is how long a failed refresh waits before trying again, it is
+// capped by the refresh interval.
+const retryDelay = 30 * time.Second
+
+// Refresher defines how the warmer refreshes the cached forecast of a place.
+type Refresher interface {
+	WarmForecast(ctx context.Context, query model.LocationQuery) error
+}
+
+// Warmer keeps the forecast of the configured hot places in cache by
+// refreshing them before their TTL lapses.
+type Warmer struct {
+	refresher Refresher
+	places    []model.LocationQuery
+	interval  time.Duration
+	jitter    time.Duration
+	limiter   *rate.Limiter
+}
+
+// New creates a cache warmer, forecastTTL is in seconds. Places are refreshed
+// RefreshBefore seconds before the TTL lapses, or at half the TTL when
+// RefreshBefore doesn't fit in it.
+func New(refresher Refresher, cfg config.WarmupConfig, forecastTTL int) *Warmer {
+	ttl := time.Duration(forecastTTL) * time.Second
+	interval := ttl - time.Duration(cfg.RefreshBefore)*time.Second
+	if interval <= 0 {
+		interval = ttl / 2
+	}
+
+	limit := rate.Inf
+	if cfg.RateLimit > 0 {
+		limit = rate.Limit(cfg.RateLimit)
+	}
+
+	places := make([]model.LocationQuery, 0, len(cfg.Places))
+	for _, p := range cfg.Places {
+		places = append(places, model.LocationQuery{
+			Name:    p.Name,
+			Country: p.Country,
+			Lat:     p.Lat,
+			Lon:     p.Lon,
+		})
+	}
+
+	return &Warmer{
+		refresher: refresher,
+		places:    places,
+		interval:  interval,
+		jitter:    time.Duration(cfg.Jitter) * time.Second,
+		limiter:   rate.NewLimiter(limit, 1),
+	}
+}
+
+// Run refreshes every place on its own schedule until ctx is done, it
+// returns once all the refreshes are stopped.
+func (w *Warmer) Run(ctx context.Context) {
+	logger := logging.GetLoggerFromContext(ctx)
+	if len(w.places) == 0 || w.interval <= 0 {
+		logger.Info("cache warmup disabled")
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, place := range w.places {
+		wg.Add(1)
+		go func(place model.LocationQuery) {
+			defer wg.Done()
+			w.warm(ctx, place)
+		}(place)
+	}
+	wg.Wait()
+}
+
+// warm refreshes a place until ctx is done, the first refresh is staggered
+// within the jitter so the places don't hit the upstream at once.
+func (w *Warmer) warm(ctx context.Context, place model.LocationQuery) {
+	logger := logging.GetLoggerFromContext(ctx)
+	timer := time.NewTimer(w.randomJitter(0))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+
+		// the limiter is shared, the upstream sees at most RateLimit refreshes per second
+		if err := w.limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		next := w.randomJitter(w.interval)
+		if err := w.refresher.WarmForecast(ctx, place); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warn("unable to warm forecast cache",
+				zap.String("location", place.DisplayName()),
+				zap.Error(err))
+			next = min(retryDelay, w.interval)
+		}
+		timer.Reset(next)
+	}
+}
+
+// randomJitter returns d shifted by a random amount within +/- jitter, never
+// below zero.
+func (w *Warmer) randomJitter(d time.Duration) time.Duration {
+	if w.jitter <= 0 {
+		return d
+	}
+	d += time.Duration(rand.Int64N(int64(2*w.jitter))) - w.jitter
+	return max(d, 0)
+}
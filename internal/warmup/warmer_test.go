@@ -0,0 +1,107 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/config"
+	warmupMock "github.com/dibrito/ennismore-weather-app/gen/mock/warmup"
+	"github.com/dibrito/ennismore-weather-app/pkg/logging"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestNew(t *testing.T) {
+	lat, lon := 47.6062, -122.3321
+	tcs := []struct {
+		name     string
+		cfg      config.WarmupConfig
+		ttl      int
+		interval time.Duration
+	}{
+		{
+			name:     "refresh before TTL",
+			cfg:      config.WarmupConfig{RefreshBefore: 300},
+			ttl:      1800,
+			interval: 25 * time.Minute,
+		},
+		{
+			name:     "refresh before longer than TTL",
+			cfg:      config.WarmupConfig{RefreshBefore: 3600},
+			ttl:      1800,
+			interval: 15 * time.Minute,
+		},
+		{
+			name: "places",
+			cfg: config.WarmupConfig{Places: []config.WarmupPlace{
+				{Name: "washington", Country: "us"},
+				{Name: "seattle", Lat: &lat, Lon: &lon},
+			}},
+			ttl:      60,
+			interval: time.Minute,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			w := New(nil, tc.cfg, tc.ttl)
+			require.Equal(t, tc.interval, w.interval)
+			require.Len(t, w.places, len(tc.cfg.Places))
+			for i, p := range tc.cfg.Places {
+				require.Equal(t, model.LocationQuery{Name: p.Name, Country: p.Country, Lat: p.Lat, Lon: p.Lon}, w.places[i])
+			}
+		})
+	}
+}
+
+func TestWarmerRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	refresher := warmupMock.NewMockRefresher(ctrl)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t)))
+	defer cancel()
+
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	refresher.EXPECT().WarmForecast(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, query model.LocationQuery) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[query.Key()]++
+			if calls["new york"] >= 2 && calls["chicago"] >= 2 {
+				cancel()
+			}
+			if query.Name == "chicago" {
+				return errors.New("upstream unavailable")
+			}
+			return nil
+		}).MinTimes(4)
+
+	w := New(refresher, config.WarmupConfig{
+		Places: []config.WarmupPlace{{Name: "new york"}, {Name: "chicago"}},
+	}, 1)
+	w.interval = 10 * time.Millisecond
+
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("warmer didn't stop after cancel")
+	}
+}
+
+func TestWarmerRunWithoutPlaces(t *testing.T) {
+	ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, zaptest.NewLogger(t))
+	// no refresher calls are expected, Run returns right away
+	New(nil, config.WarmupConfig{}, 1800).Run(ctx)
+}
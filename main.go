@@ -6,15 +6,21 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	serviceConfig "github.com/dibrito/ennismore-weather-app/config"
+	"github.com/dibrito/ennismore-weather-app/internal/admin"
+	"github.com/dibrito/ennismore-weather-app/internal/auth"
 	"github.com/dibrito/ennismore-weather-app/internal/clients/openstreetmap"
 	"github.com/dibrito/ennismore-weather-app/internal/clients/weather"
 	"github.com/dibrito/ennismore-weather-app/internal/controller"
 	httpHandler "github.com/dibrito/ennismore-weather-app/internal/handler"
+	"github.com/dibrito/ennismore-weather-app/internal/live"
 	repository "github.com/dibrito/ennismore-weather-app/internal/repository"
+	"github.com/dibrito/ennismore-weather-app/internal/rules"
+	"github.com/dibrito/ennismore-weather-app/internal/warmup"
 
 	"github.com/dibrito/ennismore-weather-app/pkg/logging"
 	"go.uber.org/zap"
@@ -40,9 +46,17 @@ func main() {
 	if err = yaml.NewDecoder(f).Decode(&cfg); err != nil {
 		logger.Fatal("unable to parse configuration", zap.Error(err))
 	}
+	if err = cfg.Validate(); err != nil {
+		logger.Fatal("invalid configuration", zap.Error(err))
+	}
 
 	// setup cache
-	cache := repository.New()
+	cache := repository.New(
+		time.Duration(cfg.CacheConfig.ForecastTTL)*time.Second,
+		time.Duration(cfg.CacheConfig.AlertsTTL)*time.Second,
+		time.Duration(cfg.CacheConfig.StaleWhileRevalidate)*time.Second,
+		time.Duration(cfg.CacheConfig.MaxStaleness)*time.Second,
+	)
 
 	// setup open streat map client
 	openstreetmapClient := openstreetmap.New(cfg.OpenstreetmapConfig)
@@ -51,14 +65,65 @@ func main() {
 	weatherClient := weather.New(cfg.WeatherConfig)
 
 	// set up controller
-	controller := controller.New(openstreetmapClient, weatherClient, cache)
+	// the weather client also serves the active alerts
+	controller := controller.New(openstreetmapClient, weatherClient, weatherClient, cache)
 	// set up handler
-	handler := httpHandler.New(controller)
+	handler := httpHandler.New(controller, cfg.APIConfig)
+
+	// set up live updates hub
+	hub := live.New(controller, cfg.LiveConfig, cfg.APIConfig.CORS, logger)
+	go hub.Run()
+
+	// set up cache warmer for the hot places
+	warmer := warmup.New(controller, cfg.WarmupConfig, cfg.CacheConfig.ForecastTTL)
+	backgroundJobs := []func(context.Context){warmer.Run}
+
+	modules := httpHandler.Modules{
+		Live: hub,
+	}
+	// the webhook rules are only served when a signing secret is configured
+	if cfg.RulesConfig.SigningSecret != "" {
+		rulesStore, err := rules.NewFileStore(cfg.RulesConfig.StoreFile)
+		if err != nil {
+			logger.Fatal("unable to load rules", zap.Error(err))
+		}
+		notifier := rules.NewNotifier(cfg.RulesConfig, logger)
+		scheduler := rules.NewScheduler(rulesStore, controller, notifier, cfg.RulesConfig.Interval)
+		backgroundJobs = append(backgroundJobs, scheduler.Run)
+		modules.Rules = rules.NewHandler(rulesStore, notifier).Routes()
+	}
+
+	// background jobs are stopped on shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.WithValue(context.Background(), logging.LoggetCtxKey{}, logger))
+	defer stopJobs()
+	var jobs sync.WaitGroup
+	for _, run := range backgroundJobs {
+		jobs.Add(1)
+		go func(run func(context.Context)) {
+			defer jobs.Done()
+			run(jobsCtx)
+		}(run)
+	}
+
+	// the API is only open when no API keys file is configured
+	if cfg.AuthConfig.KeysFile != "" {
+		keysStore, err := auth.NewFileStore(cfg.AuthConfig.KeysFile)
+		if err != nil {
+			logger.Fatal("unable to load api keys", zap.Error(err))
+		}
+		modules.Auth = auth.New(keysStore).Middleware
+	}
+	// the cache admin API is only served when an admin token is configured
+	if cfg.AdminConfig.Token != "" {
+		modules.Admin = admin.NewHandler(cache, cfg.AdminConfig).Routes()
+	}
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%v", cfg.APIConfig.Port),
-		Handler: handler.Routes(logger),
+		Handler: handler.Routes(logger, modules),
 	}
+	// WebSocket connections are hijacked, the server won't close them on shutdown
+	srv.RegisterOnShutdown(hub.Shutdown)
 
 	// start server
 	go func() {
@@ -76,6 +141,8 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	logger.Info("received shutdown signal ...")
+	stopJobs()
+	jobs.Wait()
 
 	// Close all e.g. database connection etc....
 
@@ -0,0 +1,210 @@
+// Package v1 holds the frozen v1 response shapes of weather-app API, they
+// must not change once released, new fields go to later versions.
+package v1
+
+import (
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+)
+
+// WeatherForecast represent the main forecast for weather-app API.
+type WeatherForecast struct {
+	Forecast []Forecast `json:"forecast"`
+}
+
+// Forecast represent the forecast for a city
+type Forecast struct {
+	Name   string   `json:"name"`
+	Detail []Detail `json:"detail"`
+	Alerts []Alert  `json:"alerts,omitempty"`
+	Stale  bool     `json:"stale,omitempty"`
+}
+
+// LiveMessage represent the messages pushed to WebSocket clients, either a
+// forecast update for a subscribed city or an error.
+type LiveMessage struct {
+	Type     string    `json:"type"`
+	Forecast *Forecast `json:"forecast,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Detail represent the inner details of a forecast
+type Detail struct {
+	StartTime       time.Time `json:"startTime"`
+	EndTime         time.Time `json:"endTime"`
+	Description     string    `json:"description"`
+	Temperature     *float64  `json:"temperature,omitempty"`
+	TemperatureUnit string    `json:"temperatureUnit,omitempty"`
+}
+
+// Alert represents an active weather alert, e.g. a flood or heat advisory.
+type Alert struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	Headline  string    `json:"headline"`
+	Severity  string    `json:"severity"`
+	Urgency   string    `json:"urgency"`
+	Effective time.Time `json:"effective"`
+	Expires   time.Time `json:"expires"`
+	AreaDesc  string    `json:"areaDesc"`
+}
+
+// WeatherAlerts represent the active alerts response for weather-app API.
+type WeatherAlerts struct {
+	Alerts []CityAlerts `json:"alerts"`
+}
+
+// CityAlerts represent the active alerts for a city.
+type CityAlerts struct {
+	Name   string  `json:"name"`
+	Alerts []Alert `json:"alerts"`
+}
+
+// CurrentConditions represent the current conditions response for weather-app API.
+type CurrentConditions struct {
+	Current []CityConditions `json:"current"`
+}
+
+// CityConditions represent the latest observation from the station nearest to a city.
+type CityConditions struct {
+	Name          string       `json:"name"`
+	StationID     string       `json:"stationId"`
+	StationName   string       `json:"stationName"`
+	Timestamp     time.Time    `json:"timestamp"`
+	Age           int64        `json:"age"`
+	Stale         bool         `json:"stale"`
+	Description   string       `json:"description"`
+	Temperature   *Measurement `json:"temperature,omitempty"`
+	Humidity      *Measurement `json:"humidity,omitempty"`
+	WindSpeed     *Measurement `json:"windSpeed,omitempty"`
+	WindDirection *Measurement `json:"windDirection,omitempty"`
+	Visibility    *Measurement `json:"visibility,omitempty"`
+}
+
+// Measurement represent an observed value and its unit.
+type Measurement struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// GridForecast represent the gridpoint time-series response for weather-app API.
+type GridForecast struct {
+	Grid []CityGrid `json:"grid"`
+}
+
+// CityGrid represent the requested gridpoint layers for a city.
+type CityGrid struct {
+	Name   string               `json:"name"`
+	Layers map[string]GridLayer `json:"layers"`
+}
+
+// GridLayer represent a quantitative series, e.g. temperature, at hourly resolution.
+type GridLayer struct {
+	Unit   string      `json:"unit"`
+	Values []GridValue `json:"values"`
+}
+
+// GridValue represent the value of a layer for a given hour.
+type GridValue struct {
+	Time  time.Time `json:"time"`
+	Value *float64  `json:"value"`
+}
+
+// NewWeatherForecast maps the forecast model into its v1 shape.
+func NewWeatherForecast(m model.WeatherForecast) WeatherForecast {
+	return WeatherForecast{Forecast: mapSlice(m.Forecast, NewForecast)}
+}
+
+// NewForecast maps a city forecast into its v1 shape.
+func NewForecast(f model.Forecast) Forecast {
+	return Forecast{
+		Name: f.Name,
+		Detail: mapSlice(f.Detail, func(d model.Detail) Detail {
+			return Detail{
+				StartTime:       d.StartTime,
+				EndTime:         d.EndTime,
+				Description:     d.Description,
+				Temperature:     d.Temperature,
+				TemperatureUnit: d.TemperatureUnit,
+			}
+		}),
+		Alerts: mapSlice(f.Alerts, newAlert),
+		Stale:  f.Stale,
+	}
+}
+
+// NewWeatherAlerts maps the alerts model into its v1 shape.
+func NewWeatherAlerts(m model.WeatherAlerts) WeatherAlerts {
+	return WeatherAlerts{Alerts: mapSlice(m.Alerts, func(c model.CityAlerts) CityAlerts {
+		return CityAlerts{Name: c.Name, Alerts: mapSlice(c.Alerts, newAlert)}
+	})}
+}
+
+// NewCurrentConditions maps the current conditions model into its v1 shape.
+func NewCurrentConditions(m model.CurrentConditions) CurrentConditions {
+	return CurrentConditions{Current: mapSlice(m.Current, func(c model.CityConditions) CityConditions {
+		return CityConditions{
+			Name:          c.Name,
+			StationID:     c.StationID,
+			StationName:   c.StationName,
+			Timestamp:     c.Timestamp,
+			Age:           c.Age,
+			Stale:         c.Stale,
+			Description:   c.Description,
+			Temperature:   newMeasurement(c.Temperature),
+			Humidity:      newMeasurement(c.Humidity),
+			WindSpeed:     newMeasurement(c.WindSpeed),
+			WindDirection: newMeasurement(c.WindDirection),
+			Visibility:    newMeasurement(c.Visibility),
+		}
+	})}
+}
+
+// NewGridForecast maps the gridpoint model into its v1 shape.
+func NewGridForecast(m model.GridForecast) GridForecast {
+	return GridForecast{Grid: mapSlice(m.Grid, func(c model.CityGrid) CityGrid {
+		var layers map[string]GridLayer
+		if c.Layers != nil {
+			layers = make(map[string]GridLayer, len(c.Layers))
+		}
+		for name, l := range c.Layers {
+			layers[name] = GridLayer{Unit: l.Unit, Values: mapSlice(l.Values, func(v model.GridValue) GridValue {
+				return GridValue{Time: v.Time, Value: v.Value}
+			})}
+		}
+		return CityGrid{Name: c.Name, Layers: layers}
+	})}
+}
+
+func newAlert(a model.Alert) Alert {
+	return Alert{
+		ID:        a.ID,
+		Event:     a.Event,
+		Headline:  a.Headline,
+		Severity:  a.Severity,
+		Urgency:   a.Urgency,
+		Effective: a.Effective,
+		Expires:   a.Expires,
+		AreaDesc:  a.AreaDesc,
+	}
+}
+
+func newMeasurement(m *model.Measurement) *Measurement {
+	if m == nil {
+		return nil
+	}
+	return &Measurement{Value: m.Value, Unit: m.Unit}
+}
+
+// mapSlice maps every item keeping nil slices nil, v1 sends them as null.
+func mapSlice[T, R any](items []T, f func(T) R) []R {
+	if items == nil {
+		return nil
+	}
+	result := make([]R, 0, len(items))
+	for _, item := range items {
+		result = append(result, f(item))
+	}
+	return result
+}
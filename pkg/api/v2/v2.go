@@ -0,0 +1,351 @@
+// Package v2 holds the v2 response shapes of weather-app API, every value
+// carries its unit, locations are objects and lists are never null.
+package v2
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+)
+
+// WeatherForecast represent the forecast response for weather-app API.
+type WeatherForecast struct {
+	Forecasts []Forecast `json:"forecasts"`
+}
+
+// Forecast represent the forecast periods and active alerts of a location.
+type Forecast struct {
+	Location Location `json:"location"`
+	Periods  []Period `json:"periods"`
+	Alerts   []Alert  `json:"alerts"`
+	// Stale is set when the forecast is served past its cache TTL.
+	Stale bool `json:"stale"`
+	// Distance and Bearing are only sent by nearby searches.
+	Distance *Measurement `json:"distance,omitempty"`
+	Bearing  *Measurement `json:"bearing,omitempty"`
+}
+
+// Location represent a requested location.
+type Location struct {
+	Name string `json:"name"`
+}
+
+// Period represent the forecast of a day.
+type Period struct {
+	Start                    time.Time    `json:"start"`
+	End                      time.Time    `json:"end"`
+	Summary                  string       `json:"summary,omitempty"`
+	Description              string       `json:"description"`
+	Temperature              *Measurement `json:"temperature,omitempty"`
+	PrecipitationProbability *Measurement `json:"precipitationProbability,omitempty"`
+	WindSpeed                *Measurement `json:"windSpeed,omitempty"`
+}
+
+// Measurement represent a value and its unit, e.g. degC or %.
+type Measurement struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// Alert represents an active weather alert, e.g. a flood or heat advisory.
+type Alert struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	Headline  string    `json:"headline"`
+	Severity  string    `json:"severity"`
+	Urgency   string    `json:"urgency"`
+	Effective time.Time `json:"effective"`
+	Expires   time.Time `json:"expires"`
+	Area      string    `json:"area"`
+}
+
+// WeatherAlerts represent the active alerts response for weather-app API.
+type WeatherAlerts struct {
+	Alerts []LocationAlerts `json:"alerts"`
+}
+
+// LocationAlerts represent the active alerts of a location.
+type LocationAlerts struct {
+	Location Location `json:"location"`
+	Alerts   []Alert  `json:"alerts"`
+}
+
+// CurrentConditions represent the current conditions response for weather-app API.
+type CurrentConditions struct {
+	Current []Observation `json:"current"`
+}
+
+// Observation represent the latest observation from the station nearest to a location.
+type Observation struct {
+	Location      Location     `json:"location"`
+	Station       Station      `json:"station"`
+	ObservedAt    time.Time    `json:"observedAt"`
+	AgeSeconds    int64        `json:"ageSeconds"`
+	Stale         bool         `json:"stale"`
+	Description   string       `json:"description"`
+	Temperature   *Measurement `json:"temperature,omitempty"`
+	Humidity      *Measurement `json:"humidity,omitempty"`
+	WindSpeed     *Measurement `json:"windSpeed,omitempty"`
+	WindDirection *Measurement `json:"windDirection,omitempty"`
+	Visibility    *Measurement `json:"visibility,omitempty"`
+}
+
+// Station represent an observation station.
+type Station struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// GridForecast represent the gridpoint time-series response for weather-app API.
+type GridForecast struct {
+	Grid []LocationGrid `json:"grid"`
+}
+
+// LocationGrid represent the requested gridpoint layers of a location.
+type LocationGrid struct {
+	Location Location             `json:"location"`
+	Layers   map[string]GridLayer `json:"layers"`
+}
+
+// GridLayer represent a quantitative series, e.g. temperature, at hourly resolution.
+type GridLayer struct {
+	Unit   string      `json:"unit"`
+	Values []GridValue `json:"values"`
+}
+
+// GridValue represent the value of a layer for a given hour, nil when missing.
+type GridValue struct {
+	Time  time.Time `json:"time"`
+	Value *float64  `json:"value"`
+}
+
+// Itinerary represent the forecast timeline of an itinerary.
+type Itinerary struct {
+	Timeline []Stop `json:"timeline"`
+}
+
+// Stop represent the forecast of an itinerary leg on one of its days, Period
+// is nil when the day was not forecast and Error tells why the leg failed.
+type Stop struct {
+	Date     string   `json:"date"`
+	Leg      int      `json:"leg"`
+	Location Location `json:"location"`
+	Period   *Period  `json:"period,omitempty"`
+	Stale    bool     `json:"stale"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Comparison represent the forecast of locations ranked on a metric.
+type Comparison struct {
+	Metric string `json:"metric"`
+	// Best is highest or lowest, the value that wins.
+	Best    string        `json:"best"`
+	Ranking []Rank        `json:"ranking"`
+	Days    []ComparedDay `json:"days"`
+}
+
+// Rank represent the place of a location, Value is its mean over the compared days.
+type Rank struct {
+	Rank     int          `json:"rank"`
+	Location Location     `json:"location"`
+	Value    *Measurement `json:"value,omitempty"`
+	// Days counts the compared days with a value, the locations missing
+	// days are ranked after the others.
+	Days int `json:"days"`
+	Wins int `json:"wins"`
+}
+
+// ComparedDay represent the values and winners of a compared day.
+type ComparedDay struct {
+	Date    string          `json:"date"`
+	Values  []LocationValue `json:"values"`
+	Winners []Location      `json:"winners"`
+	Tie     bool            `json:"tie"`
+}
+
+// LocationValue represent the value of a location on a compared day.
+type LocationValue struct {
+	Location Location     `json:"location"`
+	Value    *Measurement `json:"value,omitempty"`
+}
+
+// ActivityForecast represent how well each forecast day of the locations suits an activity.
+type ActivityForecast struct {
+	Activity  string             `json:"activity"`
+	Locations []LocationActivity `json:"locations"`
+}
+
+// LocationActivity represent the scored forecast days of a location.
+type LocationActivity struct {
+	Location Location      `json:"location"`
+	Days     []ActivityDay `json:"days"`
+	Stale    bool          `json:"stale"`
+}
+
+// ActivityDay represent the score of a forecast day, from 0 to 100, its
+// rating, good, fair or poor, and the reasons behind it.
+type ActivityDay struct {
+	Date    string   `json:"date"`
+	Score   int      `json:"score"`
+	Rating  string   `json:"rating"`
+	Reasons []string `json:"reasons"`
+	Period  Period   `json:"period"`
+}
+
+// NewWeatherForecast maps the forecast model into its v2 shape.
+func NewWeatherForecast(m model.WeatherForecast) WeatherForecast {
+	return WeatherForecast{Forecasts: mapSlice(m.Forecast, NewForecast)}
+}
+
+// NewForecast maps a location forecast into its v2 shape.
+func NewForecast(f model.Forecast) Forecast {
+	return Forecast{
+		Location: Location{Name: f.Name},
+		Periods:  mapSlice(f.Detail, newPeriod),
+		Alerts:   mapSlice(f.Alerts, newAlert),
+		Stale:    f.Stale,
+		Distance: newMeasurement(f.Distance),
+		Bearing:  newMeasurement(f.Bearing),
+	}
+}
+
+// NewWeatherAlerts maps the alerts model into its v2 shape.
+func NewWeatherAlerts(m model.WeatherAlerts) WeatherAlerts {
+	return WeatherAlerts{Alerts: mapSlice(m.Alerts, func(c model.CityAlerts) LocationAlerts {
+		return LocationAlerts{Location: Location{Name: c.Name}, Alerts: mapSlice(c.Alerts, newAlert)}
+	})}
+}
+
+// NewCurrentConditions maps the current conditions model into its v2 shape.
+func NewCurrentConditions(m model.CurrentConditions) CurrentConditions {
+	return CurrentConditions{Current: mapSlice(m.Current, func(c model.CityConditions) Observation {
+		return Observation{
+			Location:      Location{Name: c.Name},
+			Station:       Station{ID: c.StationID, Name: c.StationName},
+			ObservedAt:    c.Timestamp,
+			AgeSeconds:    c.Age,
+			Stale:         c.Stale,
+			Description:   c.Description,
+			Temperature:   newMeasurement(c.Temperature),
+			Humidity:      newMeasurement(c.Humidity),
+			WindSpeed:     newMeasurement(c.WindSpeed),
+			WindDirection: newMeasurement(c.WindDirection),
+			Visibility:    newMeasurement(c.Visibility),
+		}
+	})}
+}
+
+// NewGridForecast maps the gridpoint model into its v2 shape.
+func NewGridForecast(m model.GridForecast) GridForecast {
+	return GridForecast{Grid: mapSlice(m.Grid, func(c model.CityGrid) LocationGrid {
+		layers := make(map[string]GridLayer, len(c.Layers))
+		for name, l := range c.Layers {
+			layers[name] = GridLayer{Unit: l.Unit, Values: mapSlice(l.Values, func(v model.GridValue) GridValue {
+				return GridValue{Time: v.Time, Value: v.Value}
+			})}
+		}
+		return LocationGrid{Location: Location{Name: c.Name}, Layers: layers}
+	})}
+}
+
+// NewComparison maps the comparison model into its v2 shape.
+func NewComparison(m model.Comparison) Comparison {
+	return Comparison{
+		Metric: m.Metric,
+		Best:   m.Best,
+		Ranking: mapSlice(m.Ranking, func(r model.CityRank) Rank {
+			return Rank{Rank: r.Rank, Location: Location{Name: r.Name}, Value: newMeasurement(r.Value), Days: r.Days, Wins: r.Wins}
+		}),
+		Days: mapSlice(m.Days, func(d model.DayComparison) ComparedDay {
+			return ComparedDay{
+				Date: d.Date,
+				Values: mapSlice(d.Values, func(v model.CityValue) LocationValue {
+					return LocationValue{Location: Location{Name: v.Name}, Value: newMeasurement(v.Value)}
+				}),
+				Winners: mapSlice(d.Winners, func(name string) Location { return Location{Name: name} }),
+				Tie:     d.Tie,
+			}
+		}),
+	}
+}
+
+// NewItinerary maps the itinerary model into its v2 shape.
+func NewItinerary(m model.Itinerary) Itinerary {
+	return Itinerary{Timeline: mapSlice(m.Timeline, func(s model.ItineraryStop) Stop {
+		stop := Stop{Date: s.Date, Leg: s.Leg, Location: Location{Name: s.Name}, Stale: s.Stale, Error: s.Error}
+		if s.Detail != nil {
+			p := newPeriod(*s.Detail)
+			stop.Period = &p
+		}
+		return stop
+	})}
+}
+
+// NewActivityForecast maps the activity forecast model into its v2 shape.
+func NewActivityForecast(m model.ActivityForecast) ActivityForecast {
+	return ActivityForecast{
+		Activity: m.Activity,
+		Locations: mapSlice(m.Forecast, func(c model.CityActivity) LocationActivity {
+			return LocationActivity{
+				Location: Location{Name: c.Name},
+				Days: mapSlice(c.Days, func(d model.ActivityDay) ActivityDay {
+					return ActivityDay{
+						Date:    d.Date,
+						Score:   d.Score,
+						Rating:  d.Rating,
+						Reasons: append([]string{}, d.Reasons...),
+						Period:  newPeriod(d.Detail),
+					}
+				}),
+				Stale: c.Stale,
+			}
+		}),
+	}
+}
+
+func newPeriod(d model.Detail) Period {
+	p := Period{Start: d.StartTime, End: d.EndTime, Summary: d.Summary, Description: d.Description}
+	if d.Temperature != nil {
+		// forecast temperatures come as C or F
+		unit := d.TemperatureUnit
+		if unit != "" && !strings.HasPrefix(unit, "deg") {
+			unit = "deg" + unit
+		}
+		p.Temperature = &Measurement{Value: *d.Temperature, Unit: unit}
+	}
+	if d.PrecipitationProbability != nil {
+		p.PrecipitationProbability = &Measurement{Value: *d.PrecipitationProbability, Unit: "%"}
+	}
+	p.WindSpeed = newMeasurement(d.WindSpeed)
+	return p
+}
+
+func newAlert(a model.Alert) Alert {
+	return Alert{
+		ID:        a.ID,
+		Event:     a.Event,
+		Headline:  a.Headline,
+		Severity:  a.Severity,
+		Urgency:   a.Urgency,
+		Effective: a.Effective,
+		Expires:   a.Expires,
+		Area:      a.AreaDesc,
+	}
+}
+
+func newMeasurement(m *model.Measurement) *Measurement {
+	if m == nil {
+		return nil
+	}
+	return &Measurement{Value: m.Value, Unit: m.Unit}
+}
+
+// mapSlice maps every item, nil slices are mapped into empty ones.
+func mapSlice[T, R any](items []T, f func(T) R) []R {
+	result := make([]R, 0, len(items))
+	for _, item := range items {
+		result = append(result, f(item))
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package format
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+)
+
+// nowFunc is the DTSTAMP of the calendar events.
+var nowFunc = time.Now
+
+const (
+	// calendarProdID identifies the weather-app as the calendar producer.
+	calendarProdID = "-//ennismore//weather-app//EN"
+	// uidDomain makes the event UIDs globally unique, RFC 5545 3.8.4.7.
+	uidDomain = "ennismore-weather-app"
+	// calendarTimeLayout is the UTC DATE-TIME form, RFC 5545 3.3.5.
+	calendarTimeLayout = "20060102T150405Z"
+	// maxLineOctets is the longest content line before folding, RFC 5545 3.1.
+	maxLineOctets = 75
+)
+
+// Calendar encodes an RFC 5545 VCALENDAR with a VEVENT per city and period.
+// The periods are written in UTC, weather.gov sends them with the offset of
+// the location so they are absolute times and calendar apps show them on the
+// time zone of their users without the need of VTIMEZONE components. Each
+// event UID is derived from the city, the local date and the day or night part
+// of the period, weather.gov moves the start of the current period forward
+// every hour, so subscribed calendars replace the events of a period when its
+// forecast is updated.
+type Calendar struct{}
+
+func (Calendar) ContentType() string { return "text/calendar; charset=utf-8" }
+
+func (Calendar) Encode(w io.Writer, m model.WeatherForecast) error {
+	cw := &calendarWriter{w: bufio.NewWriter(w)}
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:" + calendarProdID)
+	cw.line("CALSCALE:GREGORIAN")
+	cw.line("METHOD:PUBLISH")
+	cw.line("X-WR-CALNAME:" + escapeText("Weather forecast"))
+	cw.line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+
+	stamp := nowFunc().UTC().Format(calendarTimeLayout)
+	for _, f := range m.Forecast {
+		for _, d := range f.Detail {
+			summary := d.Summary
+			if summary == "" {
+				summary = d.Description
+			}
+			cw.line("BEGIN:VEVENT")
+			cw.line("UID:" + escapeText(eventUID(f.Name, d.StartTime)))
+			cw.line("DTSTAMP:" + stamp)
+			cw.line("DTSTART:" + d.StartTime.UTC().Format(calendarTimeLayout))
+			cw.line("DTEND:" + d.EndTime.UTC().Format(calendarTimeLayout))
+			cw.line("SUMMARY:" + escapeText(f.Name+": "+summary))
+			if d.Description != "" {
+				cw.line("DESCRIPTION:" + escapeText(d.Description))
+			}
+			cw.line("LOCATION:" + escapeText(f.Name))
+			cw.line("TRANSP:TRANSPARENT")
+			cw.line("END:VEVENT")
+		}
+	}
+	cw.line("END:VCALENDAR")
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.w.Flush()
+}
+
+// eventUID identifies the forecast of a city period, e.g.
+// new-york-20240923-day@ennismore-weather-app. The start keeps the offset of
+// the location, periods starting from 6pm to 6am local time are night ones
+// and belong to the date the night started on.
+func eventUID(city string, start time.Time) string {
+	part := "day"
+	switch hour := start.Hour(); {
+	case hour < 6:
+		part, start = "night", start.AddDate(0, 0, -1)
+	case hour >= 18:
+		part = "night"
+	}
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(city) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-") + "-" + start.Format("20060102") + "-" + part + "@" + uidDomain
+}
+
+// escapeText escapes a TEXT value, RFC 5545 3.3.11.
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// calendarWriter writes CRLF terminated content lines folded at 75 octets,
+// the first error is kept and the next writes are skipped.
+type calendarWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (cw *calendarWriter) line(s string) {
+	if cw.err != nil {
+		return
+	}
+	// the continuation lines start with a space, it counts toward their length
+	limit := maxLineOctets
+	for len(s) > limit {
+		// never split a UTF-8 sequence
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if _, cw.err = cw.w.WriteString(s[:cut] + "\r\n "); cw.err != nil {
+			return
+		}
+		s = s[cut:]
+		limit = maxLineOctets - 1
+	}
+	_, cw.err = cw.w.WriteString(s + "\r\n")
+}
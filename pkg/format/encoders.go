@@ -0,0 +1,164 @@
+package format
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+)
+
+// CSV encodes a row per city and period, the first row holds the column names.
+type CSV struct{}
+
+var csvHeader = []string{"city", "startTime", "endTime", "description", "temperature",
+	"temperatureUnit", "precipitationProbability", "stale"}
+
+func (CSV) ContentType() string { return "text/csv; charset=utf-8" }
+
+func (CSV) Encode(w io.Writer, m model.WeatherForecast) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, f := range m.Forecast {
+		for _, d := range f.Detail {
+			row := []string{csvText(f.Name), d.StartTime.Format(time.RFC3339), d.EndTime.Format(time.RFC3339), csvText(d.Description),
+				formatFloat(d.Temperature), csvText(d.TemperatureUnit), formatFloat(d.PrecipitationProbability), strconv.FormatBool(f.Stale)}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvText neutralises text cells spreadsheets would evaluate as formulas,
+// e.g. =HYPERLINK(...), by prefixing them with a quote. Only the text coming
+// from the request or upstream goes through it, so negative numbers are kept.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// XML encodes a weatherForecast document, periods and alerts are nested on
+// their city.
+type XML struct{}
+
+type xmlWeatherForecast struct {
+	XMLName  xml.Name      `xml:"weatherForecast"`
+	Forecast []xmlForecast `xml:"forecast"`
+}
+
+type xmlForecast struct {
+	Name   string      `xml:"name,attr"`
+	Stale  bool        `xml:"stale,attr,omitempty"`
+	Period []xmlPeriod `xml:"period"`
+	Alert  []xmlAlert  `xml:"alert"`
+}
+
+type xmlPeriod struct {
+	Start                    time.Time `xml:"start,attr"`
+	End                      time.Time `xml:"end,attr"`
+	Description              string    `xml:"description"`
+	Temperature              *xmlValue `xml:"temperature"`
+	PrecipitationProbability *xmlValue `xml:"precipitationProbability"`
+}
+
+type xmlValue struct {
+	Unit  string  `xml:"unit,attr,omitempty"`
+	Value float64 `xml:",chardata"`
+}
+
+type xmlAlert struct {
+	ID        string    `xml:"id,attr"`
+	Event     string    `xml:"event"`
+	Headline  string    `xml:"headline"`
+	Severity  string    `xml:"severity"`
+	Effective time.Time `xml:"effective"`
+	Expires   time.Time `xml:"expires"`
+	Area      string    `xml:"area"`
+}
+
+func (XML) ContentType() string { return "application/xml; charset=utf-8" }
+
+func (XML) Encode(w io.Writer, m model.WeatherForecast) error {
+	doc := xmlWeatherForecast{Forecast: make([]xmlForecast, 0, len(m.Forecast))}
+	for _, f := range m.Forecast {
+		forecast := xmlForecast{Name: f.Name, Stale: f.Stale}
+		for _, d := range f.Detail {
+			period := xmlPeriod{Start: d.StartTime, End: d.EndTime, Description: d.Description}
+			if d.Temperature != nil {
+				period.Temperature = &xmlValue{Unit: d.TemperatureUnit, Value: *d.Temperature}
+			}
+			if d.PrecipitationProbability != nil {
+				period.PrecipitationProbability = &xmlValue{Unit: "%", Value: *d.PrecipitationProbability}
+			}
+			forecast.Period = append(forecast.Period, period)
+		}
+		for _, a := range f.Alerts {
+			forecast.Alert = append(forecast.Alert, xmlAlert{ID: a.ID, Event: a.Event, Headline: a.Headline,
+				Severity: a.Severity, Effective: a.Effective, Expires: a.Expires, Area: a.AreaDesc})
+		}
+		doc.Forecast = append(doc.Forecast, forecast)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Text encodes an aligned table for terminals, e.g. curl users.
+type Text struct{}
+
+// textTimeLayout keeps the periods short while showing their offset.
+const textTimeLayout = "Mon Jan 2 15:04 MST"
+
+func (Text) ContentType() string { return "text/plain; charset=utf-8" }
+
+func (Text) Encode(w io.Writer, m model.WeatherForecast) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CITY\tSTART\tEND\tTEMP\tPRECIP\tDESCRIPTION")
+	for _, f := range m.Forecast {
+		name := f.Name
+		if f.Stale {
+			name += " (stale)"
+		}
+		for _, d := range f.Detail {
+			temperature := "-"
+			if d.Temperature != nil {
+				temperature = formatFloat(d.Temperature) + " " + d.TemperatureUnit
+			}
+			precipitation := "-"
+			if d.PrecipitationProbability != nil {
+				precipitation = formatFloat(d.PrecipitationProbability) + "%"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", name, d.StartTime.Format(textTimeLayout),
+				d.EndTime.Format(textTimeLayout), temperature, precipitation, d.Description)
+		}
+	}
+	return tw.Flush()
+}
+
+// formatFloat formats an optional value, empty when nil.
+func formatFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
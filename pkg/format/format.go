@@ -0,0 +1,126 @@
+// Package format encodes forecasts in the non JSON media types served by the
+// weather-app, e.g. CSV for spreadsheets. Formats are registered on a Registry
+// and picked with the format query param or the Accept header.
+package format
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+)
+
+// JSON is the default format, it is encoded by the handlers so the response
+// shape follows the API version.
+const JSON = "json"
+
+// Encoder writes a forecast in a media type.
+type Encoder interface {
+	// ContentType is the Content-Type of the encoded forecasts, its media
+	// type is matched against the Accept header.
+	ContentType() string
+	Encode(w io.Writer, m model.WeatherForecast) error
+}
+
+// Registry holds the encoders by format name.
+type Registry struct {
+	encoders map[string]Encoder
+}
+
+// NewRegistry creates an empty registry, only JSON is supported.
+func NewRegistry() *Registry {
+	return &Registry{encoders: make(map[string]Encoder)}
+}
+
+// Default creates a registry with the CSV, GeoJSON, iCalendar, XML and plain
+// text encoders.
+func Default() *Registry {
+	r := NewRegistry()
+	r.Register("csv", CSV{})
+	r.Register("geojson", GeoJSON{})
+	r.Register("ics", Calendar{})
+	r.Register("xml", XML{})
+	r.Register("text", Text{})
+	return r
+}
+
+// Register adds an encoder, an encoder already registered under the name is
+// replaced.
+func (r *Registry) Register(name string, e Encoder) {
+	r.encoders[strings.ToLower(name)] = e
+}
+
+// Names returns the supported format names, sorted with JSON first.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.encoders))
+	for name := range r.encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{JSON}, names...)
+}
+
+// Negotiate returns the encoder of the requested format, the format param
+// wins over the Accept header. A nil encoder means JSON, which is also used
+// when no accepted media type is supported. An unknown format param is an
+// error.
+func (r *Registry) Negotiate(format, accept string) (Encoder, error) {
+	if format != "" {
+		format = strings.ToLower(format)
+		if format == JSON {
+			return nil, nil
+		}
+		if e, ok := r.encoders[format]; ok {
+			return e, nil
+		}
+		return nil, fmt.Errorf("supported formats are %s", strings.Join(r.Names(), ", "))
+	}
+
+	for _, mediaType := range accepted(accept) {
+		if mediaType == "application/json" || mediaType == "*/*" {
+			return nil, nil
+		}
+		for _, e := range r.encoders {
+			if ct, _, _ := mime.ParseMediaType(e.ContentType()); ct == mediaType {
+				return e, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// accepted returns the media types of an Accept header by decreasing quality,
+// the ones with a zero quality are dropped.
+func accepted(accept string) []string {
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	mediaTypes := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		mediaTypes = append(mediaTypes, r.mediaType)
+	}
+	return mediaTypes
+}
@@ -0,0 +1,283 @@
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	tcs := []struct {
+		name    string
+		format  string
+		accept  string
+		want    Encoder
+		wantErr string
+	}{
+		{name: "when nothing requested should be JSON"},
+		{name: "when format param should win over Accept", format: "CSV", accept: "application/xml", want: CSV{}},
+		{name: "when format is json should be JSON", format: "json", accept: "text/csv"},
+		{name: "when format unknown should fail", format: "yaml", wantErr: "supported formats are json, csv, geojson, ics, text, xml"},
+		{name: "when Accept matches should use its encoder", accept: "application/xml", want: XML{}},
+		{name: "when Accept has qualities should prefer the highest", accept: "text/csv;q=0.5, text/plain", want: Text{}},
+		{name: "when Accept prefers JSON should be JSON", accept: "application/json, text/csv;q=0.9"},
+		{name: "when Accept refuses a type should skip it", accept: "text/csv;q=0, application/xml;q=0.1", want: XML{}},
+		{name: "when Accept is not supported should fall back to JSON", accept: "image/png"},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Default().Negotiate(tc.format, tc.accept)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	start := time.Date(2024, 9, 23, 6, 0, 0, 0, time.UTC)
+	temperature, precipitation := 18.5, 40.0
+	m := model.WeatherForecast{Forecast: []model.Forecast{
+		{
+			Name: "london",
+			Detail: []model.Detail{{
+				StartTime:                start,
+				EndTime:                  start.Add(12 * time.Hour),
+				Description:              "Rain, then \"gray\"",
+				Temperature:              &temperature,
+				TemperatureUnit:          "C",
+				PrecipitationProbability: &precipitation,
+			}},
+		},
+		{
+			Name:  "paris",
+			Stale: true,
+			Detail: []model.Detail{{
+				StartTime:   start,
+				EndTime:     start.Add(12 * time.Hour),
+				Description: "sunny",
+			}},
+		},
+	}}
+
+	tcs := []struct {
+		name    string
+		encoder Encoder
+		want    string
+	}{
+		{
+			name:    "csv",
+			encoder: CSV{},
+			want: "city,startTime,endTime,description,temperature,temperatureUnit,precipitationProbability,stale\n" +
+				"london,2024-09-23T06:00:00Z,2024-09-23T18:00:00Z,\"Rain, then \"\"gray\"\"\",18.5,C,40,false\n" +
+				"paris,2024-09-23T06:00:00Z,2024-09-23T18:00:00Z,sunny,,,,true\n",
+		},
+		{
+			name:    "xml",
+			encoder: XML{},
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<weatherForecast>
+  <forecast name="london">
+    <period start="2024-09-23T06:00:00Z" end="2024-09-23T18:00:00Z">
+      <description>Rain, then &#34;gray&#34;</description>
+      <temperature unit="C">18.5</temperature>
+      <precipitationProbability unit="%">40</precipitationProbability>
+    </period>
+  </forecast>
+  <forecast name="paris" stale="true">
+    <period start="2024-09-23T06:00:00Z" end="2024-09-23T18:00:00Z">
+      <description>sunny</description>
+    </period>
+  </forecast>
+</weatherForecast>
+`,
+		},
+		{
+			name:    "text",
+			encoder: Text{},
+			want: "CITY           START                 END                   TEMP    PRECIP  DESCRIPTION\n" +
+				"london         Mon Sep 23 06:00 UTC  Mon Sep 23 18:00 UTC  18.5 C  40%     Rain, then \"gray\"\n" +
+				"paris (stale)  Mon Sep 23 06:00 UTC  Mon Sep 23 18:00 UTC  -       -       sunny\n",
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, tc.encoder.Encode(&buf, m))
+			require.Equal(t, tc.want, buf.String())
+		})
+	}
+}
+
+func TestCSVFormulas(t *testing.T) {
+	start := time.Date(2024, 9, 23, 6, 0, 0, 0, time.UTC)
+	temperature := -3.5
+	m := model.WeatherForecast{Forecast: []model.Forecast{{
+		Name: "=1+2",
+		Detail: []model.Detail{
+			{StartTime: start, EndTime: start, Description: "+1 inch of snow", Temperature: &temperature, TemperatureUnit: "@F"},
+			{StartTime: start, EndTime: start, Description: "-cold"},
+			{StartTime: start, EndTime: start, Description: "\tsunny"},
+		},
+	}}}
+
+	var buf bytes.Buffer
+	require.NoError(t, CSV{}.Encode(&buf, m))
+	require.Equal(t, "city,startTime,endTime,description,temperature,temperatureUnit,precipitationProbability,stale\n"+
+		"'=1+2,2024-09-23T06:00:00Z,2024-09-23T06:00:00Z,'+1 inch of snow,-3.5,'@F,,false\n"+
+		"'=1+2,2024-09-23T06:00:00Z,2024-09-23T06:00:00Z,'-cold,,,,false\n"+
+		"'=1+2,2024-09-23T06:00:00Z,2024-09-23T06:00:00Z,'\tsunny,,,,false\n", buf.String())
+}
+
+func TestGeoJSON(t *testing.T) {
+	start := time.Date(2024, 9, 23, 6, 0, 0, 0, time.UTC)
+	m := model.WeatherForecast{Forecast: []model.Forecast{
+		{
+			Name: "london",
+			Location: &model.Location{PlaceID: 1, OsmType: "relation", OsmID: 65606, Lat: "51.5073", Lon: "-0.1276",
+				DisplayName: "London, Greater London, England, United Kingdom",
+				BoundingBox: []string{"51.2867", "51.6918", "-0.5103", "0.3340"}},
+			Detail: []model.Detail{{StartTime: start, EndTime: start.Add(12 * time.Hour), Description: "gray"}},
+		},
+		{
+			Name:     "white house",
+			Stale:    true,
+			Location: &model.Location{Lat: "38.8951", Lon: "-77.0364", DisplayName: "white house"},
+		},
+		{Name: "atlantis"},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, GeoJSON{}.Encode(&buf, m))
+	require.JSONEq(t, `{
+		"type": "FeatureCollection",
+		"bbox": [-77.0364, 38.8951, 0.334, 51.6918],
+		"features": [
+			{
+				"type": "Feature",
+				"bbox": [-0.5103, 51.2867, 0.334, 51.6918],
+				"geometry": {"type": "Point", "coordinates": [-0.1276, 51.5073]},
+				"properties": {"name": "london", "displayName": "London, Greater London, England, United Kingdom",
+					"placeId": 1, "osmType": "relation", "osmId": 65606, "stale": false,
+					"periods": [{"start": "2024-09-23T06:00:00Z", "end": "2024-09-23T18:00:00Z", "description": "gray"}]}
+			},
+			{
+				"type": "Feature",
+				"geometry": {"type": "Point", "coordinates": [-77.0364, 38.8951]},
+				"properties": {"name": "white house", "displayName": "white house", "stale": true, "periods": []}
+			},
+			{
+				"type": "Feature",
+				"geometry": null,
+				"properties": {"name": "atlantis", "stale": false, "periods": []}
+			}
+		]
+	}`, buf.String())
+}
+
+func TestCalendar(t *testing.T) {
+	nowFunc = func() time.Time { return time.Date(2024, 9, 23, 5, 0, 0, 0, time.UTC) }
+	defer func() { nowFunc = time.Now }()
+
+	chicago := time.FixedZone("CDT", -5*60*60)
+	start := time.Date(2024, 9, 23, 6, 0, 0, 0, chicago)
+	m := model.WeatherForecast{Forecast: []model.Forecast{{
+		Name: "St. Louis, MO",
+		Detail: []model.Detail{
+			{
+				StartTime:   start,
+				EndTime:     start.Add(12 * time.Hour),
+				Summary:     "Chance Rain Showers",
+				Description: "A chance of rain showers; mostly cloudy, with a high near 75.\nSouth wind 5 to 10 mph, with gusts as high as 20 mph.",
+			},
+			{
+				StartTime:   start.Add(12 * time.Hour),
+				EndTime:     start.Add(24 * time.Hour),
+				Description: "Clear",
+			},
+		},
+	}}}
+
+	var buf bytes.Buffer
+	require.NoError(t, Calendar{}.Encode(&buf, m))
+	require.Equal(t, "BEGIN:VCALENDAR\r\n"+
+		"VERSION:2.0\r\n"+
+		"PRODID:-//ennismore//weather-app//EN\r\n"+
+		"CALSCALE:GREGORIAN\r\n"+
+		"METHOD:PUBLISH\r\n"+
+		"X-WR-CALNAME:Weather forecast\r\n"+
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H\r\n"+
+		"BEGIN:VEVENT\r\n"+
+		"UID:st-louis-mo-20240923-day@ennismore-weather-app\r\n"+
+		"DTSTAMP:20240923T050000Z\r\n"+
+		"DTSTART:20240923T110000Z\r\n"+
+		"DTEND:20240923T230000Z\r\n"+
+		"SUMMARY:St. Louis\\, MO: Chance Rain Showers\r\n"+
+		"DESCRIPTION:A chance of rain showers\\; mostly cloudy\\, with a high near 75.\r\n"+
+		" \\nSouth wind 5 to 10 mph\\, with gusts as high as 20 mph.\r\n"+
+		"LOCATION:St. Louis\\, MO\r\n"+
+		"TRANSP:TRANSPARENT\r\n"+
+		"END:VEVENT\r\n"+
+		"BEGIN:VEVENT\r\n"+
+		"UID:st-louis-mo-20240923-night@ennismore-weather-app\r\n"+
+		"DTSTAMP:20240923T050000Z\r\n"+
+		"DTSTART:20240923T230000Z\r\n"+
+		"DTEND:20240924T110000Z\r\n"+
+		"SUMMARY:St. Louis\\, MO: Clear\r\n"+
+		"DESCRIPTION:Clear\r\n"+
+		"LOCATION:St. Louis\\, MO\r\n"+
+		"TRANSP:TRANSPARENT\r\n"+
+		"END:VEVENT\r\n"+
+		"END:VCALENDAR\r\n", buf.String())
+}
+
+func TestEventUID(t *testing.T) {
+	chicago := time.FixedZone("CDT", -5*60*60)
+	tcs := []struct {
+		name  string
+		start time.Time
+		want  string
+	}{
+		{name: "when the day starts should be the day", start: time.Date(2024, 9, 23, 6, 0, 0, 0, chicago), want: "chicago-20240923-day@ennismore-weather-app"},
+		{name: "when the current period moved forward should keep the day", start: time.Date(2024, 9, 23, 14, 0, 0, 0, chicago), want: "chicago-20240923-day@ennismore-weather-app"},
+		{name: "when the night starts should be the night", start: time.Date(2024, 9, 23, 18, 0, 0, 0, chicago), want: "chicago-20240923-night@ennismore-weather-app"},
+		{name: "when the night moved past midnight should keep the night", start: time.Date(2024, 9, 24, 1, 0, 0, 0, chicago), want: "chicago-20240923-night@ennismore-weather-app"},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, eventUID("Chicago", tc.start))
+		})
+	}
+}
+
+func TestCalendarFolding(t *testing.T) {
+	var buf bytes.Buffer
+	cw := &calendarWriter{w: bufio.NewWriter(&buf)}
+	// a 2 octets rune sits across the 75th octet
+	cw.line("DESCRIPTION:" + strings.Repeat("a", 62) + "é" + strings.Repeat("b", 80))
+	require.NoError(t, cw.err)
+	require.NoError(t, cw.w.Flush())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	require.Len(t, lines, 3)
+	for i, l := range lines {
+		require.LessOrEqual(t, len(l), maxLineOctets)
+		require.True(t, utf8.ValidString(l))
+		if i > 0 {
+			require.True(t, strings.HasPrefix(l, " "))
+		}
+	}
+	require.Equal(t, "DESCRIPTION:"+strings.Repeat("a", 62), lines[0])
+}
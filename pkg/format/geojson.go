@@ -0,0 +1,124 @@
+package format
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+
+	v2 "github.com/dibrito/ennismore-weather-app/pkg/api/v2"
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+)
+
+// GeoJSON encodes an RFC 7946 FeatureCollection with a Point feature per
+// city, the v2 forecast periods and alerts are the feature properties. Cities
+// without a resolved location have a null geometry.
+type GeoJSON struct{}
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	BBox     []float64 `json:"bbox,omitempty"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string            `json:"type"`
+	BBox       []float64         `json:"bbox,omitempty"`
+	Geometry   *point            `json:"geometry"`
+	Properties featureProperties `json:"properties"`
+}
+
+type point struct {
+	Type string `json:"type"`
+	// Coordinates are longitude and latitude, RFC 7946 3.1.1.
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type featureProperties struct {
+	Name        string          `json:"name"`
+	DisplayName string          `json:"displayName,omitempty"`
+	PlaceID     int             `json:"placeId,omitempty"`
+	OsmType     string          `json:"osmType,omitempty"`
+	OsmID       int             `json:"osmId,omitempty"`
+	Stale       bool            `json:"stale"`
+	Distance    *v2.Measurement `json:"distance,omitempty"`
+	Bearing     *v2.Measurement `json:"bearing,omitempty"`
+	Periods     []v2.Period     `json:"periods"`
+	Alerts      []v2.Alert      `json:"alerts,omitempty"`
+}
+
+func (GeoJSON) ContentType() string { return "application/geo+json" }
+
+func (GeoJSON) Encode(w io.Writer, m model.WeatherForecast) error {
+	fc := featureCollection{Type: "FeatureCollection", Features: make([]feature, 0, len(m.Forecast))}
+	for _, f := range m.Forecast {
+		v := v2.NewForecast(f)
+		ft := feature{Type: "Feature", Properties: featureProperties{
+			Name:     f.Name,
+			Stale:    v.Stale,
+			Distance: v.Distance,
+			Bearing:  v.Bearing,
+			Periods:  v.Periods,
+			Alerts:   v.Alerts,
+		}}
+		if l := f.Location; l != nil {
+			ft.Properties.DisplayName = l.DisplayName
+			ft.Properties.PlaceID, ft.Properties.OsmType, ft.Properties.OsmID = l.PlaceID, l.OsmType, l.OsmID
+			ft.Geometry = locationPoint(*l)
+			ft.BBox = locationBBox(*l)
+		}
+		fc.BBox = union(fc.BBox, ft.BBox, ft.Geometry)
+		fc.Features = append(fc.Features, ft)
+	}
+	return json.NewEncoder(w).Encode(fc)
+}
+
+// locationPoint returns the point of a location, nil when its coordinates
+// can't be parsed.
+func locationPoint(l model.Location) *point {
+	lat, err := strconv.ParseFloat(l.Lat, 64)
+	if err != nil {
+		return nil
+	}
+	lon, err := strconv.ParseFloat(l.Lon, 64)
+	if err != nil {
+		return nil
+	}
+	return &point{Type: "Point", Coordinates: [2]float64{lon, lat}}
+}
+
+// locationBBox maps the Nominatim bounding box, south, north, west and east,
+// into the GeoJSON west, south, east and north order. It is nil when the box
+// is missing or can't be parsed.
+func locationBBox(l model.Location) []float64 {
+	if len(l.BoundingBox) != 4 {
+		return nil
+	}
+	var box [4]float64
+	for i, v := range l.BoundingBox {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil
+		}
+		box[i] = f
+	}
+	south, north, west, east := box[0], box[1], box[2], box[3]
+	return []float64{west, south, east, north}
+}
+
+// union extends the collection bbox with the feature bbox, or its point when
+// it has no bbox.
+func union(bbox, featureBBox []float64, p *point) []float64 {
+	if featureBBox == nil && p != nil {
+		featureBBox = []float64{p.Coordinates[0], p.Coordinates[1], p.Coordinates[0], p.Coordinates[1]}
+	}
+	if featureBBox == nil {
+		return bbox
+	}
+	if bbox == nil {
+		return append([]float64(nil), featureBBox...)
+	}
+	return []float64{
+		min(bbox[0], featureBBox[0]), min(bbox[1], featureBBox[1]),
+		max(bbox[2], featureBBox[2]), max(bbox[3], featureBBox[3]),
+	}
+}
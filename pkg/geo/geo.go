@@ -0,0 +1,291 @@
+// Package geo holds the geographic helpers of the weather-app, e.g. bounding
+// boxes and the spatial index over the known locations.
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Point is a WGS 84 coordinate in degrees.
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// ParsePoint parses the string coordinates of OpenStreetMap locations.
+func ParsePoint(lat, lon string) (Point, error) {
+	p := Point{}
+	var err error
+	if p.Lat, err = strconv.ParseFloat(lat, 64); err != nil {
+		return Point{}, fmt.Errorf("invalid latitude %q", lat)
+	}
+	if p.Lon, err = strconv.ParseFloat(lon, 64); err != nil {
+		return Point{}, fmt.Errorf("invalid longitude %q", lon)
+	}
+	return p, p.Validate()
+}
+
+// Validate checks the point is within the WGS 84 range.
+func (p Point) Validate() error {
+	if p.Lat < -90 || p.Lat > 90 {
+		return fmt.Errorf("latitude %v must be between -90 and 90", p.Lat)
+	}
+	if p.Lon < -180 || p.Lon > 180 {
+		return fmt.Errorf("longitude %v must be between -180 and 180", p.Lon)
+	}
+	return nil
+}
+
+// BBox is a bounding box, boxes crossing the antimeridian are not supported.
+type BBox struct {
+	MinLon float64
+	MinLat float64
+	MaxLon float64
+	MaxLat float64
+}
+
+// ParseBBox parses a minLon,minLat,maxLon,maxLat bounding box.
+func ParseBBox(s string) (BBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return BBox{}, errors.New("bbox must be minLon,minLat,maxLon,maxLat")
+	}
+	var values [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return BBox{}, fmt.Errorf("invalid bbox coordinate %q", part)
+		}
+		values[i] = v
+	}
+	b := BBox{MinLon: values[0], MinLat: values[1], MaxLon: values[2], MaxLat: values[3]}
+	for _, p := range []Point{{Lat: b.MinLat, Lon: b.MinLon}, {Lat: b.MaxLat, Lon: b.MaxLon}} {
+		if err := p.Validate(); err != nil {
+			return BBox{}, err
+		}
+	}
+	if b.MinLon > b.MaxLon || b.MinLat > b.MaxLat {
+		return BBox{}, errors.New("bbox min coordinates must not exceed the max ones")
+	}
+	return b, nil
+}
+
+// Contains reports whether the point is within the box, edges included.
+func (b BBox) Contains(p Point) bool {
+	return p.Lat >= b.MinLat && p.Lat <= b.MaxLat && p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+}
+
+// Center returns the center of the box.
+func (b BBox) Center() Point {
+	return Point{Lat: (b.MinLat + b.MaxLat) / 2, Lon: (b.MinLon + b.MaxLon) / 2}
+}
+
+// cellSize is the side in degrees of the index cells, about 110km at the
+// equator so a city view only scans a handful of cells.
+const cellSize = 1.0
+
+type cell struct{ x, y int }
+
+func cellOf(p Point) cell {
+	return cell{x: int(math.Floor(p.Lon / cellSize)), y: int(math.Floor(p.Lat / cellSize))}
+}
+
+// Index is a grid index of keyed points, it is not safe for concurrent use.
+type Index struct {
+	points map[string]Point
+	cells  map[cell]map[string]struct{}
+}
+
+// NewIndex creates an empty index.
+func NewIndex() *Index {
+	return &Index{points: make(map[string]Point), cells: make(map[cell]map[string]struct{})}
+}
+
+// Put adds the point of key, replacing its previous point.
+func (idx *Index) Put(key string, p Point) {
+	idx.Delete(key)
+	idx.points[key] = p
+	c := cellOf(p)
+	if idx.cells[c] == nil {
+		idx.cells[c] = make(map[string]struct{})
+	}
+	idx.cells[c][key] = struct{}{}
+}
+
+// Delete removes the point of key.
+func (idx *Index) Delete(key string) {
+	p, ok := idx.points[key]
+	if !ok {
+		return
+	}
+	delete(idx.points, key)
+	c := cellOf(p)
+	delete(idx.cells[c], key)
+	if len(idx.cells[c]) == 0 {
+		delete(idx.cells, c)
+	}
+}
+
+// Len returns how many points are indexed.
+func (idx *Index) Len() int {
+	return len(idx.points)
+}
+
+// Search returns the keys of the points within the box, the closest to its
+// center first. Keys sharing a point are aliases of the same place, only the
+// preferred one is returned, see preferred.
+func (idx *Index) Search(b BBox) []string {
+	lo, hi := cellOf(Point{Lat: b.MinLat, Lon: b.MinLon}), cellOf(Point{Lat: b.MaxLat, Lon: b.MaxLon})
+	var keys []string
+	// large boxes have more cells than points, scan the points instead
+	if (hi.x-lo.x+1)*(hi.y-lo.y+1) > len(idx.cells) {
+		for key, p := range idx.points {
+			if b.Contains(p) {
+				keys = append(keys, key)
+			}
+		}
+	} else {
+		for x := lo.x; x <= hi.x; x++ {
+			for y := lo.y; y <= hi.y; y++ {
+				for key := range idx.cells[cell{x: x, y: y}] {
+					if b.Contains(idx.points[key]) {
+						keys = append(keys, key)
+					}
+				}
+			}
+		}
+	}
+
+	center := b.Center()
+	sort.Slice(keys, func(i, j int) bool {
+		di, dj := planarDistance(center, idx.points[keys[i]]), planarDistance(center, idx.points[keys[j]])
+		if di != dj {
+			return di < dj
+		}
+		return preferred(keys[i], keys[j])
+	})
+	return idx.dedupe(keys)
+}
+
+// preferred orders the keys of a point, the shortest first so plain names win
+// over the ones with a country or coordinates.
+func preferred(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// dedupe drops the keys of points already seen, keeping the order.
+func (idx *Index) dedupe(keys []string) []string {
+	seen := make(map[Point]struct{}, len(keys))
+	result := keys[:0]
+	for _, key := range keys {
+		p := idx.points[key]
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		result = append(result, key)
+	}
+	return result
+}
+
+// Neighbor is an indexed point near a searched point, Distance is in km and
+// Bearing is the initial bearing from the searched point in degrees.
+type Neighbor struct {
+	Key      string
+	Distance float64
+	Bearing  float64
+}
+
+// Nearest returns the n closest points within radius km of p sorted by their
+// great-circle distance, n zero means all of them. Keys sharing a point are
+// returned once as on Search.
+func (idx *Index) Nearest(p Point, radius float64, n int) []Neighbor {
+	// the cells of the box around the radius, a degree of latitude is about
+	// 111km while the circle widens in longitude towards the poles, up to
+	// every longitude once it covers a pole
+	latDelta := radius / kmPerDegree
+	lonDelta := 180.0
+	if ratio := math.Sin(radius/earthRadius) / math.Cos(radians(p.Lat)); ratio >= 0 && ratio < 1 && radius < earthRadius*math.Pi/2 {
+		lonDelta = math.Asin(ratio) * 180 / math.Pi
+	}
+	var keys []string
+	if p.Lat-latDelta < -90 || p.Lat+latDelta > 90 || p.Lon-lonDelta < -180 || p.Lon+lonDelta > 180 {
+		// the box wraps a pole or the antimeridian, scan the points instead
+		for key := range idx.points {
+			keys = append(keys, key)
+		}
+	} else {
+		keys = idx.Search(BBox{MinLon: p.Lon - lonDelta, MinLat: p.Lat - latDelta, MaxLon: p.Lon + lonDelta, MaxLat: p.Lat + latDelta})
+	}
+
+	var neighbors []Neighbor
+	for _, key := range keys {
+		q := idx.points[key]
+		if d := Distance(p, q); d <= radius {
+			neighbors = append(neighbors, Neighbor{Key: key, Distance: d, Bearing: Bearing(p, q)})
+		}
+	}
+	sort.Slice(neighbors, func(i, j int) bool {
+		if neighbors[i].Distance != neighbors[j].Distance {
+			return neighbors[i].Distance < neighbors[j].Distance
+		}
+		return preferred(neighbors[i].Key, neighbors[j].Key)
+	})
+	seen := make(map[Point]struct{}, len(neighbors))
+	deduped := neighbors[:0]
+	for _, neighbor := range neighbors {
+		q := idx.points[neighbor.Key]
+		if _, ok := seen[q]; ok {
+			continue
+		}
+		seen[q] = struct{}{}
+		deduped = append(deduped, neighbor)
+	}
+	neighbors = deduped
+	if n > 0 && len(neighbors) > n {
+		neighbors = neighbors[:n]
+	}
+	return neighbors
+}
+
+// earthRadius is the mean Earth radius in km.
+const earthRadius = 6371.0088
+
+// kmPerDegree is the length of a degree of latitude in km.
+const kmPerDegree = earthRadius * math.Pi / 180
+
+// Distance returns the great-circle distance in km between a and b, using the
+// haversine formula.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat, dLon := lat2-lat1, radians(b.Lon-a.Lon)
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Bearing returns the initial bearing from a to b in degrees clockwise from
+// north, between 0 and 360.
+func Bearing(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLon := radians(b.Lon - a.Lon)
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// planarDistance is enough to order the points of a box by closeness.
+func planarDistance(a, b Point) float64 {
+	return math.Hypot(a.Lat-b.Lat, a.Lon-b.Lon)
+}
@@ -0,0 +1,118 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBBox(t *testing.T) {
+	tcs := []struct {
+		name    string
+		bbox    string
+		want    BBox
+		wantErr string
+	}{
+		{name: "valid", bbox: "-0.51, 51.28,0.33,51.69", want: BBox{MinLon: -0.51, MinLat: 51.28, MaxLon: 0.33, MaxLat: 51.69}},
+		{name: "missing coordinate", bbox: "-0.51,51.28,0.33", wantErr: "bbox must be minLon,minLat,maxLon,maxLat"},
+		{name: "not a number", bbox: "-0.51,north,0.33,51.69", wantErr: `invalid bbox coordinate "north"`},
+		{name: "out of range", bbox: "-0.51,51.28,0.33,91", wantErr: "latitude 91 must be between -90 and 90"},
+		{name: "min over max", bbox: "0.33,51.28,-0.51,51.69", wantErr: "bbox min coordinates must not exceed the max ones"},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseBBox(tc.bbox)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	idx := NewIndex()
+	idx.Put("london", Point{Lat: 51.5073, Lon: -0.1276})
+	idx.Put("croydon", Point{Lat: 51.3762, Lon: -0.0982})
+	idx.Put("watford", Point{Lat: 51.6565, Lon: -0.3903})
+	idx.Put("paris", Point{Lat: 48.8566, Lon: 2.3522})
+	idx.Put("chicago", Point{Lat: 41.8781, Lon: -87.6298})
+
+	greaterLondon := BBox{MinLon: -0.51, MinLat: 51.28, MaxLon: 0.33, MaxLat: 51.69}
+	require.Equal(t, []string{"london", "croydon", "watford"}, idx.Search(greaterLondon))
+
+	// moving a key drops it from its previous cell
+	idx.Put("watford", Point{Lat: 40.7128, Lon: -74.0060})
+	require.Equal(t, []string{"london", "croydon"}, idx.Search(greaterLondon))
+
+	idx.Delete("croydon")
+	require.Equal(t, []string{"london"}, idx.Search(greaterLondon))
+	require.Equal(t, 4, idx.Len())
+
+	// the whole world has more cells than points
+	world := BBox{MinLon: -180, MinLat: -90, MaxLon: 180, MaxLat: 90}
+	require.ElementsMatch(t, []string{"london", "watford", "paris", "chicago"}, idx.Search(world))
+	require.Empty(t, idx.Search(BBox{MinLon: 10, MinLat: 10, MaxLon: 11, MaxLat: 11}))
+
+	// aliases of the same place are returned once, under the shortest key
+	idx.Put("london|gb", Point{Lat: 51.5073, Lon: -0.1276})
+	idx.Put("51.5073,-0.1276", Point{Lat: 51.5073, Lon: -0.1276})
+	require.Equal(t, []string{"london"}, idx.Search(greaterLondon))
+}
+
+func TestDistanceAndBearing(t *testing.T) {
+	london := Point{Lat: 51.5073, Lon: -0.1276}
+	paris := Point{Lat: 48.8566, Lon: 2.3522}
+	require.InDelta(t, 343.5, Distance(london, paris), 0.5)
+	require.InDelta(t, 148.1, Bearing(london, paris), 0.5)
+	require.InDelta(t, 330.2, Bearing(paris, london), 0.5)
+	require.Zero(t, Distance(london, london))
+
+	// across the antimeridian
+	require.InDelta(t, 111.2, Distance(Point{Lon: 179.5}, Point{Lon: -179.5}), 0.5)
+	require.InDelta(t, 90, Bearing(Point{Lon: 179.5}, Point{Lon: -179.5}), 0.1)
+}
+
+func TestNearest(t *testing.T) {
+	idx := NewIndex()
+	idx.Put("london", Point{Lat: 51.5073, Lon: -0.1276})
+	idx.Put("croydon", Point{Lat: 51.3762, Lon: -0.0982})
+	idx.Put("watford", Point{Lat: 51.6565, Lon: -0.3903})
+	idx.Put("paris", Point{Lat: 48.8566, Lon: 2.3522})
+	idx.Put("suva", Point{Lat: -18.1416, Lon: 178.4419})
+	idx.Put("apia", Point{Lat: -13.8333, Lon: -171.7667})
+
+	keys := func(neighbors []Neighbor) []string {
+		var keys []string
+		for _, n := range neighbors {
+			keys = append(keys, n.Key)
+		}
+		return keys
+	}
+	charingCross := Point{Lat: 51.5080, Lon: -0.1247}
+	require.Equal(t, []string{"london", "croydon", "watford"}, keys(idx.Nearest(charingCross, 50, 0)))
+	require.Equal(t, []string{"london", "croydon"}, keys(idx.Nearest(charingCross, 50, 2)))
+	require.Equal(t, []string{"london", "croydon", "watford", "paris"}, keys(idx.Nearest(charingCross, 500, 0)))
+	require.Empty(t, idx.Nearest(Point{Lat: 10, Lon: 10}, 50, 0))
+
+	got := idx.Nearest(charingCross, 50, 1)
+	require.InDelta(t, 0.2, got[0].Distance, 0.05)
+	require.InDelta(t, 248.8, got[0].Bearing, 0.5)
+
+	// radius across the antimeridian
+	require.Equal(t, []string{"suva", "apia"}, keys(idx.Nearest(Point{Lat: -17, Lon: 179.9}, 1500, 0)))
+
+	// the circle is wider in longitude than radius/cos(lat) at high latitudes
+	idx.Put("svalbard", Point{Lat: 81, Lon: 46.5})
+	require.Equal(t, []string{"svalbard"}, keys(idx.Nearest(Point{Lat: 80, Lon: 20}, 500, 0)))
+	// and covers every longitude around the pole
+	idx.Put("alert", Point{Lat: 82.5, Lon: -62.3})
+	require.Equal(t, []string{"alert", "svalbard"}, keys(idx.Nearest(Point{Lat: 88, Lon: 0}, 1000, 0)))
+
+	// aliases of the same place are returned once, under the shortest key
+	idx.Put("london|gb", Point{Lat: 51.5073, Lon: -0.1276})
+	require.Equal(t, []string{"london", "croydon"}, keys(idx.Nearest(charingCross, 50, 2)))
+}
@@ -0,0 +1,83 @@
+package iso8601
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseInterval parses an ISO 8601 time interval in the <start>/<duration>
+// form used by weather.gov gridpoints, e.g. 2024-09-23T18:00:00+00:00/PT3H,
+// the <start>/<end> form is also accepted.
+func ParseInterval(s string) (time.Time, time.Duration, error) {
+	startStr, endStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("invalid interval %q: missing '/'", s)
+	}
+	start, err := time.Parse(time.RFC3339, startStr)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid interval %q: %w", s, err)
+	}
+	if !strings.HasPrefix(endStr, "P") {
+		end, err := time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			return time.Time{}, 0, fmt.Errorf("invalid interval %q: %w", s, err)
+		}
+		return start, end.Sub(start), nil
+	}
+	d, err := ParseDuration(endStr)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid interval %q: %w", s, err)
+	}
+	return start, d, nil
+}
+
+// ParseDuration parses an ISO 8601 duration made of days, hours, minutes and
+// seconds e.g. P1DT6H or PT30M. Years, months and weeks are not supported since
+// they don't map to a fixed time.Duration.
+func ParseDuration(s string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(s, "P")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var d time.Duration
+	inTime := false
+	for rest != "" {
+		if rest[0] == 'T' {
+			// the time designator must be followed by a component, once
+			if inTime || len(rest) == 1 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			inTime = true
+			rest = rest[1:]
+			continue
+		}
+		i := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if i <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n, err := strconv.ParseFloat(rest[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+
+		var unit time.Duration
+		switch {
+		case !inTime && rest[i] == 'D':
+			unit = 24 * time.Hour
+		case inTime && rest[i] == 'H':
+			unit = time.Hour
+		case inTime && rest[i] == 'M':
+			unit = time.Minute
+		case inTime && rest[i] == 'S':
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q: unsupported designator %q", s, rest[i])
+		}
+		d += time.Duration(n * float64(unit))
+		rest = rest[i+1:]
+	}
+	return d, nil
+}
@@ -0,0 +1,80 @@
+package iso8601
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInterval(t *testing.T) {
+	tcs := []struct {
+		name      string
+		interval  string
+		wantStart time.Time
+		wantDur   time.Duration
+		wantErr   bool
+	}{
+		{
+			name:      "when start and hours duration should parse",
+			interval:  "2024-09-23T18:00:00+00:00/PT3H",
+			wantStart: time.Date(2024, 9, 23, 18, 0, 0, 0, time.UTC),
+			wantDur:   3 * time.Hour,
+		},
+		{
+			name:      "when start and days with hours duration should parse",
+			interval:  "2024-09-23T18:00:00+00:00/P1DT6H",
+			wantStart: time.Date(2024, 9, 23, 18, 0, 0, 0, time.UTC),
+			wantDur:   30 * time.Hour,
+		},
+		{
+			name:      "when start and end should parse",
+			interval:  "2024-09-23T18:00:00+00:00/2024-09-23T20:30:00+00:00",
+			wantStart: time.Date(2024, 9, 23, 18, 0, 0, 0, time.UTC),
+			wantDur:   150 * time.Minute,
+		},
+		{
+			name:     "when missing duration should fail",
+			interval: "2024-09-23T18:00:00+00:00",
+			wantErr:  true,
+		},
+		{
+			name:     "when hours without time designator should fail",
+			interval: "2024-09-23T18:00:00+00:00/P3H",
+			wantErr:  true,
+		},
+		{
+			name:     "when empty duration should fail",
+			interval: "2024-09-23T18:00:00+00:00/P",
+			wantErr:  true,
+		},
+		{
+			name:     "when empty time duration should fail",
+			interval: "2024-09-23T18:00:00+00:00/PT",
+			wantErr:  true,
+		},
+		{
+			name:     "when time designator without time components should fail",
+			interval: "2024-09-23T18:00:00+00:00/P1DT",
+			wantErr:  true,
+		},
+		{
+			name:     "when months duration should fail",
+			interval: "2024-09-23T18:00:00+00:00/P1M",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			start, d, err := ParseInterval(tc.interval)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tc.wantStart.Equal(start))
+			require.Equal(t, tc.wantDur, d)
+		})
+	}
+}
@@ -1,6 +1,97 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//	ennismore-weather-app requests:
+//
+// ForecastRequest represent the body of batch forecast requests.
+type ForecastRequest struct {
+	Locations []LocationQuery `json:"locations"`
+	Options   RequestOptions  `json:"options"`
+}
+
+// LocationQuery represent a requested location, either a name with an optional
+// ISO 3166-1 alpha-2 country code or a pair of coordinates.
+type LocationQuery struct {
+	Name    string   `json:"name,omitempty"`
+	Country string   `json:"country,omitempty"`
+	Lat     *float64 `json:"lat,omitempty"`
+	Lon     *float64 `json:"lon,omitempty"`
+}
+
+// HasCoordinates reports whether the location was requested by coordinates.
+func (q LocationQuery) HasCoordinates() bool {
+	return q.Lat != nil && q.Lon != nil
+}
+
+// Key returns the key used to cache the location.
+func (q LocationQuery) Key() string {
+	if q.HasCoordinates() {
+		return fmt.Sprintf("%.4f,%.4f", *q.Lat, *q.Lon)
+	}
+	if q.Country != "" {
+		return q.Name + "|" + strings.ToLower(q.Country)
+	}
+	return q.Name
+}
+
+// ParseLocationKey returns the query of a location cache key, see Key.
+func ParseLocationKey(key string) LocationQuery {
+	if name, country, ok := strings.Cut(key, "|"); ok {
+		return LocationQuery{Name: name, Country: country}
+	}
+	if lat, lon, ok := strings.Cut(key, ","); ok {
+		latValue, latErr := strconv.ParseFloat(lat, 64)
+		lonValue, lonErr := strconv.ParseFloat(lon, 64)
+		if latErr == nil && lonErr == nil {
+			return LocationQuery{Lat: &latValue, Lon: &lonValue}
+		}
+	}
+	return LocationQuery{Name: key}
+}
+
+// DisplayName returns the location name used on responses.
+func (q LocationQuery) DisplayName() string {
+	if q.Name != "" {
+		return q.Name
+	}
+	return q.Key()
+}
+
+// RequestOptions represent the per-request options of batch requests.
+type RequestOptions struct {
+	Days    int      `json:"days,omitempty"`
+	Units   string   `json:"units,omitempty"`
+	Include []string `json:"include,omitempty"`
+}
+
+// ItineraryRequest represent the body of itinerary requests, a leg per stop
+// of the trip. Units converts the temperatures and wind speeds.
+type ItineraryRequest struct {
+	Legs  []ItineraryLeg `json:"legs"`
+	Units string         `json:"units,omitempty"`
+}
+
+// ItineraryLeg represent a stop of an itinerary, the location is forecast
+// from From to To, both YYYY-MM-DD days included.
+type ItineraryLeg struct {
+	Location LocationQuery `json:"location"`
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+}
+
+// LiveRequest represent the messages sent by WebSocket clients, action is
+// either subscribe or unsubscribe.
+type LiveRequest struct {
+	Action string   `json:"action"`
+	Cities []string `json:"cities"`
+}
 
 //	ennismore-weather-app response:
 //
@@ -13,13 +104,233 @@ type WeatherForecast struct {
 type Forecast struct {
 	Name   string   `json:"name"`
 	Detail []Detail `json:"detail"`
+	Alerts []Alert  `json:"alerts,omitempty"`
+	// Stale is set when the forecast is served past its cache TTL.
+	Stale bool `json:"stale,omitempty"`
+	// Distance and Bearing are set on nearby searches, they go from the
+	// searched point to the location.
+	Distance *Measurement `json:"distance,omitempty"`
+	Bearing  *Measurement `json:"bearing,omitempty"`
+	// Location is the resolved location of the forecast, it is only used by
+	// the encoders that place the forecasts on a map.
+	Location *Location `json:"-"`
+	// Cache and Age report where the forecast came from (HIT, MISS or STALE)
+	// and its age in seconds, they are sent as the X-Cache and Age headers.
+	Cache string `json:"-"`
+	Age   int64  `json:"-"`
 }
 
 // Detail represent the inner details of a forecast
 type Detail struct {
-	StartTime   time.Time `json:"startTime"`
-	EndTime     time.Time `json:"endTime"`
-	Description string    `json:"description"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	// Summary is the short forecast, e.g. Chance Rain Showers.
+	Summary         string   `json:"summary,omitempty"`
+	Description     string   `json:"description"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TemperatureUnit string   `json:"temperatureUnit,omitempty"`
+	// PrecipitationProbability is a percentage, nil when not forecast.
+	PrecipitationProbability *float64 `json:"precipitationProbability,omitempty"`
+	// WindSpeed is the highest forecast wind speed, nil when not forecast.
+	WindSpeed *Measurement `json:"windSpeed,omitempty"`
+}
+
+// StreamError represent a location that could not be forecast on streamed responses.
+type StreamError struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// Problem represent an RFC 7807 problem details error response, Errors holds
+// the per-field validation errors.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError represent an invalid request field, e.g. locations[0].country.
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// Rule represent a webhook notification rule, the URL is called when the
+// condition is met for the city.
+type Rule struct {
+	ID string `json:"id"`
+	// Owner is the ID of the API key that created the rule, only its key
+	// can see and change it. It is empty when auth is disabled.
+	Owner     string        `json:"owner,omitempty"`
+	City      string        `json:"city"`
+	URL       string        `json:"url"`
+	Condition RuleCondition `json:"condition"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+// RuleCondition represent when a rule is triggered, either a precipitation
+// probability at or over Threshold within the next WithinHours or an active
+// alert at least as severe as Severity.
+type RuleCondition struct {
+	Type        string  `json:"type"`
+	Threshold   float64 `json:"threshold,omitempty"`
+	WithinHours int     `json:"withinHours,omitempty"`
+	Severity    string  `json:"severity,omitempty"`
+}
+
+// RuleEvent represent the webhook payload sent when a rule is triggered.
+type RuleEvent struct {
+	RuleID      string        `json:"ruleId"`
+	City        string        `json:"city"`
+	Condition   RuleCondition `json:"condition"`
+	Reason      string        `json:"reason"`
+	TriggeredAt time.Time     `json:"triggeredAt"`
+	Periods     []Period      `json:"periods,omitempty"`
+	Alerts      []Alert       `json:"alerts,omitempty"`
+}
+
+// DeadLetter represent a webhook delivery that failed after all retries.
+type DeadLetter struct {
+	RuleID   string    `json:"ruleId"`
+	Owner    string    `json:"-"`
+	URL      string    `json:"url"`
+	Payload  RuleEvent `json:"payload"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// WeatherAlerts represent the active alerts response for weather-app API.
+type WeatherAlerts struct {
+	Alerts []CityAlerts `json:"alerts"`
+}
+
+// CityAlerts represent the active alerts for a city.
+type CityAlerts struct {
+	Name   string  `json:"name"`
+	Alerts []Alert `json:"alerts"`
+}
+
+// Alert represents an active weather alert, e.g. a flood or heat advisory.
+type Alert struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	Headline  string    `json:"headline"`
+	Severity  string    `json:"severity"`
+	Urgency   string    `json:"urgency"`
+	Effective time.Time `json:"effective"`
+	Expires   time.Time `json:"expires"`
+	AreaDesc  string    `json:"areaDesc"`
+}
+
+// CurrentConditions represent the current conditions response for weather-app API.
+type CurrentConditions struct {
+	Current []CityConditions `json:"current"`
+}
+
+// CityConditions represent the latest observation from the station nearest to a city.
+type CityConditions struct {
+	Name          string       `json:"name"`
+	StationID     string       `json:"stationId"`
+	StationName   string       `json:"stationName"`
+	Timestamp     time.Time    `json:"timestamp"`
+	Age           int64        `json:"age"`
+	Stale         bool         `json:"stale"`
+	Description   string       `json:"description"`
+	Temperature   *Measurement `json:"temperature,omitempty"`
+	Humidity      *Measurement `json:"humidity,omitempty"`
+	WindSpeed     *Measurement `json:"windSpeed,omitempty"`
+	WindDirection *Measurement `json:"windDirection,omitempty"`
+	Visibility    *Measurement `json:"visibility,omitempty"`
+}
+
+// Measurement represent an observed value and its unit.
+type Measurement struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// Itinerary represent the forecast timeline of an itinerary, a stop per leg
+// and day sorted by date. Legs sharing a day, e.g. on travel days, keep the
+// request order.
+type Itinerary struct {
+	Timeline []ItineraryStop `json:"timeline"`
+}
+
+// ItineraryStop represent the forecast of a leg on one of its days, Leg is
+// the index of the leg on the request. Detail is nil when the day was not
+// forecast and Error tells why when the whole leg failed.
+type ItineraryStop struct {
+	Date   string  `json:"date"`
+	Leg    int     `json:"leg"`
+	Name   string  `json:"name"`
+	Detail *Detail `json:"detail,omitempty"`
+	Stale  bool    `json:"stale,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// Comparison represent the forecast of cities ranked on a metric, e.g. the
+// warmest first. Best tells whether the highest or the lowest value wins.
+type Comparison struct {
+	Metric  string          `json:"metric"`
+	Best    string          `json:"best"`
+	Ranking []CityRank      `json:"ranking"`
+	Days    []DayComparison `json:"days"`
+}
+
+// CityRank represent the place of a city on a comparison, Value is its mean
+// over the Days of the compared days it has a value for and Wins how many of
+// them it won. Cities with fewer days are ranked after the others, cities
+// sharing days and value share the rank.
+type CityRank struct {
+	Rank  int          `json:"rank"`
+	Name  string       `json:"name"`
+	Value *Measurement `json:"value,omitempty"`
+	Days  int          `json:"days"`
+	Wins  int          `json:"wins"`
+}
+
+// DayComparison represent the values of a compared day, Winners are the
+// cities with the best value and Tie is set when there is more than one.
+type DayComparison struct {
+	Date    string      `json:"date"`
+	Values  []CityValue `json:"values"`
+	Winners []string    `json:"winners"`
+	Tie     bool        `json:"tie"`
+}
+
+// CityValue represent the value of a city on a compared day, nil when the
+// metric was not forecast.
+type CityValue struct {
+	Name  string       `json:"name"`
+	Value *Measurement `json:"value,omitempty"`
+}
+
+// GridForecast represent the gridpoint time-series response for weather-app API.
+type GridForecast struct {
+	Grid []CityGrid `json:"grid"`
+}
+
+// CityGrid represent the requested gridpoint layers for a city.
+type CityGrid struct {
+	Name   string               `json:"name"`
+	Layers map[string]GridLayer `json:"layers"`
+}
+
+// GridLayer represent a quantitative series, e.g. temperature, at hourly resolution.
+type GridLayer struct {
+	Unit   string      `json:"unit"`
+	Values []GridValue `json:"values"`
+}
+
+// GridValue represent the value of a layer for a given hour.
+type GridValue struct {
+	Time  time.Time `json:"time"`
+	Value *float64  `json:"value"`
 }
 
 //	gateways responses:
@@ -35,6 +346,9 @@ type Location struct {
 	DisplayName string `json:"display_name"`
 	Class       string `json:"class"`
 	Type        string `json:"type"`
+	// BoundingBox is the area of the place as south, north, west and east
+	// coordinates, e.g. ["51.28", "51.69", "-0.51", "0.33"].
+	BoundingBox []string `json:"boundingbox,omitempty"`
 }
 
 // WeatherPointsResponse represent the response for Weather API requests.
@@ -44,7 +358,8 @@ type WeatherPointsResponse struct {
 
 // WeatherProperties represent the forecast URL for a pair of points.
 type WeatherProperties struct {
-	ForecastURL string `json:"forecast"`
+	ForecastURL         string `json:"forecast"`
+	ForecastGridDataURL string `json:"forecastGridData"`
 }
 
 // ForecastResponse represent the response for calling forcast URL for a pair of points.
@@ -59,12 +374,146 @@ type ForecastProperties struct {
 
 // Period represents the forecast detail.
 type Period struct {
-	StartTime   time.Time `json:"startTime"`
-	EndTime     time.Time `json:"endTime"`
-	Description string    `json:"detailedForecast"`
+	StartTime                  time.Time         `json:"startTime"`
+	EndTime                    time.Time         `json:"endTime"`
+	ShortForecast              string            `json:"shortForecast"`
+	Description                string            `json:"detailedForecast"`
+	Temperature                *float64          `json:"temperature"`
+	TemperatureUnit            string            `json:"temperatureUnit"`
+	ProbabilityOfPrecipitation QuantitativeValue `json:"probabilityOfPrecipitation"`
+	// WindSpeed is a speed or a range, e.g. 5 to 10 mph.
+	WindSpeed string `json:"windSpeed"`
+}
+
+// AlertsResponse represent the response for Weather API active alerts requests.
+type AlertsResponse struct {
+	Features []AlertFeature `json:"features"`
+}
+
+// AlertFeature represents a single alert in the active alerts collection.
+type AlertFeature struct {
+	Properties Alert `json:"properties"`
+}
+
+// StationsResponse represent the response for Weather API observation stations requests.
+type StationsResponse struct {
+	Features []StationFeature `json:"features"`
+}
+
+// StationFeature represents a single station in the stations collection.
+type StationFeature struct {
+	Properties Station `json:"properties"`
+}
+
+// Station represents an observation station, stations are sorted by distance.
+type Station struct {
+	ID   string `json:"stationIdentifier"`
+	Name string `json:"name"`
+}
+
+// ObservationResponse represent the response for Weather API latest observation requests.
+type ObservationResponse struct {
+	Properties Observation `json:"properties"`
+}
+
+// Observation represents the values observed by a station.
+type Observation struct {
+	Timestamp        time.Time         `json:"timestamp"`
+	TextDescription  string            `json:"textDescription"`
+	Temperature      QuantitativeValue `json:"temperature"`
+	RelativeHumidity QuantitativeValue `json:"relativeHumidity"`
+	WindSpeed        QuantitativeValue `json:"windSpeed"`
+	WindDirection    QuantitativeValue `json:"windDirection"`
+	Visibility       QuantitativeValue `json:"visibility"`
+}
+
+// QuantitativeValue represents a Weather API value with its WMO unit code,
+// value is nil when the station did not report it.
+type QuantitativeValue struct {
+	UnitCode string   `json:"unitCode"`
+	Value    *float64 `json:"value"`
+}
+
+// GridpointResponse represent the response for Weather API gridpoints requests,
+// properties holds both the layers and the grid metadata.
+type GridpointResponse struct {
+	Properties map[string]json.RawMessage `json:"properties"`
+}
+
+// GridpointLayer represents a gridpoint quantitative layer, e.g. temperature.
+type GridpointLayer struct {
+	UOM    string           `json:"uom"`
+	Values []GridpointValue `json:"values"`
+}
+
+// GridpointValue represents a layer value for an ISO 8601 interval, e.g.
+// 2024-09-23T18:00:00+00:00/PT3H.
+type GridpointValue struct {
+	ValidTime string   `json:"validTime"`
+	Value     *float64 `json:"value"`
+}
+
+// CacheKey summarizes what is cached under a location key, Age is the
+// seconds since the oldest forecast period was fetched and TTL the seconds
+// left until it expires, negative once stale.
+type CacheKey struct {
+	Key      string   `json:"key"`
+	Kinds    []string `json:"kinds"`
+	Age      *int64   `json:"age,omitempty"`
+	TTL      *int64   `json:"ttl,omitempty"`
+	AlertTTL *int64   `json:"alertTtl,omitempty"`
+}
+
+// CacheKeys is a page of cached keys.
+type CacheKeys struct {
+	Keys   []CacheKey `json:"keys"`
+	Total  int        `json:"total"`
+	Offset int        `json:"offset"`
+	Limit  int        `json:"limit"`
+}
+
+// CacheEntry holds everything cached under a location key.
+type CacheEntry struct {
+	Key             string                 `json:"key"`
+	Location        *Location              `json:"location,omitempty"`
+	Station         *Station               `json:"station,omitempty"`
+	Periods         map[string]PeriodEntry `json:"periods,omitempty"`
+	Alerts          []Alert                `json:"alerts,omitempty"`
+	AlertsExpiresAt *time.Time             `json:"alertsExpiresAt,omitempty"`
+}
+
+// PeriodEntry is a cached forecast period and when it was fetched.
+type PeriodEntry struct {
+	Period    Period    `json:"period"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// CacheSnapshot is an export of the whole cache, it can be imported back.
+type CacheSnapshot struct {
+	CreatedAt time.Time    `json:"createdAt"`
+	Entries   []CacheEntry `json:"entries"`
+}
+
+// ActivityForecast represent how well each forecast day of the cities suits an
+// activity, e.g. running.
+type ActivityForecast struct {
+	Activity string         `json:"activity"`
+	Forecast []CityActivity `json:"forecast"`
+}
+
+// CityActivity represent the scored forecast days of a city.
+type CityActivity struct {
+	Name  string        `json:"name"`
+	Days  []ActivityDay `json:"days"`
+	Stale bool          `json:"stale,omitempty"`
 }
 
-type CacheResponse struct {
-	Location map[string]Location          `json:"locations"`
-	Periods  map[string]map[string]Period `json:"periods"`
+// ActivityDay represent the score of a forecast day, from 0 to 100, and the
+// reasons behind it. Rating is good, fair or poor following the score.
+type ActivityDay struct {
+	Date    string   `json:"date"`
+	Score   int      `json:"score"`
+	Rating  string   `json:"rating"`
+	Reasons []string `json:"reasons"`
+	Detail  Detail   `json:"detail"`
 }
@@ -0,0 +1,75 @@
+// Package problem writes the RFC 7807 application/problem+json error
+// responses shared by every weather-app handler.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dibrito/ennismore-weather-app/pkg/model"
+	"github.com/go-chi/chi/middleware"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// Problem types, relative to the API root.
+const (
+	TypeInvalidRequest      = "/problems/invalid-request"
+	TypeUnauthorized        = "/problems/unauthorized"
+	TypeForbidden           = "/problems/forbidden"
+	TypeNotFound            = "/problems/not-found"
+	TypeMethodNotAllowed    = "/problems/method-not-allowed"
+	TypeBodyTooLarge        = "/problems/body-too-large"
+	TypeRateLimited         = "/problems/rate-limited"
+	TypeInternal            = "/problems/internal"
+	TypeUpstreamUnavailable = "/problems/upstream-unavailable"
+	TypeUpstreamTimeout     = "/problems/upstream-timeout"
+)
+
+// types maps the status codes into their problem type.
+var types = map[int]string{
+	http.StatusBadRequest:            TypeInvalidRequest,
+	http.StatusUnauthorized:          TypeUnauthorized,
+	http.StatusForbidden:             TypeForbidden,
+	http.StatusNotFound:              TypeNotFound,
+	http.StatusMethodNotAllowed:      TypeMethodNotAllowed,
+	http.StatusRequestEntityTooLarge: TypeBodyTooLarge,
+	http.StatusTooManyRequests:       TypeRateLimited,
+	http.StatusInternalServerError:   TypeInternal,
+	http.StatusBadGateway:            TypeUpstreamUnavailable,
+	http.StatusServiceUnavailable:    TypeUpstreamUnavailable,
+	http.StatusGatewayTimeout:        TypeUpstreamTimeout,
+}
+
+// New creates the problem of a status code, the title is the status text.
+func New(status int, detail string, errors ...model.FieldError) model.Problem {
+	typ, ok := types[status]
+	if !ok {
+		typ = "about:blank"
+	}
+	return model.Problem{
+		Type:   typ,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Errors: errors,
+	}
+}
+
+// Error replies with the problem of a status code, see New.
+func Error(w http.ResponseWriter, req *http.Request, status int, detail string, errors ...model.FieldError) {
+	Write(w, req, New(status, detail, errors...))
+}
+
+// Write replies with the problem, the request path and ID are added to it.
+func Write(w http.ResponseWriter, req *http.Request, p model.Problem) {
+	p.Instance = req.URL.Path
+	p.RequestID = middleware.GetReqID(req.Context())
+	// a problem only holds strings and numbers, it always marshals
+	bs, _ := json.Marshal(p)
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(append(bs, '\n'))
+}
@@ -0,0 +1,85 @@
+package units
+
+import (
+	"fmt"
+	"strings"
+)
+
+// System defines the unit system used on weather-app responses.
+type System string
+
+const (
+	Metric   System = "metric"
+	Imperial System = "imperial"
+)
+
+// Parse maps the units query param into a unit System, empty defaults to Metric.
+func Parse(s string) (System, error) {
+	switch System(strings.ToLower(strings.TrimSpace(s))) {
+	case "", Metric:
+		return Metric, nil
+	case Imperial:
+		return Imperial, nil
+	default:
+		return "", fmt.Errorf("unsupported units: %q", s)
+	}
+}
+
+// Convert converts a weather.gov quantitative value, identified by its WMO unit
+// code e.g. wmoUnit:degC, into the given unit System. It returns the converted
+// value and a human readable unit, unknown unit codes are returned untouched.
+func Convert(value float64, unitCode string, system System) (float64, string) {
+	switch strings.TrimPrefix(unitCode, "wmoUnit:") {
+	case "degC":
+		if system == Imperial {
+			return CelsiusToFahrenheit(value), "degF"
+		}
+		return value, "degC"
+	case "degF":
+		if system == Metric {
+			return FahrenheitToCelsius(value), "degC"
+		}
+		return value, "degF"
+	case "km_h-1":
+		if system == Imperial {
+			return value / kmPerMile, "mph"
+		}
+		return value, "km/h"
+	case "m_s-1":
+		if system == Imperial {
+			return value * 3.6 / kmPerMile, "mph"
+		}
+		return value * 3.6, "km/h"
+	case "m":
+		if system == Imperial {
+			return value / 1000 / kmPerMile, "mi"
+		}
+		return value / 1000, "km"
+	case "percent":
+		return value, "%"
+	case "degree_(angle)":
+		return value, "deg"
+	default:
+		return value, unitCode
+	}
+}
+
+const kmPerMile = 1.609344
+
+// Kilometers converts a distance given in the unit System, km or miles, into km.
+func Kilometers(distance float64, system System) float64 {
+	if system == Imperial {
+		return distance * kmPerMile
+	}
+	return distance
+}
+
+// CelsiusToFahrenheit converts a temperature from degC to degF.
+func CelsiusToFahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
+
+// FahrenheitToCelsius converts a temperature from degF to degC.
+func FahrenheitToCelsius(f float64) float64 {
+	return (f - 32) * 5 / 9
+}
@@ -3,8 +3,8 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"testing"
+	"time"
 
 	serviceConfig "github.com/dibrito/ennismore-weather-app/config"
 	"github.com/dibrito/ennismore-weather-app/internal/clients/openstreetmap"
@@ -29,7 +29,7 @@ func TestIntegration(t *testing.T) {
 	logger := zaptest.NewLogger(t)
 	// Create a context with the logger
 	ctx := context.WithValue(context.Background(), logging.LoggetCtxKey{}, logger)
-	res, err := openstreetmapClient.GetLocation(ctx, "new york")
+	res, err := openstreetmapClient.GetLocation(ctx, "new york", "")
 	require.NoError(t, err)
 	if len(res) == 0 {
 		t.Errorf("want:>0 got:%v", len(res))
@@ -54,7 +54,8 @@ func TestIntegration(t *testing.T) {
 }
 
 func TestCache(t *testing.T) {
-	cache := repository.New()
+	cache := repository.New(time.Minute, time.Minute, 0, 0)
 	cache.PutPeriods("city", "start", model.Period{})
-	fmt.Println(cache.GetCache())
+	_, ok := cache.GetPeriods("city", "start")
+	require.True(t, ok)
 }